@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/vimgo/vimgo/internal/room"
+	"github.com/vimgo/vimgo/internal/sshd"
+	"golang.org/x/crypto/ssh"
+)
+
+func main() {
+	home, _ := os.UserHomeDir()
+	addr := flag.String("addr", ":2222", "address to listen on")
+	hostKey := flag.String("host-key", "ssh_host_ed25519_key", "host key file, generated if missing")
+	authorizedKeys := flag.String("authorized-keys", filepath.Join(home, ".ssh", "authorized_keys"), "public keys of the users let in")
+	size := flag.Int("size", 19, "board size of solo games and new rooms (9, 13, or 19)")
+	record := flag.String("record", "", "directory to record sessions to as asciicast files, which vimgo-web -record replays")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), "\nClients connect with ssh -p 2222 host, for a game of their own, or run\n"+sshd.Usage)
+	}
+	flag.Parse()
+
+	if *size != 9 && *size != 13 && *size != 19 {
+		fmt.Println("Invalid size. Please use 9, 13, or 19.")
+		os.Exit(1)
+	}
+	if *record != "" {
+		if err := os.MkdirAll(*record, 0o755); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if _, err := os.Stat(*authorizedKeys); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	signer, err := sshd.LoadHostKey(*hostKey)
+	if err != nil {
+		fmt.Printf("host key: %v\n", err)
+		os.Exit(1)
+	}
+	config := &ssh.ServerConfig{PublicKeyCallback: sshd.Authorize(*authorizedKeys)}
+	config.AddHostKey(signer)
+
+	// The players' terminals are not the server's: assume one with 256
+	// colors, as most are.
+	lipgloss.SetColorProfile(termenv.ANSI256)
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("ssh listening on %s (host key %s)", ln.Addr(), ssh.FingerprintSHA256(signer.PublicKey()))
+	// Rooms opened here are only for this server's players; vimgo-web
+	// -ssh-addr shares them with the browser's.
+	srv := &sshd.Server{Hub: room.NewHub(), Size: *size, RecordDir: *record}
+	log.Fatal(srv.Serve(ln, config))
+}
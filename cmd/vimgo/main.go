@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,6 +13,11 @@ import (
 
 func main() {
 	size := flag.Int("size", 19, "Board size (9, 13, or 19)")
+	rcFile := flag.String("u", "", "Configuration file to use instead of ~/.vimgorc (NONE to skip)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file.sgf ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	if *size != 9 && *size != 13 && *size != 19 {
@@ -19,7 +26,25 @@ func main() {
 	}
 
 	m := terminal.NewModel(*size)
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	m.Clipboard = os.Stderr
+	m.Tmux = os.Getenv("TMUX") != ""
+	switch *rcFile {
+	case "NONE":
+	case "":
+		// A missing ~/.vimgorc is fine; other errors are shown on start.
+		if err := m.Source(terminal.DefaultRCFile()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			m.Error = err
+		}
+	default:
+		if err := m.Source(*rcFile); err != nil {
+			m.Error = err
+		}
+	}
+	if err := m.OpenFiles(flag.Args()); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running VimGo: %v", err)
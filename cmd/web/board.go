@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/game"
+	"github.com/vimgo/vimgo/internal/protocol"
+	"github.com/vimgo/vimgo/internal/room"
+)
+
+// handleBoard speaks the JSON protocol of package protocol with a native
+// board in room rm. Seats are taken as in handleRoom; the game itself
+// stays with the room, which checks every request against the rules.
+func handleBoard(w http.ResponseWriter, r *http.Request, sm *sessions, rm *room.Room) {
+	q := r.URL.Query()
+	sm.serve(w, r, "board:"+rm.ID, "", func(s *session) error {
+		member, err := rm.Join(q.Get("name"), parseSeat(q.Get("seat")))
+		if err != nil {
+			s.writeJSON(protocol.NewError("", err))
+			return err
+		}
+		startBoard(s, member, false)
+		return nil
+	})
+}
+
+// handleWatch lets anyone watch room rm, read-only: a browser gets the
+// board page, which connects back to the same address with a websocket.
+// Watchers get the game's moves one by one as they are played, and
+// browse the earlier ones on their own board.
+func handleWatch(w http.ResponseWriter, r *http.Request, sm *sessions, rm *room.Room, pages fs.FS) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		http.ServeFileFS(w, r, pages, "board.html")
+		return
+	}
+	sm.serve(w, r, "watch:"+rm.ID, "", func(s *session) error {
+		startBoard(s, rm.Watch(r.URL.Query().Get("name")), true)
+		return nil
+	})
+}
+
+// startBoard sends member's room to the client of session s, and carries
+// out the requests it reads. A read-only client gets moves as Played
+// messages and may not ask for anything. The member stays in the room
+// as long as the session, not the websocket.
+func startBoard(s *session, member *room.Member, readOnly bool) {
+	rm := member.Room()
+	s.watching = readOnly
+	// Everything is sent from one goroutine, which greets a client that
+	// attached again when told so on redraws.
+	redraws := make(chan struct{}, 1)
+	s.redraw = func() {
+		select {
+		case redraws <- struct{}{}:
+		default:
+		}
+	}
+	s.stop = member.Leave
+	s.handle = func(_ int, payload []byte) {
+		var req protocol.Request
+		if err := json.Unmarshal(payload, &req); err != nil {
+			s.writeJSON(protocol.NewError("", err))
+			return
+		}
+		err := room.ErrWatching
+		if !readOnly {
+			err = handleRequest(member, req)
+		}
+		if err != nil {
+			s.writeJSON(protocol.NewError(req.ID, err))
+		}
+	}
+
+	go func() {
+		defer close(s.done)
+		var last protocol.State
+		greet := func() {
+			s.writeJSON(protocol.NewHello(rm.ID, member.Name, member.Color))
+			if state, err := stateMessage(rm.SGF()); err == nil {
+				last = state
+				s.writeJSON(state)
+			}
+			if c, ok := rm.Clock(); ok {
+				s.writeJSON(protocol.NewClock(c.Black, c.White, c.Running))
+			}
+		}
+		greet()
+
+		// The room closes both channels together, when the member leaves
+		// or is dropped.
+		events, viewers := member.Events(), member.Viewers()
+		for {
+			var msg any
+			var err error
+			select {
+			case <-redraws:
+				greet()
+				_, spectators := rm.Players()
+				msg = protocol.NewViewers(spectators)
+			case n, ok := <-viewers:
+				if !ok {
+					return
+				}
+				msg = protocol.NewViewers(n)
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				if msg, err = eventMessage(ev); err != nil {
+					s.log.Error("encoding room event", "err", err)
+					continue
+				}
+				if state, isState := msg.(protocol.State); isState && readOnly {
+					if p, ok := protocol.NextMove(last, state); ok {
+						msg = p
+					}
+					last = state
+				}
+			}
+			s.writeJSON(msg)
+		}
+	}()
+}
+
+// handleRequest carries out a client's request in the member's room.
+func handleRequest(m *room.Member, req protocol.Request) error {
+	switch req.Type {
+	case protocol.TypeMove:
+		return m.Play(&board.Point{X: req.X, Y: req.Y})
+	case protocol.TypePass:
+		return m.Play(nil)
+	case protocol.TypeUndo:
+		return m.Undo()
+	case protocol.TypeChat:
+		return m.Say(req.Text)
+	}
+	return fmt.Errorf("unknown request %q", req.Type)
+}
+
+// eventMessage turns a room event into its protocol message.
+func eventMessage(ev room.Event) (any, error) {
+	switch ev.Kind {
+	case room.GameChanged:
+		return stateMessage(ev.SGF)
+	case room.ClockTick:
+		return protocol.NewClock(ev.Clock.Black, ev.Clock.White, ev.Clock.Running), nil
+	case room.UndoRequested:
+		return protocol.NewUndo(ev.From, ev.Color), nil
+	case room.Chat:
+		return protocol.NewChat(ev.From, ev.Color, ev.Text), nil
+	case room.Left:
+		return protocol.NewLeft(ev.From, ev.Color), nil
+	}
+	return nil, fmt.Errorf("unknown event %d", ev.Kind)
+}
+
+func stateMessage(sgf string) (protocol.State, error) {
+	g, err := game.Load(sgf)
+	if err != nil {
+		return protocol.State{}, err
+	}
+	return protocol.NewState(g), nil
+}
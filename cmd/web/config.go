@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vimgo/vimgo/web"
+)
+
+// config is how the web server is set up. Every setting is a flag, and
+// a flag not given takes its value from the environment variable named
+// after it, such as VIMGO_MAX_PER_IP for -max-per-ip.
+type config struct {
+	addr string
+	// bin, if set, is a vimgo binary run in a pty for each terminal
+	// session, instead of running VimGo in this process.
+	bin string
+	// staticDir, if set, is served instead of the pages built in.
+	staticDir string
+	// recordDir, if set, is where terminal sessions are recorded.
+	recordDir string
+	certFile  string
+	keyFile   string
+	// origins are the origins, besides the server's own, whose pages may
+	// open websockets; "*" allows any.
+	origins []string
+
+	// metricsAddr, if set, serves /metrics apart from the web UI, which
+	// does not serve it.
+	metricsAddr string
+	logFormat   string
+
+	// sshAddr, if set, serves VimGo over ssh as well, to the users whose
+	// keys are in sshAuthorizedKeys, sharing the rooms.
+	sshAddr           string
+	sshHostKey        string
+	sshAuthorizedKeys string
+
+	maxSessions     int
+	maxPerIP        int
+	grace           time.Duration
+	idleTimeout     time.Duration
+	shutdownTimeout time.Duration
+}
+
+func loadConfig(args []string) (config, error) {
+	var c config
+	var origins string
+	home, _ := os.UserHomeDir()
+	fs := flag.NewFlagSet("web", flag.ContinueOnError)
+	fs.StringVar(&c.addr, "addr", ":8080", "address to listen on")
+	fs.StringVar(&c.bin, "bin", "", "vimgo binary to run in a pty for each terminal session, instead of in this process")
+	fs.StringVar(&c.staticDir, "static", "", "directory of web pages to serve instead of the built-in ones, such as web/static")
+	fs.StringVar(&c.recordDir, "record", "", "directory to record terminal sessions to as asciicast files, replayed at /replay/<id>")
+	fs.StringVar(&c.certFile, "tls-cert", "", "TLS certificate file, to serve HTTPS with -tls-key")
+	fs.StringVar(&c.keyFile, "tls-key", "", "TLS key file")
+	fs.StringVar(&origins, "origins", "", "comma-separated origins allowed to connect besides the server's own, * for any")
+	fs.StringVar(&c.metricsAddr, "metrics-addr", "", "address to serve /metrics on, such as localhost:9090; without it there are no metrics")
+	fs.StringVar(&c.logFormat, "log-format", "text", "log format: text or json")
+	fs.StringVar(&c.sshAddr, "ssh-addr", "", "address to serve ssh on as well, with the same rooms, such as :2222")
+	fs.StringVar(&c.sshHostKey, "ssh-host-key", "ssh_host_ed25519_key", "ssh host key file, generated if missing")
+	fs.StringVar(&c.sshAuthorizedKeys, "ssh-authorized-keys", filepath.Join(home, ".ssh", "authorized_keys"), "public keys of the users let in over ssh")
+	fs.IntVar(&c.maxSessions, "max-sessions", 100, "most sessions at once, 0 for no limit")
+	fs.IntVar(&c.maxPerIP, "max-per-ip", 10, "most connections from one IP address, 0 for no limit")
+	fs.DurationVar(&c.grace, "grace", time.Minute, "how long a session waits for its client to reconnect, 0 to end it at once")
+	fs.DurationVar(&c.idleTimeout, "idle-timeout", 30*time.Minute, "end sessions whose client sent nothing for this long, 0 never")
+	fs.DurationVar(&c.shutdownTimeout, "shutdown-timeout", 10*time.Second, "how long to wait for sessions to end on SIGTERM")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "Each flag not given is read from VIMGO_<FLAG>, as VIMGO_MAX_PER_IP for -max-per-ip.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return c, err
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envName(f.Name))
+		if given[f.Name] || !ok || err != nil {
+			return
+		}
+		if setErr := fs.Set(f.Name, v); setErr != nil {
+			err = fmt.Errorf("%s: %w", envName(f.Name), setErr)
+		}
+	})
+	if err != nil {
+		return c, err
+	}
+
+	for _, o := range strings.Split(origins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			c.origins = append(c.origins, o)
+		}
+	}
+	if c.logFormat != "text" && c.logFormat != "json" {
+		return c, fmt.Errorf("invalid log format %q: text or json", c.logFormat)
+	}
+	if (c.certFile == "") != (c.keyFile == "") {
+		return c, errors.New("-tls-cert and -tls-key go together")
+	}
+	return c, nil
+}
+
+// pages returns the web pages to serve.
+func (c config) pages() fs.FS {
+	if c.staticDir != "" {
+		return os.DirFS(c.staticDir)
+	}
+	return web.Static()
+}
+
+// envName is the environment variable for flag name.
+func envName(name string) string {
+	return "VIMGO_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// checkOrigin lets pages from the server itself and from c.origins open
+// websockets. Clients other than browsers send no origin and are let in.
+func (c config) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, o := range c.origins {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		check   func(c config) bool
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			check: func(c config) bool {
+				return c.addr == ":8080" && c.maxSessions == 100 && c.grace == time.Minute && c.logFormat == "text"
+			},
+		},
+		{
+			name:  "env",
+			env:   map[string]string{"VIMGO_MAX_PER_IP": "3", "VIMGO_IDLE_TIMEOUT": "5m", "VIMGO_ADDR": ":9000"},
+			check: func(c config) bool { return c.maxPerIP == 3 && c.idleTimeout == 5*time.Minute && c.addr == ":9000" },
+		},
+		{
+			name:  "flag over env",
+			args:  []string{"-max-per-ip", "7"},
+			env:   map[string]string{"VIMGO_MAX_PER_IP": "3"},
+			check: func(c config) bool { return c.maxPerIP == 7 },
+		},
+		{
+			name:  "origins",
+			args:  []string{"-origins", " https://a.example, ,https://b.example "},
+			check: func(c config) bool { return strings.Join(c.origins, " ") == "https://a.example https://b.example" },
+		},
+		{
+			name:    "bad env",
+			env:     map[string]string{"VIMGO_GRACE": "soon"},
+			wantErr: "VIMGO_GRACE",
+		},
+		{
+			name:    "log format",
+			args:    []string{"-log-format", "xml"},
+			wantErr: "invalid log format",
+		},
+		{
+			name:    "cert without key",
+			env:     map[string]string{"VIMGO_TLS_CERT": "cert.pem"},
+			wantErr: "go together",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			c, err := loadConfig(tc.args)
+			switch {
+			case tc.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error with %q, got %v", tc.wantErr, err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case !tc.check(c):
+				t.Fatalf("unexpected config %+v", c)
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	c := config{origins: []string{"https://play.example/"}}
+	for _, tc := range []struct {
+		host, origin string
+		want         bool
+	}{
+		{"vimgo.example", "", true},
+		{"vimgo.example", "https://vimgo.example", true},
+		{"vimgo.example:8080", "http://VIMGO.example:8080", true},
+		{"vimgo.example", "https://play.example", true},
+		{"vimgo.example", "https://evil.example", false},
+		{"vimgo.example", "https://vimgo.example.evil.example", false},
+		{"vimgo.example", "null", false},
+	} {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.Host = tc.host
+		if tc.origin != "" {
+			r.Header.Set("Origin", tc.origin)
+		}
+		if got := c.checkOrigin(r); got != tc.want {
+			t.Errorf("origin %q on %s: expected %v, got %v", tc.origin, tc.host, tc.want, got)
+		}
+	}
+
+	c.origins = []string{"*"}
+	r := httptest.NewRequest("GET", "/ws", nil)
+	r.Header.Set("Origin", "https://evil.example")
+	if !c.checkOrigin(r) {
+		t.Errorf("expected * to let any origin in")
+	}
+}
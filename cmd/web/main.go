@@ -1,32 +1,47 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/charmbracelet/lipgloss"
 	"github.com/creack/pty"
 	"github.com/gorilla/websocket"
+	"github.com/muesli/termenv"
+	"github.com/vimgo/vimgo/internal/protocol"
+	"github.com/vimgo/vimgo/internal/room"
+	"github.com/vimgo/vimgo/internal/sshd"
+	"github.com/vimgo/vimgo/internal/ui/terminal"
+	"golang.org/x/crypto/ssh"
 )
 
-const defaultAddr = ":8080"
-
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	Subprotocols:    []string{protocol.Subprotocol},
 }
 
+// ptyCols and ptyRows are the size of a terminal session until its
+// client tells its own.
+const (
+	ptyCols = 120
+	ptyRows = 40
+)
+
 type wsControlMessage struct {
 	Type string `json:"type"`
 	Cols uint16 `json:"cols"`
@@ -34,93 +49,281 @@ type wsControlMessage struct {
 }
 
 func main() {
-	bin := "./vimgo"
-	staticDir := filepath.Join("web", "static")
+	cfg, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var logs slog.Handler = slog.NewTextHandler(os.Stderr, nil)
+	if cfg.logFormat == "json" {
+		logs = slog.NewJSONHandler(os.Stderr, nil)
+	}
+	slog.SetDefault(slog.New(logs))
+	upgrader.CheckOrigin = cfg.checkOrigin
+	if cfg.recordDir != "" {
+		if err := os.MkdirAll(cfg.recordDir, 0o755); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	hub := room.NewHub()
+	sm := newSessions(cfg)
+	// The reaper looks twice per grace period or idle timeout, whichever
+	// is shorter.
+	var every time.Duration
+	for _, d := range []time.Duration{cfg.grace, cfg.idleTimeout} {
+		if d > 0 && (every == 0 || d < every) {
+			every = d
+		}
+	}
+	if every > 0 {
+		go sm.reap(max(every/2, time.Second))
+	}
+
+	// Room players run in this process and render for xterm.js, not for
+	// the server's own output.
+	lipgloss.SetColorProfile(termenv.TrueColor)
 
-	http.Handle("/", http.FileServer(http.Dir(staticDir)))
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	pages := cfg.pages()
+	mux.Handle("/", http.FileServerFS(pages))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		handleNewRoom(w, r, hub)
+	})
+	mux.HandleFunc("/watch/{id}", func(w http.ResponseWriter, r *http.Request) {
+		rm, ok := hub.Get(r.PathValue("id"))
+		if !ok {
+			http.Error(w, fmt.Sprintf("no room %q", r.PathValue("id")), http.StatusNotFound)
+			return
+		}
+		handleWatch(w, r, sm, rm, pages)
+	})
+	mux.HandleFunc("/replay/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleReplay(w, r, cfg.recordDir, pages)
+	})
+	mux.HandleFunc("/ws/board", func(w http.ResponseWriter, r *http.Request) {
+		rm, ok := hub.Get(r.URL.Query().Get("room"))
+		if !ok {
+			http.Error(w, fmt.Sprintf("no room %q", r.URL.Query().Get("room")), http.StatusNotFound)
+			return
+		}
+		handleBoard(w, r, sm, rm)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if id := r.URL.Query().Get("room"); id != "" {
+			rm, ok := hub.Get(id)
+			if !ok {
+				http.Error(w, fmt.Sprintf("no room %q", id), http.StatusNotFound)
+				return
+			}
+			handleRoom(w, r, sm, rm)
+			return
+		}
 		size := parseBoardSize(r.URL.Query().Get("size"))
-		handleWS(w, r, bin, size)
+		handleWS(w, r, sm, cfg.bin, size)
 	})
 
-	log.Printf("web ui listening on http://localhost%s", defaultAddr)
-	log.Fatal(http.ListenAndServe(defaultAddr, nil))
-}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !sm.ready() {
+			http.Error(w, errShuttingDown.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
 
-func handleWS(w http.ResponseWriter, r *http.Request, bin string, size int) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("websocket upgrade failed: %v", err)
-		return
+	srv := &http.Server{Addr: cfg.addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		host := cfg.addr
+		if strings.HasPrefix(host, ":") {
+			host = "localhost" + host
+		}
+		var err error
+		if cfg.certFile != "" {
+			slog.Info("web ui listening", "url", "https://"+host)
+			err = srv.ListenAndServeTLS(cfg.certFile, cfg.keyFile)
+		} else {
+			slog.Info("web ui listening", "url", "http://"+host)
+			err = srv.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("serving failed", "err", err)
+			os.Exit(1)
+		}
+	}()
+	// Metrics are only served apart from the web UI, for the operators.
+	if cfg.metricsAddr != "" {
+		metrics := http.NewServeMux()
+		metrics.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			handleMetrics(w, sm, hub)
+		})
+		go func() {
+			slog.Info("metrics listening", "addr", cfg.metricsAddr)
+			ms := &http.Server{Addr: cfg.metricsAddr, Handler: metrics, ReadHeaderTimeout: 10 * time.Second}
+			if err := ms.ListenAndServe(); err != nil {
+				slog.Error("serving metrics failed", "err", err)
+				os.Exit(1)
+			}
+		}()
 	}
-	defer conn.Close()
 
-	cmd := exec.Command(bin, "-size", strconv.Itoa(size))
-	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	var sshListener net.Listener
+	if cfg.sshAddr != "" {
+		if sshListener, err = listenSSH(cfg, hub); err != nil {
+			slog.Error("serving ssh failed", "err", err)
+			os.Exit(1)
+		}
+	}
 
-	ptmx, err := pty.Start(cmd)
-	if err != nil {
-		log.Printf("failed to start pty process: %v", err)
-		writeErr(conn, "failed to start vimgo process: "+err.Error())
-		return
+	// On SIGTERM, stop taking connections and end the sessions, which
+	// websockets keep out of the server's own shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+	slog.Info("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	if sshListener != nil {
+		sshListener.Close()
 	}
-	_ = pty.Setsize(ptmx, &pty.Winsize{Cols: 120, Rows: 40})
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("shutdown", "err", err)
+	}
+	if err := sm.shutdown(ctx); err != nil {
+		slog.Warn("sessions still running at shutdown", "err", err)
+	}
+}
 
-	defer func() {
-		_ = ptmx.Close()
-		if cmd.Process != nil {
-			_ = cmd.Process.Kill()
-			_, _ = cmd.Process.Wait()
+// listenSSH serves VimGo over ssh on cfg.sshAddr, in the rooms of hub,
+// and returns the listener, for the shutdown to close.
+func listenSSH(cfg config, hub *room.Hub) (net.Listener, error) {
+	if _, err := os.Stat(cfg.sshAuthorizedKeys); err != nil {
+		return nil, err
+	}
+	signer, err := sshd.LoadHostKey(cfg.sshHostKey)
+	if err != nil {
+		return nil, fmt.Errorf("host key: %w", err)
+	}
+	config := &ssh.ServerConfig{PublicKeyCallback: sshd.Authorize(cfg.sshAuthorizedKeys)}
+	config.AddHostKey(signer)
+	ln, err := net.Listen("tcp", cfg.sshAddr)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("ssh listening", "addr", ln.Addr().String(), "host_key", ssh.FingerprintSHA256(signer.PublicKey()))
+	srv := &sshd.Server{Hub: hub, Size: 19, RecordDir: cfg.recordDir}
+	go func() {
+		if err := srv.Serve(ln, config); !errors.Is(err, net.ErrClosed) {
+			slog.Error("serving ssh failed", "err", err)
+			os.Exit(1)
 		}
 	}()
+	return ln, nil
+}
 
-	done := make(chan struct{})
+// handleWS gives the websocket's user a VimGo of their own, run in this
+// process, or in a pty running bin when set.
+func handleWS(w http.ResponseWriter, r *http.Request, sm *sessions, bin string, size int) {
+	if bin == "" {
+		sm.serve(w, r, "local", "VimGo", func(s *session) error {
+			startLocal(s, size)
+			return nil
+		})
+		return
+	}
+	sm.serve(w, r, "pty", "VimGo", func(s *session) error {
+		return startPTY(s, bin, size)
+	})
+}
 
-	go func() {
-		defer close(done)
-		buf := make([]byte, 8192)
-		for {
-			n, readErr := ptmx.Read(buf)
-			if n > 0 {
-				if writeErr := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); writeErr != nil {
-					return
-				}
-			}
-			if readErr != nil {
-				if readErr != io.EOF && !errors.Is(readErr, syscall.EIO) {
-					log.Printf("pty read error: %v", readErr)
-				}
-				return
-			}
-		}
-	}()
+// startLocal runs VimGo in this process for session s, without a
+// ~/.vimgorc and away from the server's files: they are no business of
+// its users. Yanks to the "+ register reach the browser's clipboard as in
+// a terminal.
+func startLocal(s *session, size int) {
+	m := terminal.NewModel(size)
+	m.Clipboard = s
+	m.NoFiles = true
+	startProgram(s, m, func() {})
+}
 
-	for {
-		msgType, payload, err := conn.ReadMessage()
-		if err != nil {
-			break
+// startPTY runs bin in a pty for session s. The process lives as long as
+// the session, not the websocket.
+func startPTY(s *session, bin string, size int) error {
+	cmd := exec.Command(bin, "-size", strconv.Itoa(size))
+	// Its terminal is the browser's xterm.js, not one in the server's
+	// tmux, if any.
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, "TMUX=") {
+			cmd.Env = append(cmd.Env, v)
 		}
+	}
+	cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		stats.ptySpawnFailures.Add(1)
+		s.log.Error("starting vimgo failed", "err", err)
+		s.writeErr("failed to start vimgo process: " + err.Error())
+		return err
+	}
+	_ = pty.Setsize(ptmx, &pty.Winsize{Cols: ptyCols, Rows: ptyRows})
 
+	s.handle = func(msgType int, payload []byte) {
 		switch msgType {
 		case websocket.BinaryMessage:
 			if _, err := ptmx.Write(payload); err != nil {
-				log.Printf("pty write error: %v", err)
-				return
+				s.log.Warn("pty write failed", "err", err)
 			}
 		case websocket.TextMessage:
 			var ctl wsControlMessage
 			if err := json.Unmarshal(payload, &ctl); err != nil {
-				continue
+				return
 			}
 			if ctl.Type == "resize" && ctl.Cols > 0 && ctl.Rows > 0 {
 				if err := pty.Setsize(ptmx, &pty.Winsize{Cols: ctl.Cols, Rows: ctl.Rows}); err != nil {
-					log.Printf("pty resize error: %v", err)
+					s.log.Warn("pty resize failed", "err", err)
 				}
+				s.resized(int(ctl.Cols), int(ctl.Rows))
 			}
 		}
 	}
+	// A SIGWINCH makes VimGo draw the whole screen again, even when the
+	// size stays the same.
+	s.redraw = func() {
+		_, _ = s.Write([]byte(terminalModes))
+		_ = cmd.Process.Signal(syscall.SIGWINCH)
+	}
+	s.stop = func() {
+		_ = cmd.Process.Kill()
+	}
 
-	<-done
+	go func() {
+		defer close(s.done)
+		buf := make([]byte, 8192)
+		for {
+			n, readErr := ptmx.Read(buf)
+			if n > 0 {
+				_, _ = s.Write(buf[:n])
+			}
+			if readErr != nil {
+				if readErr != io.EOF && !errors.Is(readErr, syscall.EIO) {
+					s.log.Warn("pty read failed", "err", readErr)
+				}
+				break
+			}
+		}
+		_ = ptmx.Close()
+		_ = cmd.Process.Kill()
+		_, _ = cmd.Process.Wait()
+	}()
+	return nil
 }
 
 func parseBoardSize(raw string) int {
@@ -134,10 +337,11 @@ func parseBoardSize(raw string) int {
 	if v == 9 || v == 13 || v == 19 {
 		return v
 	}
-	log.Printf("invalid board size %q, falling back to 19", raw)
+	slog.Warn("invalid board size, falling back to 19", "size", raw)
 	return 19
 }
 
-func writeErr(conn *websocket.Conn, msg string) {
-	_ = conn.WriteMessage(websocket.TextMessage, []byte("\r\n"+fmt.Sprintf("[vimgo-web] %s", msg)+"\r\n"))
+// errMessage formats msg for the client's terminal.
+func errMessage(msg string) []byte {
+	return []byte("\r\n" + fmt.Sprintf("[vimgo-web] %s", msg) + "\r\n")
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vimgo/vimgo/internal/room"
+)
+
+// stats counts what the server does, for /metrics.
+var stats = newMetrics()
+
+// sessionKinds are the kinds of sessions reported, the part of a
+// session's kind before any ":<room>".
+var sessionKinds = []string{"local", "pty", "room", "board", "watch"}
+
+// metrics are the server's counters. Gauges, such as the sessions
+// running, are read when scraped instead.
+type metrics struct {
+	ptySpawnFailures atomic.Int64
+	bytesIn          atomic.Int64
+	bytesOut         atomic.Int64
+
+	mu sync.Mutex
+	// durations is a histogram of how long sessions ran: counts[i] is
+	// the number of sessions that ended within buckets[i] seconds, but
+	// not the bucket before; the last count is for longer ones.
+	buckets []float64
+	counts  []int64
+	sum     float64
+}
+
+func newMetrics() *metrics {
+	buckets := []float64{10, 60, 300, 900, 1800, 3600, 7200, 14400}
+	return &metrics{buckets: buckets, counts: make([]int64, len(buckets)+1)}
+}
+
+// ended records a session that ran for d.
+func (m *metrics) ended(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := d.Seconds()
+	m.counts[sort.SearchFloat64s(m.buckets, s)]++
+	m.sum += s
+}
+
+// handleMetrics writes the metrics in the Prometheus text format.
+func handleMetrics(w http.ResponseWriter, sm *sessions, hub *room.Hub) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	stats.write(w, sm, hub)
+}
+
+func (m *metrics) write(w io.Writer, sm *sessions, hub *room.Hub) {
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	metric("vimgo_sessions_active", "gauge", "Sessions running, by kind.")
+	active := sm.count()
+	for _, kind := range sessionKinds {
+		fmt.Fprintf(w, "vimgo_sessions_active{kind=%q} %d\n", kind, active[kind])
+	}
+
+	metric("vimgo_pty_spawn_failures_total", "counter", "vimgo processes that failed to start.")
+	fmt.Fprintf(w, "vimgo_pty_spawn_failures_total %d\n", m.ptySpawnFailures.Load())
+
+	metric("vimgo_websocket_bytes_total", "counter", "Bytes of websocket messages, by direction.")
+	fmt.Fprintf(w, "vimgo_websocket_bytes_total{direction=\"in\"} %d\n", m.bytesIn.Load())
+	fmt.Fprintf(w, "vimgo_websocket_bytes_total{direction=\"out\"} %d\n", m.bytesOut.Load())
+
+	metric("vimgo_session_duration_seconds", "histogram", "How long sessions ran.")
+	m.mu.Lock()
+	var count int64
+	for i, le := range m.buckets {
+		count += m.counts[i]
+		fmt.Fprintf(w, "vimgo_session_duration_seconds_bucket{le=\"%g\"} %d\n", le, count)
+	}
+	count += m.counts[len(m.buckets)]
+	fmt.Fprintf(w, "vimgo_session_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	fmt.Fprintf(w, "vimgo_session_duration_seconds_sum %g\n", m.sum)
+	fmt.Fprintf(w, "vimgo_session_duration_seconds_count %d\n", count)
+	m.mu.Unlock()
+
+	metric("vimgo_rooms_open", "gauge", "Rooms open.")
+	fmt.Fprintf(w, "vimgo_rooms_open %d\n", hub.Len())
+	metric("vimgo_room_moves_played_total", "counter", "Moves and passes played in rooms, counting those taken back.")
+	fmt.Fprintf(w, "vimgo_room_moves_played_total %d\n", hub.Played())
+}
+
+// kindOf is the kind of session reported for kind.
+func kindOf(kind string) string {
+	k, _, _ := strings.Cut(kind, ":")
+	return k
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/room"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	m := newMetrics()
+	m.ptySpawnFailures.Add(2)
+	m.bytesIn.Add(10)
+	m.bytesOut.Add(2048)
+	m.ended(5 * time.Second)
+	m.ended(60 * time.Second)
+	m.ended(5 * time.Hour)
+
+	sm := newSessions(config{})
+	for i, kind := range []string{"local", "local", "room:abc", "watch:abc"} {
+		sm.tokens[string(rune('a'+i))] = &session{kind: kind}
+	}
+	hub := room.NewHub()
+	rm := hub.Create(room.Settings{Size: 9})
+	black, _ := rm.Join("", board.Black)
+	black.Play(nil)
+
+	var out strings.Builder
+	m.write(&out, sm, hub)
+	want := `# HELP vimgo_sessions_active Sessions running, by kind.
+# TYPE vimgo_sessions_active gauge
+vimgo_sessions_active{kind="local"} 2
+vimgo_sessions_active{kind="pty"} 0
+vimgo_sessions_active{kind="room"} 1
+vimgo_sessions_active{kind="board"} 0
+vimgo_sessions_active{kind="watch"} 1
+# HELP vimgo_pty_spawn_failures_total vimgo processes that failed to start.
+# TYPE vimgo_pty_spawn_failures_total counter
+vimgo_pty_spawn_failures_total 2
+# HELP vimgo_websocket_bytes_total Bytes of websocket messages, by direction.
+# TYPE vimgo_websocket_bytes_total counter
+vimgo_websocket_bytes_total{direction="in"} 10
+vimgo_websocket_bytes_total{direction="out"} 2048
+# HELP vimgo_session_duration_seconds How long sessions ran.
+# TYPE vimgo_session_duration_seconds histogram
+vimgo_session_duration_seconds_bucket{le="10"} 1
+vimgo_session_duration_seconds_bucket{le="60"} 2
+vimgo_session_duration_seconds_bucket{le="300"} 2
+vimgo_session_duration_seconds_bucket{le="900"} 2
+vimgo_session_duration_seconds_bucket{le="1800"} 2
+vimgo_session_duration_seconds_bucket{le="3600"} 2
+vimgo_session_duration_seconds_bucket{le="7200"} 2
+vimgo_session_duration_seconds_bucket{le="14400"} 2
+vimgo_session_duration_seconds_bucket{le="+Inf"} 3
+vimgo_session_duration_seconds_sum 18065
+vimgo_session_duration_seconds_count 3
+# HELP vimgo_rooms_open Rooms open.
+# TYPE vimgo_rooms_open gauge
+vimgo_rooms_open 1
+# HELP vimgo_room_moves_played_total Moves and passes played in rooms, counting those taken back.
+# TYPE vimgo_room_moves_played_total counter
+vimgo_room_moves_played_total 1
+`
+	if out.String() != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, out.String())
+	}
+}
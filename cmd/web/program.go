@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gorilla/websocket"
+)
+
+// startProgram runs VimGo's model m in this process for session s, as a
+// tea.Program reading the keys and writing the screen of its client, and
+// calls cleanup once the program ended. The program lives as long as
+// the session, not the websocket.
+func startProgram(s *session, m tea.Model, cleanup func()) *tea.Program {
+	input, keys := io.Pipe()
+	p := tea.NewProgram(m,
+		tea.WithInput(input),
+		tea.WithOutput(s),
+		tea.WithEnvironment([]string{"TERM=xterm-256color"}),
+		tea.WithoutSignalHandler(),
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+
+	s.handle = func(msgType int, payload []byte) {
+		switch msgType {
+		case websocket.BinaryMessage:
+			_, _ = keys.Write(payload)
+		case websocket.TextMessage:
+			var ctl wsControlMessage
+			if err := json.Unmarshal(payload, &ctl); err != nil {
+				return
+			}
+			if ctl.Type == "resize" && ctl.Cols > 0 && ctl.Rows > 0 {
+				p.Send(tea.WindowSizeMsg{Width: int(ctl.Cols), Height: int(ctl.Rows)})
+				s.resized(int(ctl.Cols), int(ctl.Rows))
+			}
+		}
+	}
+	s.redraw = func() {
+		_, _ = s.Write([]byte(terminalModes))
+		p.Send(tea.ClearScreen())
+	}
+	s.stop = p.Quit
+
+	go func() {
+		defer close(s.done)
+		if _, err := p.Run(); err != nil {
+			s.log.Error("vimgo failed", "err", err)
+		}
+		keys.Close()
+		cleanup()
+	}()
+	return p
+}
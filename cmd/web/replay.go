@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/vimgo/vimgo/internal/asciicast"
+)
+
+// handleReplay plays back the recording of a terminal session: /replay/<id>
+// is the terminal page, which plays the recording it fetches from
+// /replay/<id>.cast.
+func handleReplay(w http.ResponseWriter, r *http.Request, recordDir string, pages fs.FS) {
+	id, cast := strings.CutSuffix(r.PathValue("id"), asciicast.Ext)
+	path, ok := asciicast.File(recordDir, id)
+	if ok && recordDir != "" {
+		_, err := os.Stat(path)
+		ok = err == nil
+	}
+	if !ok || recordDir == "" {
+		http.Error(w, fmt.Sprintf("no recording %q", id), http.StatusNotFound)
+		return
+	}
+	if !cast {
+		http.ServeFileFS(w, r, pages, "index.html")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	http.ServeFile(w, r, path)
+}
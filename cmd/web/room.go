@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/room"
+	"github.com/vimgo/vimgo/internal/ui/terminal"
+)
+
+// handleNewRoom opens a room and sends the browser to it: to the terminal
+// page, or to the board page with ?ui=board. ?time=10m gives each player
+// ten minutes; ?chat=players or off keeps spectators or everyone from
+// chatting, and ?slow=5s has each member wait between two messages. The
+// address of the page it lands on is the link to share with the other
+// player.
+func handleNewRoom(w http.ResponseWriter, r *http.Request, hub *room.Hub) {
+	q := r.URL.Query()
+	settings := room.Settings{Size: parseBoardSize(q.Get("size"))}
+	if raw := q.Get("time"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			http.Error(w, fmt.Sprintf("invalid time %q", raw), http.StatusBadRequest)
+			return
+		}
+		settings.MainTime = d
+	}
+	chat, err := room.ParseChatPolicy(q.Get("chat"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	settings.Chat = chat
+	if raw := q.Get("slow"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			http.Error(w, fmt.Sprintf("invalid slow %q", raw), http.StatusBadRequest)
+			return
+		}
+		settings.SlowChat = d
+	}
+	rm := hub.Create(settings)
+	page := "/"
+	if q.Get("ui") == "board" {
+		page = "/board.html"
+	}
+	for _, k := range []string{"size", "time", "chat", "slow", "ui"} {
+		q.Del(k)
+	}
+	q.Set("room", rm.ID)
+	http.Redirect(w, r, page+"?"+q.Encode(), http.StatusSeeOther)
+}
+
+// handleRoom puts the websocket's user in room rm: they take a free seat,
+// or the one asked for with ?seat=black or white, and watch when both are
+// taken. Each gets their own VimGo, run in this process and bound to the
+// room, so the moves they play go to the room's game.
+func handleRoom(w http.ResponseWriter, r *http.Request, sm *sessions, rm *room.Room) {
+	q := r.URL.Query()
+	sm.serve(w, r, "room:"+rm.ID, "VimGo room "+rm.ID, func(s *session) error {
+		member, err := rm.Join(q.Get("name"), parseSeat(q.Get("seat")))
+		if err != nil {
+			s.writeErr(err.Error())
+			return err
+		}
+		if err := startRoom(s, member); err != nil {
+			member.Leave()
+			s.writeErr("failed to open the room's game: " + err.Error())
+			return err
+		}
+		return nil
+	})
+}
+
+// startRoom runs VimGo for member in session s, away from the server's
+// files. The member stays in the room as long as the session, not the
+// websocket.
+func startRoom(s *session, member *room.Member) error {
+	rm := member.Room()
+	m := terminal.NewModel(rm.Size())
+	m.NoFiles = true
+	if err := m.OpenRemote(member, roomTitle(member), rm.SGF()); err != nil {
+		return err
+	}
+
+	p := startProgram(s, m, member.Leave)
+	go func() {
+		for ev := range member.Events() {
+			switch ev.Kind {
+			case room.GameChanged:
+				p.Send(terminal.RemoteUpdate{Remote: member, SGF: ev.SGF})
+			case room.Chat:
+				p.Send(terminal.RemoteChat{Remote: member, From: room.Speaker(ev.From, ev.Color), Text: ev.Text})
+			case room.Left:
+				p.Send(terminal.RemoteChat{Remote: member, Text: ev.Notice()})
+			}
+		}
+		// The room dropped us.
+		p.Quit()
+	}()
+	return nil
+}
+
+// roomTitle names the room's buffer after the room and the member's seat.
+func roomTitle(m *room.Member) string {
+	seat := "watching"
+	if m.Color != board.Empty {
+		seat = strings.ToLower(m.Color.String())
+	}
+	return fmt.Sprintf("room %s (%s)", m.Room().ID, seat)
+}
+
+func parseSeat(raw string) board.Color {
+	switch raw {
+	case "black", "b":
+		return board.Black
+	case "white", "w":
+		return board.White
+	}
+	return board.Empty
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vimgo/vimgo/internal/asciicast"
+	"github.com/vimgo/vimgo/internal/protocol"
+)
+
+// terminalModes are the terminal modes VimGo turns on when it starts: the
+// alternate screen, a hidden cursor and mouse reporting. A reattaching
+// browser gets a fresh xterm.js, so they are sent again before the
+// redraw.
+const terminalModes = "\x1b[?1049h\x1b[?25l\x1b[?1002h\x1b[?1006h"
+
+// writeTimeout is how long a write to a client may take. A client that
+// stops reading is disconnected once it is over, instead of holding up
+// the session's output.
+var writeTimeout = 10 * time.Second
+
+// session is what a client runs on the server: its own VimGo, a seat in
+// a room or a board. It outlives the websocket that started it, so that a
+// reloaded page takes it up again by sending its token back; a session
+// left without a client for the grace period, or whose client sent
+// nothing for the idle timeout, is ended by the reaper.
+type session struct {
+	token string
+	// id names the session in logs, where the token, which gives
+	// control of it, is kept out.
+	id  string
+	log *slog.Logger
+	// started is when the session started.
+	started time.Time
+	// kind tells what runs, such as "pty" or "room:<id>": a token only
+	// takes up a session of the kind asked for.
+	kind string
+	// watching sessions only show a game, and never idle out.
+	watching bool
+
+	// handle carries out a message read from the client.
+	handle func(msgType int, payload []byte)
+	// redraw brings a client that reattached up to date.
+	redraw func()
+	// stop ends the session; done is closed once it ended.
+	stop func()
+	done chan struct{}
+
+	// cast records the output of a terminal session, as the recording
+	// replay, when the server records sessions.
+	cast   *asciicast.Recorder
+	replay string
+
+	// wmu keeps writes to the client, which may block until writeTimeout,
+	// one at a time and out of mu.
+	wmu      sync.Mutex
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	detached time.Time
+	// active is when the client last sent something.
+	active time.Time
+}
+
+// Write sends p to the client as a binary message. Without a client the
+// output is dropped: the redraw after reattaching makes up for it. The
+// recording gets it all the same.
+func (s *session) Write(p []byte) (int, error) {
+	if s.cast != nil {
+		_ = s.cast.Output(p)
+	}
+	s.send(websocket.BinaryMessage, p)
+	return len(p), nil
+}
+
+// resized records that the client's terminal is now cols by rows.
+func (s *session) resized(cols, rows int) {
+	if s.cast != nil {
+		_ = s.cast.Resize(cols, rows)
+	}
+}
+
+// writeJSON sends v to the client as a text message.
+func (s *session) writeJSON(v any) {
+	p, err := json.Marshal(v)
+	if err != nil {
+		s.log.Error("encoding message", "err", err)
+		return
+	}
+	s.send(websocket.TextMessage, p)
+}
+
+// writeErr tells the client about an error.
+func (s *session) writeErr(msg string) {
+	s.send(websocket.TextMessage, errMessage(msg))
+}
+
+func (s *session) send(msgType int, p []byte) {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+	if conn != nil {
+		s.write(conn, msgType, p)
+	}
+}
+
+// write sends a message to conn, closing it if the client is too slow to
+// take it: its reads then fail and it is detached.
+func (s *session) write(conn *websocket.Conn, msgType int, p []byte) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	if err := writeMessage(conn, msgType, p); err != nil {
+		conn.Close()
+	}
+}
+
+// writeMessage writes a message of type msgType to conn within
+// writeTimeout, counting its bytes.
+func writeMessage(conn *websocket.Conn, msgType int, p []byte) error {
+	stats.bytesOut.Add(int64(len(p)))
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteMessage(msgType, p)
+}
+
+// attach makes conn the session's client, sending it the token. A client
+// still attached, such as the same page open twice, is disconnected.
+func (s *session) attach(conn *websocket.Conn) {
+	// Output sent to conn from now on waits for the token to go first.
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.mu.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.conn, s.detached, s.active = conn, time.Time{}, time.Now()
+	s.mu.Unlock()
+
+	msg := protocol.NewSession(s.token)
+	msg.Replay = s.replay
+	if p, err := json.Marshal(msg); err == nil {
+		if err := writeMessage(conn, websocket.TextMessage, p); err != nil {
+			conn.Close()
+		}
+	}
+}
+
+// touch records that the client sent something.
+func (s *session) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = time.Now()
+}
+
+// detach lets go of conn if it is still the session's client.
+func (s *session) detach(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == conn {
+		s.conn, s.detached = nil, time.Now()
+	}
+}
+
+// expired reports whether the session has been without a client since
+// before abandoned, or without word from it since before idle.
+func (s *session) expired(abandoned, idle time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil && s.detached.Before(abandoned) {
+		return true
+	}
+	return !s.watching && s.active.Before(idle)
+}
+
+var (
+	errTooManySessions = errors.New("too many sessions, try again later")
+	errTooManyConns    = errors.New("too many connections from your address")
+	errShuttingDown    = errors.New("server shutting down")
+)
+
+// sessions keeps the running sessions by token, within the limits of the
+// server's config.
+type sessions struct {
+	cfg config
+
+	mu     sync.Mutex
+	tokens map[string]*session
+	// starting counts the sessions admitted but not yet added.
+	starting int
+	conns    map[string]int
+	draining bool
+}
+
+func newSessions(cfg config) *sessions {
+	return &sessions{cfg: cfg, tokens: make(map[string]*session), conns: make(map[string]int)}
+}
+
+// serve upgrades the request to a websocket and runs on it the session of
+// the kind given: the one whose token came with ?session=, redrawn, or
+// else a new one set up by start. start fills in the session's functions
+// and starts what it runs, or reports why it could not to the client.
+// A terminal session has a title, under which it is recorded when the
+// server records sessions. serve returns when the client goes away.
+func (sm *sessions) serve(w http.ResponseWriter, r *http.Request, kind, title string, start func(s *session) error) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	s := sm.get(r.URL.Query().Get("session"), kind)
+	fresh := s == nil
+	if err := sm.admit(ip, fresh); err != nil {
+		code := http.StatusServiceUnavailable
+		if err == errTooManyConns {
+			code = http.StatusTooManyRequests
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	defer sm.release(ip)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("websocket upgrade failed", "kind", kind, "ip", ip, "err", err)
+		if fresh {
+			sm.add(nil)
+		}
+		return
+	}
+	defer conn.Close()
+
+	if !fresh {
+		s.log.Info("session reattached", "ip", ip)
+		s.attach(conn)
+		s.redraw()
+	} else {
+		s = &session{token: newToken(), id: newToken()[:8], kind: kind, started: time.Now(), done: make(chan struct{})}
+		s.log = slog.With("session", s.id, "kind", kind)
+		s.log.Info("session started", "ip", ip)
+		if title != "" {
+			sm.record(s, title)
+		}
+		s.attach(conn)
+		if err := start(s); err != nil {
+			sm.add(nil)
+			if s.cast != nil {
+				s.cast.Close()
+			}
+			return
+		}
+		if !sm.add(s) {
+			// The server began shutting down while s started.
+			s.stop()
+			if s.cast != nil {
+				s.cast.Close()
+			}
+			return
+		}
+		go func() {
+			<-s.done
+			sm.remove(s)
+			d := time.Since(s.started)
+			stats.ended(d)
+			s.log.Info("session ended", "seconds", d.Round(time.Millisecond).Seconds())
+			if s.cast != nil {
+				s.cast.Close()
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.conn != nil {
+				s.conn.Close()
+			}
+		}()
+	}
+
+	for {
+		msgType, payload, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		stats.bytesIn.Add(int64(len(payload)))
+		s.touch()
+		s.handle(msgType, payload)
+	}
+	s.log.Info("client disconnected")
+	s.detach(conn)
+	if sm.cfg.grace <= 0 && sm.remove(s) {
+		s.stop()
+	}
+}
+
+// record has session s recorded in the server's recording directory, if
+// it has one.
+func (sm *sessions) record(s *session, title string) {
+	if sm.cfg.recordDir == "" {
+		return
+	}
+	cast, id, err := asciicast.Create(sm.cfg.recordDir, asciicast.Header{
+		Width:  ptyCols,
+		Height: ptyRows,
+		Title:  title,
+		Env:    map[string]string{"TERM": "xterm-256color"},
+	})
+	if err != nil {
+		s.log.Error("recording failed", "err", err)
+		return
+	}
+	s.log.Info("recording", "replay", id)
+	s.cast, s.replay = cast, id
+}
+
+// count returns the number of sessions running, by the kinds of
+// sessionKinds.
+func (sm *sessions) count() map[string]int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	n := make(map[string]int)
+	for _, s := range sm.tokens {
+		n[kindOf(s.kind)]++
+	}
+	return n
+}
+
+// ready reports whether new sessions are taken.
+func (sm *sessions) ready() bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return !sm.draining
+}
+
+// admit counts a connection from ip, if the limits allow it, and when
+// fresh holds a place for a new session until add. Once done with the
+// connection, release it.
+func (sm *sessions) admit(ip string, fresh bool) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	switch {
+	case sm.draining:
+		return errShuttingDown
+	case fresh && sm.cfg.maxSessions > 0 && len(sm.tokens)+sm.starting >= sm.cfg.maxSessions:
+		return errTooManySessions
+	case sm.cfg.maxPerIP > 0 && sm.conns[ip] >= sm.cfg.maxPerIP:
+		return errTooManyConns
+	}
+	sm.conns[ip]++
+	if fresh {
+		sm.starting++
+	}
+	return nil
+}
+
+func (sm *sessions) release(ip string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.conns[ip]--; sm.conns[ip] <= 0 {
+		delete(sm.conns, ip)
+	}
+}
+
+func (sm *sessions) get(token, kind string) *session {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if s := sm.tokens[token]; s != nil && s.kind == kind {
+		return s
+	}
+	return nil
+}
+
+// add keeps s, started in the place admit held for it, unless the server
+// is shutting down. A nil s gives the place up.
+func (sm *sessions) add(s *session) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.starting--
+	if s == nil || sm.draining {
+		return false
+	}
+	sm.tokens[s.token] = s
+	return true
+}
+
+// remove forgets s, reporting whether it was still kept.
+func (sm *sessions) remove(s *session) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.tokens[s.token] != s {
+		return false
+	}
+	delete(sm.tokens, s.token)
+	return true
+}
+
+// reap ends, every interval, the sessions that have been without a client
+// for longer than the grace period or idle for longer than the idle
+// timeout.
+func (sm *sessions) reap(interval time.Duration) {
+	for range time.Tick(interval) {
+		now := time.Now()
+		idle := time.Time{}
+		if sm.cfg.idleTimeout > 0 {
+			idle = now.Add(-sm.cfg.idleTimeout)
+		}
+		for _, s := range sm.list() {
+			if s.expired(now.Add(-sm.cfg.grace), idle) && sm.remove(s) {
+				s.log.Info("session expired")
+				s.stop()
+			}
+		}
+	}
+}
+
+// list returns the sessions running, to be looked at without holding
+// sm.mu.
+func (sm *sessions) list() []*session {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	all := make([]*session, 0, len(sm.tokens))
+	for _, s := range sm.tokens {
+		all = append(all, s)
+	}
+	return all
+}
+
+// shutdown ends every session, taking no new ones, and waits until they
+// are all done or ctx is.
+func (sm *sessions) shutdown(ctx context.Context) error {
+	sm.mu.Lock()
+	sm.draining = true
+	sm.mu.Unlock()
+	all := sm.list()
+
+	for _, s := range all {
+		s.stop()
+	}
+	for _, s := range all {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// newToken returns a random session token, hard to guess as it gives
+// control of the session.
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAdmitAndRelease(t *testing.T) {
+	sm := newSessions(config{maxSessions: 2, maxPerIP: 2})
+
+	if err := sm.admit("10.0.0.1", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sm.admit("10.0.0.1", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sm.admit("10.0.0.1", false); err != errTooManyConns {
+		t.Fatalf("expected a third connection from one address refused, got %v", err)
+	}
+	// Two sessions are starting: a new one from elsewhere waits, while a
+	// client taking up its session is let in.
+	if err := sm.admit("10.0.0.2", true); err != errTooManySessions {
+		t.Fatalf("expected a third session refused, got %v", err)
+	}
+	if err := sm.admit("10.0.0.2", false); err != nil {
+		t.Fatalf("expected a reconnection let in, got %v", err)
+	}
+	sm.release("10.0.0.2")
+
+	// A session that failed to start gives its place up; one that started
+	// keeps it.
+	a, b := &session{token: "a"}, &session{token: "b"}
+	sm.add(nil)
+	sm.add(a)
+	sm.release("10.0.0.1")
+	if sm.starting != 0 || sm.conns["10.0.0.1"] != 1 {
+		t.Fatalf("expected 0 starting and 1 connection, got %d and %v", sm.starting, sm.conns)
+	}
+	if err := sm.admit("10.0.0.2", true); err != nil {
+		t.Fatalf("expected a place for a new session, got %v", err)
+	}
+	if err := sm.admit("10.0.0.3", true); err != errTooManySessions {
+		t.Fatalf("expected the sessions full again, got %v", err)
+	}
+	sm.add(b)
+	sm.release("10.0.0.2")
+	sm.release("10.0.0.1")
+	if len(sm.conns) != 0 {
+		t.Fatalf("expected no connections left, got %v", sm.conns)
+	}
+	// Sessions that ended free their places.
+	sm.remove(a)
+	sm.remove(b)
+	if err := sm.admit("10.0.0.3", true); err != nil {
+		t.Fatalf("expected a place once sessions ended, got %v", err)
+	}
+	sm.add(nil)
+	sm.release("10.0.0.3")
+
+	sm.shutdown(t.Context())
+	if err := sm.admit("10.0.0.3", false); err != errShuttingDown {
+		t.Fatalf("expected connections refused while shutting down, got %v", err)
+	}
+	if sm.ready() {
+		t.Fatalf("expected the server not ready while shutting down")
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	now := time.Now()
+	abandoned, idle := now.Add(-time.Minute), now.Add(-30*time.Minute)
+	for _, tc := range []struct {
+		name     string
+		s        *session
+		expected bool
+	}{
+		{"attached and active", &session{conn: &websocket.Conn{}, active: now}, false},
+		{"attached and idle", &session{conn: &websocket.Conn{}, active: now.Add(-time.Hour)}, true},
+		{"watching and idle", &session{conn: &websocket.Conn{}, watching: true, active: now.Add(-time.Hour)}, false},
+		{"detached within grace", &session{detached: now.Add(-time.Second), active: now.Add(-time.Second)}, false},
+		{"detached past grace", &session{detached: now.Add(-2 * time.Minute), active: now.Add(-2 * time.Minute)}, true},
+		{"watching detached past grace", &session{watching: true, detached: now.Add(-2 * time.Minute)}, true},
+	} {
+		if got := tc.s.expired(abandoned, idle); got != tc.expected {
+			t.Errorf("%s: expected expired %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+
+	// Without an idle timeout the reaper passes the zero time.
+	s := &session{conn: &websocket.Conn{}, active: now.Add(-24 * time.Hour)}
+	if s.expired(abandoned, time.Time{}) {
+		t.Errorf("expected no idle expiry without an idle timeout")
+	}
+}
+
+func TestSendToStalledClient(t *testing.T) {
+	defer func(d time.Duration) { writeTimeout = d }(writeTimeout)
+	writeTimeout = 100 * time.Millisecond
+
+	conns := make(chan *websocket.Conn)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	defer srv.Close()
+	// The client never reads.
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	s := &session{token: "a", log: slog.Default()}
+	conn := <-conns
+	s.attach(conn)
+	sm := newSessions(config{})
+	sm.tokens[s.token] = s
+
+	done := make(chan struct{})
+	go func() {
+		p := make([]byte, 64<<10)
+		for i := 0; i < 1000; i++ {
+			s.send(websocket.BinaryMessage, p)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sending to a client that does not read blocked")
+	}
+	// The session can be looked at while its client is stalled.
+	if s.expired(time.Now().Add(-time.Hour), time.Time{}) || len(sm.list()) != 1 {
+		t.Fatalf("expected the session kept")
+	}
+	if _, _, err := conn.NextReader(); err == nil {
+		t.Fatalf("expected the stalled client disconnected")
+	}
+}
@@ -0,0 +1,177 @@
+// Package asciicast records terminal sessions in the asciicast v2 format
+// of asciinema: a line with the JSON Header, then a JSON array per event,
+// [time, code, data], time being seconds since the start, code "o" for
+// output and "r" for a resize to data's COLSxROWS.
+package asciicast
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Ext is the extension of recordings.
+const Ext = ".cast"
+
+// Header describes a recording. Timestamp is the Unix time it started.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes the events of a terminal session to a recording. It is
+// safe for use by several goroutines.
+type Recorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	now   func() time.Time
+	// pending is the start of a UTF-8 sequence cut at the end of the last
+	// output, held back until the rest comes: events hold text, not
+	// bytes.
+	pending []byte
+	err     error
+}
+
+// NewRecorder starts a recording on w with the header h, filling in its
+// version and, if zero, its timestamp.
+func NewRecorder(w io.Writer, h Header) (*Recorder, error) {
+	return newRecorder(w, h, time.Now)
+}
+
+func newRecorder(w io.Writer, h Header, now func() time.Time) (*Recorder, error) {
+	r := &Recorder{w: w, start: now(), now: now}
+	h.Version = 2
+	if h.Timestamp == 0 {
+		h.Timestamp = r.start.Unix()
+	}
+	line, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Create starts a recording in a new file of dir, named after a random
+// ID, which File finds again.
+func Create(dir string, h Header) (*Recorder, string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	id := hex.EncodeToString(b)
+	f, err := os.OpenFile(filepath.Join(dir, id+Ext), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, "", err
+	}
+	r, err := NewRecorder(f, h)
+	if err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	return r, id, nil
+}
+
+// File returns the file of dir holding the recording with the given ID,
+// or false when id is not one Create makes.
+func File(dir, id string) (string, bool) {
+	if len(id) != 16 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, id+Ext), true
+}
+
+// Output records p, written to the terminal.
+func (r *Recorder) Output(p []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := append(r.pending, p...)
+	cut := incomplete(data)
+	r.pending = append([]byte(nil), data[len(data)-cut:]...)
+	if len(data) == cut {
+		return r.err
+	}
+	return r.event("o", string(data[:len(data)-cut]))
+}
+
+// Resize records that the terminal is now cols wide and rows high.
+func (r *Recorder) Resize(cols, rows int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close records the output still held back and, if the recording's
+// writer is an io.Closer, closes it. Later events are dropped.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == errClosed {
+		return nil
+	}
+	if len(r.pending) > 0 {
+		r.event("o", string(r.pending))
+		r.pending = nil
+	}
+	err := r.err
+	r.err = errClosed
+	if c, ok := r.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+var errClosed = errors.New("asciicast: recording closed")
+
+// event writes an event of the given code, stopping at the first error.
+func (r *Recorder) event(code, data string) error {
+	if r.err != nil {
+		return r.err
+	}
+	t := r.now().Sub(r.start).Seconds()
+	line, err := json.Marshal([]any{math.Round(t*1e6) / 1e6, code, data})
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+	r.err = err
+	return err
+}
+
+// incomplete returns the length of the UTF-8 sequence started but not
+// finished at the end of p.
+func incomplete(p []byte) int {
+	for n := 1; n <= utf8.UTFMax-1 && n <= len(p); n++ {
+		c := p[len(p)-n]
+		if c < utf8.RuneSelf {
+			return 0
+		}
+		if utf8.RuneStart(c) {
+			if utf8.FullRune(p[len(p)-n:]) {
+				return 0
+			}
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package asciicast
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	var out strings.Builder
+	start := time.Unix(1700000000, 0)
+	now := start
+	r, err := newRecorder(&out, Header{Width: 80, Height: 24, Env: map[string]string{"TERM": "xterm-256color"}}, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("newRecorder failed: %v", err)
+	}
+
+	now = start.Add(1500 * time.Millisecond)
+	r.Output([]byte("\x1b[Hhello"))
+	now = start.Add(2 * time.Second)
+	r.Resize(100, 30)
+	// A character cut in two is recorded once whole.
+	stone := []byte("●")
+	r.Output(append([]byte("a"), stone[:1]...))
+	r.Output(stone[1:2])
+	now = start.Add(2250 * time.Millisecond)
+	r.Output(stone[2:])
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := r.Output([]byte("late")); err == nil {
+		t.Fatalf("expected output after Close to fail")
+	}
+
+	want := []string{
+		`{"version":2,"width":80,"height":24,"timestamp":1700000000,"env":{"TERM":"xterm-256color"}}`,
+		`[1.5,"o","\u001b[Hhello"]`,
+		`[2,"r","100x30"]`,
+		`[2,"o","a"]`,
+		`[2.25,"o","●"]`,
+	}
+	if got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestRecorder_CloseFlushesCutCharacter(t *testing.T) {
+	var out strings.Builder
+	r, _ := NewRecorder(&out, Header{Width: 80, Height: 24})
+	r.Output([]byte("x\xe2\x97"))
+	r.Close()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], `"x"`) || !strings.Contains(lines[2], `"�`) {
+		t.Fatalf("expected the cut character recorded at Close, got %q", lines)
+	}
+}
+
+func TestCreateAndFile(t *testing.T) {
+	dir := t.TempDir()
+	r, id, err := Create(dir, Header{Width: 80, Height: 24, Title: "VimGo"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	r.Output([]byte("hi"))
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	path, ok := File(dir, id)
+	if !ok || path != filepath.Join(dir, id+Ext) {
+		t.Fatalf("File(%q) = %q, %v", id, path, ok)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the recording: %v", err)
+	}
+	if !strings.Contains(string(data), `"title":"VimGo"`) || !strings.Contains(string(data), `"o","hi"]`) {
+		t.Fatalf("unexpected recording %q", data)
+	}
+
+	for _, bad := range []string{"", "..", "../../etc/passwd", strings.Repeat("g", 16), id + "0"} {
+		if _, ok := File(dir, bad); ok {
+			t.Errorf("expected File to refuse %q", bad)
+		}
+	}
+}
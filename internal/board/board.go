@@ -75,3 +75,16 @@ func (b *Board) Copy() *Board {
 	copy(newB.Grid, b.Grid)
 	return newB
 }
+
+// IsStar reports whether (x, y) is a star point (hoshi) on 9, 13 and 19 boards.
+func (b *Board) IsStar(x, y int) bool {
+	switch b.Size {
+	case 19:
+		return (x == 3 || x == 9 || x == 15) && (y == 3 || y == 9 || y == 15)
+	case 13:
+		return (x == 3 || x == 6 || x == 9) && (y == 3 || y == 6 || y == 9)
+	case 9:
+		return (x == 2 || x == 6) && (y == 2 || y == 6) || (x == 4 && y == 4)
+	}
+	return false
+}
@@ -12,3 +12,30 @@ func TestBoard(t *testing.T) {
 		t.Errorf("expected Black at (0,0), got %v", b.At(0, 0))
 	}
 }
+
+func TestRectNormalizesCorners(t *testing.T) {
+	r := NewRect(Point{X: 4, Y: 1}, Point{X: 2, Y: 3})
+	if r.Min != (Point{X: 2, Y: 1}) || r.Max != (Point{X: 4, Y: 3}) {
+		t.Fatalf("unexpected rect %+v", r)
+	}
+	if r.Width() != 3 || r.Height() != 3 {
+		t.Fatalf("expected 3x3, got %dx%d", r.Width(), r.Height())
+	}
+	if len(r.Points()) != 9 {
+		t.Fatalf("expected 9 points, got %d", len(r.Points()))
+	}
+}
+
+func TestExtractPattern(t *testing.T) {
+	b := New(9)
+	b.Set(1, 1, Black)
+	b.Set(2, 1, White)
+
+	p := b.Extract(NewRect(Point{X: 1, Y: 1}, Point{X: 2, Y: 2}))
+	if p.Width != 2 || p.Height != 2 {
+		t.Fatalf("expected 2x2 pattern, got %dx%d", p.Width, p.Height)
+	}
+	if p.At(0, 0) != Black || p.At(1, 0) != White || p.At(0, 1) != Empty {
+		t.Fatalf("unexpected pattern cells %v", p.Cells)
+	}
+}
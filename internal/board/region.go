@@ -0,0 +1,72 @@
+package board
+
+// Rect is an inclusive rectangle of intersections, e.g. a visual selection.
+type Rect struct {
+	Min, Max Point
+}
+
+// NewRect returns the rectangle spanned by two corners given in any order.
+func NewRect(a, b Point) Rect {
+	r := Rect{Min: a, Max: b}
+	if r.Min.X > r.Max.X {
+		r.Min.X, r.Max.X = r.Max.X, r.Min.X
+	}
+	if r.Min.Y > r.Max.Y {
+		r.Min.Y, r.Max.Y = r.Max.Y, r.Min.Y
+	}
+	return r
+}
+
+func (r Rect) Width() int {
+	return r.Max.X - r.Min.X + 1
+}
+
+func (r Rect) Height() int {
+	return r.Max.Y - r.Min.Y + 1
+}
+
+func (r Rect) Contains(x, y int) bool {
+	return x >= r.Min.X && x <= r.Max.X && y >= r.Min.Y && y <= r.Max.Y
+}
+
+// Points lists the intersections of r row by row.
+func (r Rect) Points() []Point {
+	points := make([]Point, 0, r.Width()*r.Height())
+	for y := r.Min.Y; y <= r.Max.Y; y++ {
+		for x := r.Min.X; x <= r.Max.X; x++ {
+			points = append(points, Point{X: x, Y: y})
+		}
+	}
+	return points
+}
+
+// Pattern is a rectangular snapshot of board contents, detached from any
+// particular position so it can be pasted elsewhere.
+type Pattern struct {
+	Width  int
+	Height int
+	Cells  []Color
+}
+
+func (p *Pattern) At(x, y int) Color {
+	if x < 0 || x >= p.Width || y < 0 || y >= p.Height {
+		return Empty
+	}
+	return p.Cells[y*p.Width+x]
+}
+
+// Extract copies the contents of r into a new pattern. Parts of r that lie
+// off the board are read as empty.
+func (b *Board) Extract(r Rect) *Pattern {
+	p := &Pattern{
+		Width:  r.Width(),
+		Height: r.Height(),
+		Cells:  make([]Color, r.Width()*r.Height()),
+	}
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			p.Cells[y*p.Width+x] = b.At(r.Min.X+x, r.Min.Y+y)
+		}
+	}
+	return p
+}
@@ -0,0 +1,51 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// ColumnLabel returns the letter used for column x, skipping 'I' as usual in Go.
+func ColumnLabel(x int) string {
+	if x >= 8 {
+		x++
+	}
+	return string(rune('A' + x))
+}
+
+// ASCII renders the part of b inside r as a plain-text diagram with
+// coordinates, using X for Black, O for White, + for star points and . for
+// other empty points. The result is suitable for pasting into mails or
+// problem collections.
+func ASCII(b *board.Board, r board.Rect) string {
+	var sb strings.Builder
+
+	sb.WriteString("   ")
+	for x := r.Min.X; x <= r.Max.X; x++ {
+		sb.WriteString(" " + ColumnLabel(x))
+	}
+	sb.WriteString("\n")
+
+	for y := r.Min.Y; y <= r.Max.Y; y++ {
+		sb.WriteString(fmt.Sprintf("%3d", b.Size-y))
+		for x := r.Min.X; x <= r.Max.X; x++ {
+			sb.WriteString(" ")
+			switch b.At(x, y) {
+			case board.Black:
+				sb.WriteString("X")
+			case board.White:
+				sb.WriteString("O")
+			default:
+				if b.IsStar(x, y) {
+					sb.WriteString("+")
+				} else {
+					sb.WriteString(".")
+				}
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
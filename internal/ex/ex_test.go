@@ -0,0 +1,101 @@
+package ex
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRangeBangAndChain(t *testing.T) {
+	cmds, err := Parse("10,$-1d | w! out.sgf|q")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cmds) != 3 {
+		t.Fatalf("expected 3 commands, got %d", len(cmds))
+	}
+
+	d := cmds[0]
+	if d.Name != "d" || d.Range == nil {
+		t.Fatalf("unexpected first command %+v", d)
+	}
+	start, end, err := d.Range.Resolve(5, 30)
+	if err != nil || start != 10 || end != 29 {
+		t.Fatalf("expected range 10-29, got %d-%d (%v)", start, end, err)
+	}
+
+	w := cmds[1]
+	if w.Name != "w" || !w.Bang || w.Arg != "out.sgf" {
+		t.Fatalf("unexpected second command %+v", w)
+	}
+}
+
+func TestParseVisualRangeAndBareNumber(t *testing.T) {
+	cmds, err := Parse("'<,'>count")
+	if err != nil || !cmds[0].Range.Visual || cmds[0].Name != "count" {
+		t.Fatalf("unexpected parse %+v (%v)", cmds, err)
+	}
+
+	cmds, err = Parse("42")
+	if err != nil || cmds[0].Name != "" || cmds[0].Range.Start.Number != 42 {
+		t.Fatalf("unexpected parse %+v (%v)", cmds, err)
+	}
+}
+
+func TestRegistryAbbreviations(t *testing.T) {
+	r := NewRegistry(
+		Spec{Name: "w[rite]", Bang: true},
+		Spec{Name: "c[oordinates]"},
+		Spec{Name: "cou[nt]", Range: true},
+	)
+
+	for name, want := range map[string]string{"w": "write", "writ": "write", "co": "coordinates", "cou": "count"} {
+		s, err := r.Lookup(name)
+		if err != nil || s.full() != want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q", name, s.full(), err, want)
+		}
+	}
+	if _, err := r.Lookup("writes"); err == nil {
+		t.Errorf("expected error for unknown command")
+	}
+
+	cmd := Command{Name: "c", Range: &Range{Visual: true}}
+	if _, err := r.Check(&cmd); err == nil {
+		t.Errorf("expected E481 for range on :coordinates")
+	}
+}
+
+func TestCompleteCommandsAndOptions(t *testing.T) {
+	r := NewRegistry(
+		Spec{Name: "se[t]", Complete: CompleteOption},
+		Spec{Name: "sc[ore]"},
+	)
+
+	start, got := r.Complete("10|s", nil)
+	if start != 3 || len(got) != 2 || got[0] != "score" || got[1] != "set" {
+		t.Fatalf("unexpected command completion %d %v", start, got)
+	}
+
+	start, got = r.Complete("set noco", []string{"coords", "komi"})
+	if start != 6 || len(got) != 1 || got[0] != "coords" {
+		t.Fatalf("unexpected option completion %d %v", start, got)
+	}
+}
+
+func TestWithoutFiles(t *testing.T) {
+	r := NewRegistry(Spec{Name: "w[rite]", Complete: CompleteFile})
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "game.sgf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, got := r.Complete("w "+dir+"/g", nil); len(got) != 1 {
+		t.Fatalf("expected a file completion, got %v", got)
+	}
+	if _, got := r.WithoutFiles().Complete("w "+dir+"/g", nil); len(got) != 0 {
+		t.Fatalf("expected no file completion, got %v", got)
+	}
+	if _, got := r.WithoutFiles().Complete("wr", nil); len(got) != 1 || got[0] != "write" {
+		t.Fatalf("expected the command still completed, got %v", got)
+	}
+}
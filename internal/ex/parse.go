@@ -0,0 +1,216 @@
+package ex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Command is one command of an ex command line, e.g. "10,20d" or "w! out.sgf".
+type Command struct {
+	Range *Range
+	// Name is the command as typed; Registry.Lookup resolves abbreviations.
+	Name string
+	Bang bool
+	// Arg is the rest of the command, with surrounding blanks removed.
+	Arg string
+}
+
+// Args splits Arg on blanks.
+func (c Command) Args() []string {
+	return strings.Fields(c.Arg)
+}
+
+// Range is a command range. Addresses count moves, except for the Visual
+// range '<,'> which stands for the last rectangular selection.
+type Range struct {
+	Start  Address
+	End    Address
+	Visual bool
+}
+
+// AddressKind says how an Address is resolved.
+type AddressKind int
+
+const (
+	AddrNumber  AddressKind = iota // an absolute move number
+	AddrCurrent                    // "." the current move
+	AddrLast                       // "$" the last move of the line
+)
+
+// Address is a single move address with an optional +/- offset.
+type Address struct {
+	Kind   AddressKind
+	Number int
+	Offset int
+}
+
+// Resolve turns the address into a move number.
+func (a Address) Resolve(current, last int) int {
+	n := a.Number
+	switch a.Kind {
+	case AddrCurrent:
+		n = current
+	case AddrLast:
+		n = last
+	}
+	return n + a.Offset
+}
+
+// Resolve returns the first and last move numbers of the range and checks
+// them against the line, which has moves 0 (the start) to last.
+func (r *Range) Resolve(current, last int) (int, int, error) {
+	start := r.Start.Resolve(current, last)
+	end := r.End.Resolve(current, last)
+	if start > end {
+		start, end = end, start
+	}
+	if start < 0 || end > last {
+		return 0, 0, fmt.Errorf("E16: Invalid range")
+	}
+	return start, end, nil
+}
+
+// Parse splits a command line on unescaped "|" and parses each command.
+func Parse(line string) ([]Command, error) {
+	var cmds []Command
+	for _, part := range splitBar(line) {
+		part = strings.TrimLeft(part, " \t:")
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		cmd, err := parseCommand(part)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+func splitBar(line string) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case line[i] == '|':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(line[i])
+		}
+	}
+	return append(parts, cur.String())
+}
+
+func parseCommand(s string) (Command, error) {
+	var cmd Command
+
+	rng, rest, err := parseRange(s)
+	if err != nil {
+		return cmd, err
+	}
+	cmd.Range = rng
+	rest = strings.TrimLeft(rest, " \t")
+
+	// Names are alphabetic; a few commands such as ":?" are one symbol.
+	n := 0
+	for n < len(rest) && isLetter(rest[n]) {
+		n++
+	}
+	if n == 0 && len(rest) > 0 && rest[0] == '?' {
+		n = 1
+	}
+	cmd.Name = rest[:n]
+	rest = rest[n:]
+
+	if strings.HasPrefix(rest, "!") {
+		cmd.Bang = true
+		rest = rest[1:]
+	}
+	cmd.Arg = strings.TrimSpace(rest)
+	if cmd.Name == "" && cmd.Arg != "" {
+		return cmd, fmt.Errorf("E492: Not an editor command: %s", strings.TrimSpace(s))
+	}
+	return cmd, nil
+}
+
+func parseRange(s string) (*Range, string, error) {
+	if rest, ok := strings.CutPrefix(s, "'<,'>"); ok {
+		return &Range{Visual: true}, rest, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "%"); ok {
+		return &Range{Start: Address{Kind: AddrNumber, Number: 1}, End: Address{Kind: AddrLast}}, rest, nil
+	}
+
+	start, rest, ok, err := parseAddress(s)
+	if err != nil || !ok {
+		return nil, s, err
+	}
+	r := &Range{Start: start, End: start}
+	if after, found := strings.CutPrefix(rest, ","); found {
+		end, rest2, ok, err := parseAddress(after)
+		if err != nil {
+			return nil, s, err
+		}
+		if !ok {
+			return nil, s, fmt.Errorf("E14: Invalid address")
+		}
+		r.End = end
+		rest = rest2
+	}
+	return r, rest, nil
+}
+
+func parseAddress(s string) (Address, string, bool, error) {
+	var a Address
+	i := 0
+	switch {
+	case i < len(s) && s[i] == '.':
+		a.Kind = AddrCurrent
+		i++
+	case i < len(s) && s[i] == '$':
+		a.Kind = AddrLast
+		i++
+	case i < len(s) && isDigit(s[i]):
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		a.Number, _ = strconv.Atoi(s[:i])
+	case i < len(s) && (s[i] == '+' || s[i] == '-'):
+		// A bare offset is relative to the current move.
+		a.Kind = AddrCurrent
+	default:
+		return a, s, false, nil
+	}
+
+	for i < len(s) && (s[i] == '+' || s[i] == '-') {
+		sign := 1
+		if s[i] == '-' {
+			sign = -1
+		}
+		i++
+		j := i
+		for j < len(s) && isDigit(s[j]) {
+			j++
+		}
+		n := 1
+		if j > i {
+			n, _ = strconv.Atoi(s[i:j])
+		}
+		a.Offset += sign * n
+		i = j
+	}
+	return a, s[i:], true, nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,178 @@
+package ex
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Completion says what Tab completes in a command's argument.
+type Completion int
+
+const (
+	CompleteNone Completion = iota
+	CompleteFile
+	CompleteOption
+	CompleteCommand
+)
+
+// Spec describes an ex command. Name is written the Vim way, with the
+// optional part in brackets: "w[rite]" accepts :w, :wr, ... up to :write.
+type Spec struct {
+	Name     string
+	Bang     bool
+	Range    bool
+	Complete Completion
+}
+
+func (s Spec) full() string {
+	return strings.NewReplacer("[", "", "]", "").Replace(s.Name)
+}
+
+func (s Spec) minLen() int {
+	if i := strings.Index(s.Name, "["); i >= 0 {
+		return i
+	}
+	return len(s.Name)
+}
+
+// Registry resolves command names and abbreviations. When an abbreviation
+// matches several commands the one registered first wins, as Vim does.
+type Registry struct {
+	specs []Spec
+}
+
+func NewRegistry(specs ...Spec) *Registry {
+	return &Registry{specs: specs}
+}
+
+// Lookup returns the spec for name, which may be abbreviated.
+func (r *Registry) Lookup(name string) (Spec, error) {
+	for _, s := range r.specs {
+		full := s.full()
+		if len(name) >= s.minLen() && strings.HasPrefix(full, name) {
+			return s, nil
+		}
+	}
+	return Spec{}, fmt.Errorf("E492: Not an editor command: %s", name)
+}
+
+// Check resolves cmd.Name to the full command name and validates the range
+// and bang against the spec.
+func (r *Registry) Check(cmd *Command) (Spec, error) {
+	spec, err := r.Lookup(cmd.Name)
+	if err != nil {
+		return spec, err
+	}
+	if cmd.Range != nil && !spec.Range {
+		return spec, fmt.Errorf("E481: No range allowed")
+	}
+	if cmd.Bang && !spec.Bang {
+		return spec, fmt.Errorf("E477: No ! allowed")
+	}
+	cmd.Name = spec.full()
+	return spec, nil
+}
+
+// WithoutFiles returns a copy of the registry whose commands complete no
+// file names, for sessions that may not touch the files of the host.
+func (r *Registry) WithoutFiles() *Registry {
+	specs := make([]Spec, len(r.specs))
+	for i, s := range r.specs {
+		if s.Complete == CompleteFile {
+			s.Complete = CompleteNone
+		}
+		specs[i] = s
+	}
+	return &Registry{specs: specs}
+}
+
+// Names lists the full command names.
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.specs))
+	for i, s := range r.specs {
+		names[i] = s.full()
+	}
+	return names
+}
+
+// Complete returns completions for the command line up to the cursor: the
+// byte offset where the word being completed starts, and the candidates
+// that can replace it. options lists the names :set accepts.
+func (r *Registry) Complete(line string, options []string) (int, []string) {
+	// Only the last command of a chain is completed.
+	start := strings.LastIndex(line, "|") + 1
+	for start < len(line) && (line[start] == ' ' || line[start] == ':') {
+		start++
+	}
+	_, afterRange, err := parseRange(line[start:])
+	if err != nil {
+		return 0, nil
+	}
+	start = len(line) - len(afterRange)
+
+	// Still typing the command name?
+	nameEnd := start
+	for nameEnd < len(line) && isLetter(line[nameEnd]) {
+		nameEnd++
+	}
+	if nameEnd == len(line) {
+		return start, matching(r.Names(), line[start:])
+	}
+
+	spec, err := r.Lookup(line[start:nameEnd])
+	if err != nil {
+		return 0, nil
+	}
+	argStart := strings.LastIndexAny(line, " \t") + 1
+	if argStart <= nameEnd {
+		return 0, nil
+	}
+	word := line[argStart:]
+
+	switch spec.Complete {
+	case CompleteCommand:
+		return argStart, matching(r.Names(), word)
+	case CompleteOption:
+		if found := matching(options, word); len(found) > 0 || !strings.HasPrefix(word, "no") {
+			return argStart, found
+		}
+		// "noxxx" resets boolean option xxx.
+		return argStart + 2, matching(options, word[2:])
+	case CompleteFile:
+		return argStart, completeFile(word)
+	}
+	return 0, nil
+}
+
+func matching(words []string, prefix string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) && !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// completeFile lists paths starting with prefix; directories end in a slash
+// so completion can continue inside them.
+func completeFile(prefix string) []string {
+	matches, _ := filepath.Glob(globEscape(prefix) + "*")
+	for i, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			matches[i] = m + string(filepath.Separator)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func globEscape(s string) string {
+	return strings.NewReplacer("*", `\*`, "?", `\?`, "[", `\[`).Replace(s)
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/sgf"
+)
+
+// CopyMoves returns detached copies of moves from through to of the current
+// line, with the setup nodes that follow each of them. Move 0 stands for
+// the setup nodes before the first move, not counting the root.
+func (g *Game) CopyMoves(from, to int) ([]*Node, error) {
+	if from < 0 || to < from {
+		return nil, fmt.Errorf("invalid move range %d-%d", from, to)
+	}
+	var nodes []*Node
+	for _, n := range g.Line()[1:] {
+		if k := n.MoveNumber(); k >= from && k <= to {
+			nodes = append(nodes, n.clone())
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no moves %d-%d in this line", from, to)
+	}
+	return nodes, nil
+}
+
+// Graft plays copied nodes as a line below the current node and goes to its
+// end. Moves that already follow are reused. If a move is illegal here,
+// nothing is changed.
+func (g *Game) Graft(line []*Node) error {
+	original := g.Current
+	var first *Node
+	for _, n := range line {
+		var child *Node
+		if n.IsMove() {
+			child = g.Current.findMove(n.Color, n.Point)
+		}
+		if child == nil {
+			child = n.clone()
+			child.Parent = g.Current
+			g.Current.Children = append(g.Current.Children, child)
+			if first == nil {
+				first = child
+			}
+		}
+		if err := g.apply(child); err != nil {
+			if first != nil {
+				first.Parent.removeChild(first)
+			}
+			_ = g.GoTo(original)
+			return err
+		}
+		g.visited()
+	}
+	if first != nil {
+		g.changed()
+	}
+	return nil
+}
+
+// clone copies n's own properties, without its place in the tree.
+func (n *Node) clone() *Node {
+	c := &Node{Color: n.Color, PL: n.PL, Comment: n.Comment}
+	if n.Point != nil {
+		p := *n.Point
+		c.Point = &p
+	}
+	if n.Setup != nil {
+		c.Setup = make(map[board.Point]board.Color, len(n.Setup))
+		for p, color := range n.Setup {
+			c.Setup[p] = color
+		}
+	}
+	c.Extra = append(c.Extra, n.Extra...)
+	return c
+}
+
+// LineSGF writes copied nodes as a one-line SGF game for a board of the
+// given size.
+func LineSGF(size int, line []*Node) string {
+	root := &sgf.Node{Props: sgfHeader(size)}
+	parent := root
+	for _, n := range line {
+		out := exportNode(&Node{Color: n.Color, Point: n.Point, Setup: n.Setup, PL: n.PL, Comment: n.Comment, Extra: n.Extra})
+		parent.Children = []*sgf.Node{out}
+		parent = out
+	}
+	return sgf.Write(root)
+}
+
+// PositionSGF writes the current position as an SGF game whose root sets up
+// the stones and the player to move.
+func (g *Game) PositionSGF() string {
+	setup := &Node{Setup: make(map[board.Point]board.Color), PL: g.CurrentPlayer}
+	for y := 0; y < g.Board.Size; y++ {
+		for x := 0; x < g.Board.Size; x++ {
+			if c := g.Board.At(x, y); c != board.Empty {
+				setup.Setup[board.Point{X: x, Y: y}] = c
+			}
+		}
+	}
+	root := exportNode(setup)
+	root.Props = append(sgfHeader(g.Board.Size), root.Props...)
+	return sgf.Write(root)
+}
+
+func sgfHeader(size int) []sgf.Property {
+	return []sgf.Property{
+		{ID: "GM", Values: []string{"1"}},
+		{ID: "FF", Values: []string{"4"}},
+		{ID: "CA", Values: []string{"UTF-8"}},
+		{ID: "SZ", Values: []string{strconv.Itoa(size)}},
+	}
+}
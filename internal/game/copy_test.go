@@ -0,0 +1,72 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestCopyMovesAndGraft(t *testing.T) {
+	g := NewGame(9)
+	g.Move(2, 2)
+	g.Move(6, 6)
+	g.Current.Comment = "good"
+	g.Move(2, 6)
+
+	line, err := g.CopyMoves(2, 3)
+	if err != nil {
+		t.Fatalf("CopyMoves failed: %v", err)
+	}
+	if len(line) != 2 || line[0].Comment != "good" || line[0].Parent != nil {
+		t.Fatalf("expected two detached copies, got %+v", line)
+	}
+
+	// Graft the moves as a variation after move 1 of another game.
+	other := NewGame(9)
+	other.Move(4, 4)
+	other.MarkSaved()
+	if err := other.Graft(line); err != nil {
+		t.Fatalf("Graft failed: %v", err)
+	}
+	if other.Current.MoveNumber() != 3 || other.Board.At(2, 6) != board.Black || other.Current.Parent.Comment != "good" {
+		t.Fatalf("graft did not play the moves")
+	}
+	if !other.Modified() {
+		t.Fatalf("graft should modify the game")
+	}
+	if line[0].Parent != nil {
+		t.Fatalf("graft must not change the copied nodes")
+	}
+}
+
+func TestGraftIllegalMoveChangesNothing(t *testing.T) {
+	g := NewGame(9)
+	g.Move(2, 2)
+	g.Move(6, 6)
+	line, _ := g.CopyMoves(1, 2)
+
+	// 2,2 is taken, so the line cannot be played from here.
+	if err := g.Graft(line); err == nil {
+		t.Fatalf("expected error for an occupied point")
+	}
+	if g.Current.MoveNumber() != 2 || len(g.Current.Children) != 0 {
+		t.Fatalf("failed graft changed the tree")
+	}
+}
+
+func TestPositionSGF(t *testing.T) {
+	g := NewGame(9)
+	g.Move(0, 0)
+	g.Move(1, 0)
+	out := g.PositionSGF()
+	for _, want := range []string{"SZ[9]", "AB[aa]", "AW[ba]", "PL[B]"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %s in %s", want, out)
+		}
+	}
+	loaded, err := Load(out)
+	if err != nil || loaded.Board.At(1, 0) != board.White {
+		t.Fatalf("position SGF does not load back: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/sgf"
+)
+
+// Follow takes over the main line of other, a later state of the same game
+// such as one played over the network, keeping the variations of g. The
+// nodes g already has for other's moves are reused, with other's
+// properties; a move g has at the head of its main line that other does
+// not was taken back, and goes with everything below it. g stays on its
+// current node, or goes to the end of the main line if that node went.
+func (g *Game) Follow(other *Game) error {
+	current := g.Current
+	g.Root.Comment, g.Root.PL = other.Root.Comment, other.Root.PL
+	g.Root.Extra = append([]sgf.Property(nil), other.Root.Extra...)
+
+	n := g.Root
+	for _, src := range other.Root.Line()[1:] {
+		child := n.findSame(src)
+		if child == nil {
+			child = src.clone()
+			child.Parent = n
+			n.Children = append(n.Children, child)
+		} else {
+			child.Comment, child.PL = src.Comment, src.PL
+			child.Extra = append([]sgf.Property(nil), src.Extra...)
+		}
+		n.takeBack(child)
+		n.promote(child)
+		n = child
+	}
+	n.takeBack(nil)
+
+	if !g.contains(current) {
+		line := g.Root.Line()
+		current = line[len(line)-1]
+	}
+	return g.GoTo(current)
+}
+
+// findSame returns the child of n that plays the same move as src or, for
+// a setup node, sets up the same stones.
+func (n *Node) findSame(src *Node) *Node {
+	if src.IsMove() {
+		return n.findMove(src.Color, src.Point)
+	}
+	for _, child := range n.Children {
+		if !child.IsMove() && sameSetup(child.Setup, src.Setup) {
+			return child
+		}
+	}
+	return nil
+}
+
+func sameSetup(a, b map[board.Point]board.Color) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for p, c := range a {
+		if b[p] != c {
+			return false
+		}
+	}
+	return true
+}
+
+// takeBack removes the move continuing n's main line unless it is kept.
+// Only the game being followed puts moves there, so one it no longer has
+// was taken back.
+func (n *Node) takeBack(kept *Node) {
+	if len(n.Children) == 0 {
+		return
+	}
+	if first := n.Children[0]; first != kept && first.IsMove() {
+		n.removeChild(first)
+		first.Parent = nil
+	}
+}
+
+// promote makes child the first of n's children, continuing its main line.
+func (n *Node) promote(child *Node) {
+	n.removeChild(child)
+	n.Children = append([]*Node{child}, n.Children...)
+}
@@ -0,0 +1,49 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestGame_FollowKeepsVariations(t *testing.T) {
+	remote := NewGame(9)
+	remote.Move(2, 2)
+	remote.Move(6, 6)
+	g, _ := Load(remote.SGF())
+
+	// A local variation from move 1, and the game goes on remotely.
+	g.GoToMove(1)
+	g.Move(5, 5)
+	variation := g.Current
+	remote.Move(3, 3)
+	remote.Root.Set("RE", "B+R")
+
+	if err := g.Follow(remote); err != nil {
+		t.Fatalf("Follow failed: %v", err)
+	}
+	if g.Current != variation || g.Board.At(5, 5) != board.White {
+		t.Fatalf("expected to stay on the local variation")
+	}
+	if g.LastMoveNumber() != 2 || g.Info("RE") != "B+R" {
+		t.Fatalf("expected the variation kept with the game information, got %d moves and RE %q", g.LastMoveNumber(), g.Info("RE"))
+	}
+	g.GoTo(g.Root)
+	if g.LastMoveNumber() != 3 || g.Root.Children[0].Children[0].Children[0].Point.X != 3 {
+		t.Fatalf("expected the remote moves on the main line")
+	}
+
+	// The last move is taken back, and another played.
+	remote.DeleteMoves(3, 3, false)
+	remote.Move(4, 4)
+	g.GoToMove(3)
+	if err := g.Follow(remote); err != nil {
+		t.Fatalf("Follow failed: %v", err)
+	}
+	if g.Board.At(3, 3) != board.Empty || g.Board.At(4, 4) != board.Black {
+		t.Fatalf("expected the taken back move gone and the current node at the new end")
+	}
+	if n := len(g.Root.Children[0].Children[0].Children); n != 1 {
+		t.Fatalf("expected the taken back move out of the tree, got %d children", n)
+	}
+}
@@ -2,10 +2,14 @@ package game
 
 import (
 	"fmt"
+
 	"github.com/vimgo/vimgo/internal/board"
 	"github.com/vimgo/vimgo/internal/rules"
 )
 
+// Game holds the game tree and the position at its current node. Board,
+// captures, LastMove and Moves always describe the path from Root to
+// Current.
 type Game struct {
 	Board         *board.Board
 	CurrentPlayer board.Color
@@ -15,6 +19,26 @@ type Game struct {
 	WhiteCaptures int
 	LastMove      *board.Point
 	Moves         []string // Store moves in SGF format: B[pd], W[aa]
+
+	Root    *Node
+	Current *Node
+
+	// edits are the states of the current node before the edits merged
+	// into it, latest last, for Undo. They are dropped when another node
+	// becomes current.
+	edits []edit
+
+	// rev is the revision of the tree and saved the one at the last load
+	// or save.
+	rev   *revision
+	saved *revision
+}
+
+// revision is a state of the tree. Each change makes a new one, and taking
+// back an edit returns to the one the edit was made to, so undoing back to
+// the saved state leaves the game unmodified.
+type revision struct {
+	prev *revision
 }
 
 type undoState struct {
@@ -26,85 +50,157 @@ type undoState struct {
 }
 
 func NewGame(size int) *Game {
+	root := &Node{}
 	return &Game{
 		Board:         board.New(size),
 		CurrentPlayer: board.Black,
 		History:       []*board.Board{},
 		undoStack:     []undoState{},
+		Root:          root,
+		Current:       root,
 	}
 }
 
 // Move places a stone at (x, y) if valid, updates captures and turn.
+// Playing a move that already follows the current node reuses that node, so
+// undoing and replaying the same move does not create a variation.
 func (g *Game) Move(x, y int) error {
-	if !rules.IsMoveValid(g.Board, x, y, g.CurrentPlayer) {
-		return fmt.Errorf("invalid move at (%d, %d)", x, y)
+	p := &board.Point{X: x, Y: y}
+	child := g.Current.findMove(g.CurrentPlayer, p)
+	created := child == nil
+	if created {
+		child = &Node{Parent: g.Current, Color: g.CurrentPlayer, Point: p}
+	}
+	if err := g.apply(child); err != nil {
+		return err
+	}
+	g.visited()
+	if created {
+		child.Parent.Children = append(child.Parent.Children, child)
+		g.changed()
 	}
+	return nil
+}
 
-	// Ko detection (basic version: compare to immediate previous state)
-	// For full Ko, we'd check against all previous states or use Zobrist hashing.
-	// We check if the resulting board would be identical to the one before the previous move.
-	tempBoard := g.Board.Copy()
-	tempBoard.Set(x, y, g.CurrentPlayer)
-	captured := rules.FindCapturedStones(tempBoard, x, y, g.CurrentPlayer)
-	for _, p := range captured {
-		tempBoard.Set(p.X, p.Y, board.Empty)
+// Pass records a pass for the player to move.
+func (g *Game) Pass() {
+	child := g.Current.findMove(g.CurrentPlayer, nil)
+	if child == nil {
+		child = &Node{Parent: g.Current, Color: g.CurrentPlayer}
+		g.Current.Children = append(g.Current.Children, child)
+		g.changed()
 	}
+	// A pass never fails to apply.
+	_ = g.apply(child)
+	g.visited()
+}
 
-	if len(g.History) > 0 {
-		prevBoard := g.History[len(g.History)-1]
-		if boardsEqual(tempBoard, prevBoard) {
-			return fmt.Errorf("ko violation")
-		}
+// visited records the current node as the child of its parent that
+// Forward goes to. Only playing and stepping through the tree count as
+// visits: replaying a position for display or a search does not.
+func (g *Game) visited() {
+	if g.Current.Parent != nil {
+		g.Current.Parent.next = g.Current
 	}
+}
+
+// apply advances the position from g.Current to its child n.
+func (g *Game) apply(n *Node) error {
+	next := g.Board.Copy()
+	var captured []board.Point
 
-	// Apply move
-	var prevLastMove *board.Point
-	if g.LastMove != nil {
-		prevLastMove = &board.Point{X: g.LastMove.X, Y: g.LastMove.Y}
+	if n.IsMove() && n.Point != nil {
+		x, y := n.Point.X, n.Point.Y
+		if !rules.IsMoveValid(g.Board, x, y, n.Color) {
+			return fmt.Errorf("invalid move at (%d, %d)", x, y)
+		}
+
+		// Ko detection (basic version: compare to immediate previous state)
+		// For full Ko, we'd check against all previous states or use Zobrist hashing.
+		// We check if the resulting board would be identical to the one before the previous move.
+		next.Set(x, y, n.Color)
+		captured = rules.FindCapturedStones(next, x, y, n.Color)
+		for _, c := range captured {
+			next.Set(c.X, c.Y, board.Empty)
+		}
+
+		if len(g.History) > 0 {
+			prevBoard := g.History[len(g.History)-1]
+			if boardsEqual(next, prevBoard) {
+				return fmt.Errorf("ko violation")
+			}
+		}
 	}
-	g.History = append(g.History, g.Board.Copy())
+	applySetup(next, n)
+
+	g.History = append(g.History, g.Board)
 	g.undoStack = append(g.undoStack, undoState{
 		currentPlayer: g.CurrentPlayer,
 		blackCaptures: g.BlackCaptures,
 		whiteCaptures: g.WhiteCaptures,
-		lastMove:      prevLastMove,
+		lastMove:      g.LastMove,
 		movesLen:      len(g.Moves),
 	})
-	g.Board.Set(x, y, g.CurrentPlayer)
+	g.Board = next
+	g.Current = n
+	g.edits = nil
 
-	// Update captures
-	if g.CurrentPlayer == board.Black {
-		g.BlackCaptures += len(captured)
-	} else {
-		g.WhiteCaptures += len(captured)
+	if n.IsMove() {
+		if n.Color == board.Black {
+			g.BlackCaptures += len(captured)
+		} else {
+			g.WhiteCaptures += len(captured)
+		}
+		g.LastMove = n.Point
+		g.Moves = append(g.Moves, n.moveString())
+		g.CurrentPlayer = n.Color.Opposite()
 	}
-
-	// Remove captured stones
-	for _, p := range captured {
-		g.Board.Set(p.X, p.Y, board.Empty)
+	if n.PL != board.Empty {
+		g.CurrentPlayer = n.PL
 	}
+	return nil
+}
 
-	// Update last move and switch player
-	g.LastMove = &board.Point{X: x, Y: y}
+// Modified reports whether the tree changed since it was loaded or last
+// marked saved. Moving around the tree does not count as a change.
+func (g *Game) Modified() bool {
+	return g.rev != g.saved
+}
 
-	// Record move for SGF
-	colorStr := "B"
-	if g.CurrentPlayer == board.White {
-		colorStr = "W"
-	}
-	// Note: this assumes we have sgf package or just format it here.
-	// We'll format it here for simplicity since game doesn't strictly depend on sgf for recording.
-	sgfX := rune('a' + x)
-	sgfY := rune('a' + y)
-	g.Moves = append(g.Moves, fmt.Sprintf("%s[%c%c]", colorStr, sgfX, sgfY))
+// MarkSaved records that the game was written out.
+func (g *Game) MarkSaved() {
+	g.saved = g.rev
+}
 
-	g.CurrentPlayer = g.CurrentPlayer.Opposite()
+func (g *Game) changed() {
+	g.rev = &revision{prev: g.rev}
+}
 
-	return nil
+func applySetup(b *board.Board, n *Node) {
+	for p, c := range n.Setup {
+		b.Set(p.X, p.Y, c)
+	}
 }
 
-// Undo reverts to the previous board state.
+// Undo reverts to the previous board state. The undone node stays in the
+// tree, so it can be revisited as a variation. Edits merged into the
+// current node are taken back first, one at a time.
 func (g *Game) Undo() error {
+	if len(g.edits) > 0 {
+		g.undoEdit()
+		return nil
+	}
+	undone := g.Current
+	if err := g.back(); err != nil {
+		return err
+	}
+	// Forward comes back to the undone node.
+	g.Current.next = undone
+	return nil
+}
+
+// back returns to the position before the current node.
+func (g *Game) back() error {
 	if len(g.History) == 0 {
 		return fmt.Errorf("nothing to undo")
 	}
@@ -124,9 +220,81 @@ func (g *Game) Undo() error {
 	g.WhiteCaptures = prev.whiteCaptures
 	g.LastMove = prev.lastMove
 	g.Moves = g.Moves[:prev.movesLen]
+	g.Current = g.Current.Parent
+	g.edits = nil
 	return nil
 }
 
+// Forward goes to the child of the current node that was visited last, or
+// to the first one, undoing an Undo.
+func (g *Game) Forward() error {
+	children := g.Current.Children
+	if len(children) == 0 {
+		return fmt.Errorf("nothing to redo")
+	}
+	child := children[0]
+	for _, c := range children {
+		if c == g.Current.next {
+			child = c
+		}
+	}
+	if err := g.apply(child); err != nil {
+		return err
+	}
+	g.visited()
+	return nil
+}
+
+// GoTo replays the game from the root to n, which must belong to the tree.
+func (g *Game) GoTo(n *Node) error {
+	if !g.contains(n) {
+		return fmt.Errorf("node is not in this game")
+	}
+	g.reset()
+	for _, step := range n.path() {
+		if err := g.apply(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// At returns a copy of the game positioned at n. The copy shares the tree,
+// so it is meant for display; moving in it changes g's tree too.
+func (g *Game) At(n *Node) (*Game, error) {
+	view := &Game{Board: board.New(g.Board.Size), Root: g.Root, Current: g.Root}
+	if err := view.GoTo(n); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+func (g *Game) contains(n *Node) bool {
+	for n != nil && n != g.Root {
+		n = n.Parent
+	}
+	return n != nil
+}
+
+// reset returns to the root position.
+func (g *Game) reset() {
+	g.Board = board.New(g.Board.Size)
+	g.CurrentPlayer = board.Black
+	g.History = []*board.Board{}
+	g.undoStack = []undoState{}
+	g.BlackCaptures = 0
+	g.WhiteCaptures = 0
+	g.LastMove = nil
+	g.Moves = nil
+	g.Current = g.Root
+	g.edits = nil
+
+	applySetup(g.Board, g.Root)
+	if g.Root.PL != board.Empty {
+		g.CurrentPlayer = g.Root.PL
+	}
+}
+
 func boardsEqual(b1, b2 *board.Board) bool {
 	if b1.Size != b2.Size {
 		return false
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"errors"
 	"github.com/vimgo/vimgo/internal/board"
 	"testing"
 )
@@ -104,3 +105,258 @@ func TestGame_UndoRestoresCapturesAndMetadata(t *testing.T) {
 		t.Fatalf("expected last move restored to (0,0), got %+v", g.LastMove)
 	}
 }
+
+func TestGame_EditIsUndoable(t *testing.T) {
+	g := NewGame(9)
+	if err := g.Move(4, 4); err != nil {
+		t.Fatalf("unexpected move error: %v", err)
+	}
+
+	err := g.Edit(map[board.Point]board.Color{
+		{X: 4, Y: 4}: board.Empty,
+		{X: 0, Y: 0}: board.White,
+	})
+	if err != nil {
+		t.Fatalf("unexpected edit error: %v", err)
+	}
+	if g.Board.At(4, 4) != board.Empty || g.Board.At(0, 0) != board.White {
+		t.Fatalf("edit not applied")
+	}
+	if g.CurrentPlayer != board.White {
+		t.Fatalf("edit should not change the player to move, got %v", g.CurrentPlayer)
+	}
+
+	if err := g.Undo(); err != nil {
+		t.Fatalf("unexpected undo error: %v", err)
+	}
+	if g.Board.At(4, 4) != board.Black || g.Board.At(0, 0) != board.Empty {
+		t.Fatalf("undo did not restore the board before the edit")
+	}
+	if len(g.Moves) != 1 {
+		t.Fatalf("expected the move to survive undoing the edit, got %d moves", len(g.Moves))
+	}
+}
+
+func TestGame_DeleteMovesReattachesTail(t *testing.T) {
+	g := NewGame(9)
+	for _, p := range []board.Point{{X: 0, Y: 0}, {X: 8, Y: 8}, {X: 2, Y: 2}, {X: 6, Y: 6}, {X: 4, Y: 4}} {
+		if err := g.Move(p.X, p.Y); err != nil {
+			t.Fatalf("unexpected move error: %v", err)
+		}
+	}
+	if err := g.GoToMove(1); err != nil {
+		t.Fatalf("unexpected goto error: %v", err)
+	}
+
+	if err := g.DeleteMoves(2, 3, false); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	if g.LastMoveNumber() != 3 {
+		t.Fatalf("expected 3 moves left, got %d", g.LastMoveNumber())
+	}
+	if g.Current.MoveNumber() != 1 {
+		t.Fatalf("expected to stay at move 1, got %d", g.Current.MoveNumber())
+	}
+	if err := g.GoToMove(3); err != nil {
+		t.Fatalf("unexpected goto error: %v", err)
+	}
+	if g.Board.At(8, 8) != board.Empty || g.Board.At(2, 2) != board.Empty || g.Board.At(4, 4) != board.Black {
+		t.Fatalf("unexpected position after delete")
+	}
+}
+
+func TestGame_DeleteMovesRejectsIllegalTail(t *testing.T) {
+	g := NewGame(9)
+	g.Move(1, 0) // B
+	g.Move(0, 0) // W
+	g.Move(0, 1) // B captures (0,0)
+	g.Move(8, 8) // W
+	g.Move(0, 0) // B fills the captured point
+
+	// Without move 3 the white stone is never captured, so move 5 would
+	// be played on an occupied point.
+	if err := g.DeleteMoves(3, 3, false); err == nil {
+		t.Fatalf("expected error for illegal remaining moves")
+	}
+	if g.LastMoveNumber() != 5 || g.Current.MoveNumber() != 5 {
+		t.Fatalf("tree or position changed after failed delete")
+	}
+}
+
+func TestGame_DeleteMovesRejectsIllegalVariation(t *testing.T) {
+	g := NewGame(9)
+	g.Move(1, 0) // B
+	g.Move(0, 0) // W
+	g.Move(0, 1) // B captures (0,0)
+	g.Move(8, 8) // W
+	g.Move(4, 4) // B
+	g.Undo()
+	g.Move(0, 0) // B fills the captured point, in a variation of move 5
+	g.GoToMove(0)
+	g.GoToMove(5)
+
+	// The main line stays legal without move 3, but the variation would be
+	// played on the white stone.
+	if err := g.DeleteMoves(3, 3, false); err == nil {
+		t.Fatalf("expected error for an illegal variation after the moves")
+	}
+	if g.LastMoveNumber() != 5 || g.Current.MoveNumber() != 5 || *g.Current.Point != (board.Point{X: 4, Y: 4}) {
+		t.Fatalf("tree or position changed after failed delete")
+	}
+	if len(g.Root.Children[0].Children[0].Children[0].Children[0].Children) != 2 {
+		t.Fatalf("expected the variation kept after failed delete")
+	}
+}
+
+func TestGame_DeleteMovesWithVariations(t *testing.T) {
+	g := NewGame(9)
+	g.Move(0, 0) // B
+	g.Move(8, 8) // W
+	g.Move(2, 2) // B
+	g.Undo()
+	g.Move(6, 6) // B, a variation of move 3
+	g.GoToMove(0)
+	g.Move(0, 0)
+	g.Move(8, 8)
+	g.Move(2, 2)
+	g.Move(4, 4) // W
+
+	if err := g.DeleteMoves(2, 2, false); !errors.Is(err, ErrVariations) {
+		t.Fatalf("expected ErrVariations, got %v", err)
+	}
+	if g.LastMoveNumber() != 4 || len(g.Root.Children[0].Children[0].Children) != 2 {
+		t.Fatalf("tree changed after refused delete")
+	}
+
+	if err := g.DeleteMoves(2, 2, true); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	// The moves after keep their colors: Black plays twice in a row.
+	line := g.Line()
+	if len(line) != 4 || len(line[1].Children) != 1 {
+		t.Fatalf("expected the variation deleted, got a line of %d", len(line))
+	}
+	if line[2].Color != board.Black || line[3].Color != board.White {
+		t.Fatalf("expected the reattached moves to keep their colors")
+	}
+	if g.CurrentPlayer != board.Black {
+		t.Fatalf("expected Black to move after White's last move, got %v", g.CurrentPlayer)
+	}
+}
+
+func TestGame_ModifiedTracksTreeChanges(t *testing.T) {
+	g := NewGame(9)
+	if g.Modified() {
+		t.Fatalf("new game should not be modified")
+	}
+	g.Move(4, 4)
+	if !g.Modified() {
+		t.Fatalf("expected modified after a move")
+	}
+	g.MarkSaved()
+
+	// Navigating and replaying an existing move is not a change.
+	g.Undo()
+	g.Move(4, 4)
+	if g.Modified() {
+		t.Fatalf("revisiting a move should not modify the game")
+	}
+
+	g.ToggleStone(board.Point{X: 0, Y: 0}, board.White)
+	if !g.Modified() {
+		t.Fatalf("expected modified after a setup edit")
+	}
+}
+
+func TestGame_AtLeavesGameAlone(t *testing.T) {
+	g := NewGame(9)
+	g.Move(2, 2)
+	first := g.Current
+	g.Move(6, 6)
+
+	view, err := g.At(first)
+	if err != nil {
+		t.Fatalf("At failed: %v", err)
+	}
+	if view.Board.At(6, 6) != board.Empty || view.Board.At(2, 2) != board.Black {
+		t.Fatalf("view should show the position after move 1")
+	}
+	if g.Current.MoveNumber() != 2 || g.Board.At(6, 6) != board.White {
+		t.Fatalf("At should not move the game")
+	}
+
+	other := NewGame(9)
+	if _, err := other.At(first); err == nil {
+		t.Fatalf("expected error for a node of another game")
+	}
+}
+
+func TestNode_LineMove(t *testing.T) {
+	g := NewGame(9)
+	g.Move(2, 2)
+	g.Move(6, 6)
+	g.Move(2, 6)
+	g.GoToMove(1)
+	g.Move(5, 5) // variation at move 2
+	variation := g.Current
+
+	if n := g.Root.LineMove(2); n.Point == nil || *n.Point != (board.Point{X: 6, Y: 6}) {
+		t.Fatalf("main line move 2 should be at 6,6")
+	}
+	if n := variation.LineMove(1); n != variation.Parent {
+		t.Fatalf("move 1 should be shared by both lines")
+	}
+	if n := variation.LineMove(10); n != variation {
+		t.Fatalf("a short line should end at its last node")
+	}
+	if n := variation.LineMove(0); n != g.Root {
+		t.Fatalf("move 0 should be the root")
+	}
+}
+
+func TestGame_ForwardReturnsToVisitedVariation(t *testing.T) {
+	g := NewGame(9)
+	g.Move(2, 2)
+	g.Move(6, 6)
+	g.Undo()
+	g.Move(5, 5) // variation, second child
+	g.Undo()
+	g.Undo()
+
+	if err := g.Forward(); err != nil {
+		t.Fatalf("Forward failed: %v", err)
+	}
+	if err := g.Forward(); err != nil {
+		t.Fatalf("Forward failed: %v", err)
+	}
+	if g.Board.At(5, 5) != board.White {
+		t.Fatalf("expected Forward to follow the variation visited last")
+	}
+	if err := g.Forward(); err == nil {
+		t.Fatalf("expected error at the end of the line")
+	}
+}
+
+func TestGame_ForwardIgnoresSearchAndDisplay(t *testing.T) {
+	g := NewGame(9)
+	g.Move(5, 5) // variation
+	g.Current.Comment = "tesuji"
+	g.Undo()
+	g.Move(3, 3) // main move, visited last
+	g.Undo()
+
+	q, _ := ParseQuery("tesuji", 9)
+	n, _ := g.Search(q, false)
+	if n == nil {
+		t.Fatalf("expected the search to find the variation")
+	}
+	if _, err := g.At(n); err != nil {
+		t.Fatalf("At failed: %v", err)
+	}
+	if err := g.Forward(); err != nil {
+		t.Fatalf("Forward failed: %v", err)
+	}
+	if g.Board.At(3, 3) != board.Black {
+		t.Fatalf("expected Forward to follow the move visited last, not the one searched or shown")
+	}
+}
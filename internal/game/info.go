@@ -0,0 +1,68 @@
+package game
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/sgf"
+)
+
+// Get returns the first value of property id among the SGF properties kept
+// in n.Extra, such as PB on the root or BL on a move.
+func (n *Node) Get(id string) (string, bool) {
+	for _, p := range n.Extra {
+		if p.ID == id && len(p.Values) > 0 {
+			return p.Values[0], true
+		}
+	}
+	return "", false
+}
+
+// Set sets property id to value among the SGF properties kept in n.Extra,
+// replacing any values it had.
+func (n *Node) Set(id, value string) {
+	for i, p := range n.Extra {
+		if p.ID == id {
+			n.Extra[i].Values = []string{value}
+			return
+		}
+	}
+	n.Extra = append(n.Extra, sgf.Property{ID: id, Values: []string{value}})
+}
+
+// Info returns a game information property of the root such as PB, BR or
+// RE, or "" if the game does not have it.
+func (g *Game) Info(id string) string {
+	v, _ := g.Root.Get(id)
+	return v
+}
+
+// TimeLeft returns the time player c has left at the current node: the
+// last BL or WL property on the way to it, with the byo-yomi periods or
+// stones of OB or OW (0 when not given), or else the main time TM. ok is
+// false when the game records no time.
+func (g *Game) TimeLeft(c board.Color) (left time.Duration, periods int, ok bool) {
+	timeID, periodsID := "BL", "OB"
+	if c == board.White {
+		timeID, periodsID = "WL", "OW"
+	}
+	for n := g.Current; n != nil; n = n.Parent {
+		v, found := n.Get(timeID)
+		if !found && n.Parent == nil {
+			v, found = n.Get("TM")
+		}
+		if !found {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		if o, found := n.Get(periodsID); found {
+			periods, _ = strconv.Atoi(o)
+		}
+		return time.Duration(seconds * float64(time.Second)), periods, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestGame_InfoAndTimeLeft(t *testing.T) {
+	g, err := Load("(;GM[1]SZ[9]PB[Lee Sedol]BR[9p]TM[600];B[cc]BL[590.5];W[gg]WL[580]OW[3];B[cg])")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if g.Info("PB") != "Lee Sedol" || g.Info("BR") != "9p" || g.Info("PW") != "" {
+		t.Fatalf("unexpected game info PB=%q BR=%q PW=%q", g.Info("PB"), g.Info("BR"), g.Info("PW"))
+	}
+
+	left, periods, ok := g.TimeLeft(board.Black)
+	if !ok || left != 590500*time.Millisecond || periods != 0 {
+		t.Fatalf("black: got %v %d %v", left, periods, ok)
+	}
+	left, periods, ok = g.TimeLeft(board.White)
+	if !ok || left != 580*time.Second || periods != 3 {
+		t.Fatalf("white: got %v %d %v", left, periods, ok)
+	}
+
+	// Before any move, both players have the main time.
+	g.GoTo(g.Root)
+	if left, _, ok := g.TimeLeft(board.White); !ok || left != 10*time.Minute {
+		t.Fatalf("expected the main time at the root, got %v %v", left, ok)
+	}
+
+	if _, _, ok := NewGame(9).TimeLeft(board.Black); ok {
+		t.Fatalf("a game without times should have no clock")
+	}
+}
+
+func TestNode_SetRoundTrips(t *testing.T) {
+	g := NewGame(9)
+	g.Root.Set("PB", "Honinbo")
+	g.Root.Set("PB", "Shusaku")
+	g.Root.Set("PW", "Gennan")
+
+	loaded, err := Load(g.SGF())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Info("PB") != "Shusaku" || loaded.Info("PW") != "Gennan" {
+		t.Fatalf("expected the set names back, got PB=%q PW=%q", loaded.Info("PB"), loaded.Info("PW"))
+	}
+}
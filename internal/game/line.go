@@ -0,0 +1,148 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Line returns the nodes from the root to the end of the current variation:
+// the path to Current, continued along first children.
+func (g *Game) Line() []*Node {
+	return g.Current.Line()
+}
+
+// Line returns the nodes from the root through n to the end of its
+// variation, following first children after n.
+func (n *Node) Line() []*Node {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	line := append([]*Node{root}, n.path()...)
+	for ; len(n.Children) > 0; n = n.Children[0] {
+		line = append(line, n.Children[0])
+	}
+	return line
+}
+
+// LineMove returns the node of move number k on the line through n: the
+// root for 0, or the last node when the line is shorter.
+func (n *Node) LineMove(k int) *Node {
+	line := n.Line()
+	if k <= 0 {
+		return line[0]
+	}
+	for _, node := range line {
+		if node.IsMove() && node.MoveNumber() == k {
+			return node
+		}
+	}
+	return line[len(line)-1]
+}
+
+// LastMoveNumber returns the number of moves in the current line.
+func (g *Game) LastMoveNumber() int {
+	line := g.Line()
+	return line[len(line)-1].MoveNumber()
+}
+
+// GoToMove jumps to move number n of the current line; 0 is the start.
+func (g *Game) GoToMove(n int) error {
+	if n == 0 {
+		return g.GoTo(g.Root)
+	}
+	for _, node := range g.Line() {
+		if node.IsMove() && node.MoveNumber() == n {
+			return g.GoTo(node)
+		}
+	}
+	return fmt.Errorf("no move %d in this line", n)
+}
+
+// ErrVariations is returned by DeleteMoves for moves with variations,
+// which would be deleted with them.
+var ErrVariations = errors.New("the moves have variations")
+
+// DeleteMoves removes moves from through to of the current line, together
+// with the setup nodes between them. The moves after them are reattached
+// in their place, with the variations branching off them; if any of those
+// moves becomes illegal, nothing is changed. They
+// keep their colors, so deleting an odd number of moves leaves a player
+// with two moves in a row, as SGF allows. Variations branching off the
+// deleted nodes are deleted too, but only with variations set; otherwise
+// it fails with ErrVariations.
+func (g *Game) DeleteMoves(from, to int, variations bool) error {
+	if from < 1 || to < from {
+		return fmt.Errorf("invalid move range %d-%d", from, to)
+	}
+
+	line := g.Line()
+	first, last := -1, -1
+	for i, n := range line {
+		if !n.IsMove() {
+			continue
+		}
+		if n.MoveNumber() == from {
+			first = i
+		}
+		if n.MoveNumber() == to {
+			last = i
+		}
+	}
+	if first < 0 || last < 0 {
+		return fmt.Errorf("no moves %d-%d in this line", from, to)
+	}
+	if !variations {
+		for _, n := range line[first : last+1] {
+			// The line goes on through one child; others branch off.
+			if len(n.Children) > 1 {
+				return fmt.Errorf("cannot delete moves %d-%d: %w", from, to, ErrVariations)
+			}
+		}
+	}
+
+	parent := line[first].Parent
+	idx := 0
+	for i, c := range parent.Children {
+		if c == line[first] {
+			idx = i
+		}
+	}
+	var tail *Node
+	if last+1 < len(line) {
+		tail = line[last+1]
+	}
+
+	// Keep Current if it survives, otherwise continue from where the
+	// deleted moves were.
+	target := g.Current
+	for _, n := range line[first : last+1] {
+		if n == g.Current {
+			target = parent
+		}
+	}
+	original := g.Current
+
+	if tail != nil {
+		parent.Children[idx] = tail
+		tail.Parent = parent
+	} else {
+		parent.removeChild(line[first])
+	}
+
+	if tail != nil {
+		// Replaying to the end of every variation checks all the moves
+		// reattached.
+		for _, leaf := range tail.leaves() {
+			if err := g.GoTo(leaf); err != nil {
+				// Restore the tree and position.
+				parent.Children[idx] = line[first]
+				tail.Parent = line[last]
+				_ = g.GoTo(original)
+				return fmt.Errorf("cannot delete moves %d-%d: %v", from, to, err)
+			}
+		}
+	}
+	g.changed()
+	return g.GoTo(target)
+}
@@ -0,0 +1,62 @@
+package game
+
+import "github.com/vimgo/vimgo/internal/board"
+
+// Captured returns the stones the current move captured, or nil if the
+// current node is not a move.
+func (g *Game) Captured() []board.Point {
+	n := g.Current
+	if !n.IsMove() || n.Point == nil || len(g.History) == 0 {
+		return nil
+	}
+	prev := g.History[len(g.History)-1]
+	var points []board.Point
+	for y := 0; y < g.Board.Size; y++ {
+		for x := 0; x < g.Board.Size; x++ {
+			if prev.At(x, y) == n.Color.Opposite() && g.Board.At(x, y) == board.Empty {
+				points = append(points, board.Point{X: x, Y: y})
+			}
+		}
+	}
+	return points
+}
+
+// MoveNumbers maps every stone on the board that was played as a move to
+// the number of that move, as printed game records show them.
+func (g *Game) MoveNumbers() map[board.Point]int {
+	numbers := make(map[board.Point]int)
+	colors := make(map[board.Point]board.Color)
+	k := 0
+	for _, n := range g.Current.path() {
+		if !n.IsMove() {
+			continue
+		}
+		k++
+		if n.Point != nil {
+			numbers[*n.Point] = k
+			colors[*n.Point] = n.Color
+		}
+	}
+	for p := range numbers {
+		// A later capture or setup may have changed the point.
+		if g.Board.At(p.X, p.Y) != colors[p] {
+			delete(numbers, p)
+		}
+	}
+	return numbers
+}
+
+// Variations returns the moves that follow the current node when there is
+// more than one, the main line first.
+func (g *Game) Variations() []*Node {
+	if len(g.Current.Children) < 2 {
+		return nil
+	}
+	var moves []*Node
+	for _, child := range g.Current.Children {
+		if child.IsMove() && child.Point != nil {
+			moves = append(moves, child)
+		}
+	}
+	return moves
+}
@@ -0,0 +1,49 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestGame_CapturedAndMoveNumbers(t *testing.T) {
+	g := NewGame(9)
+	// White at 1,0 is captured by black's fourth move at 2,0.
+	for _, p := range []board.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 5, Y: 5}, {X: 2, Y: 0}} {
+		if err := g.Move(p.X, p.Y); err != nil {
+			t.Fatalf("Move(%v) failed: %v", p, err)
+		}
+	}
+
+	captured := g.Captured()
+	if len(captured) != 1 || captured[0] != (board.Point{X: 1, Y: 0}) {
+		t.Fatalf("expected the stone at 1,0 captured, got %v", captured)
+	}
+	numbers := g.MoveNumbers()
+	if numbers[board.Point{X: 2, Y: 0}] != 5 || numbers[board.Point{X: 0, Y: 0}] != 1 {
+		t.Fatalf("unexpected move numbers %v", numbers)
+	}
+	if _, ok := numbers[board.Point{X: 1, Y: 0}]; ok {
+		t.Fatalf("captured stone should have no number")
+	}
+
+	g.Undo()
+	if g.Captured() != nil {
+		t.Fatalf("expected no captures for a quiet move, got %v", g.Captured())
+	}
+}
+
+func TestGame_Variations(t *testing.T) {
+	g := NewGame(9)
+	g.Move(2, 2)
+	g.Undo()
+	if g.Variations() != nil {
+		t.Fatalf("a single continuation is not a variation")
+	}
+	g.Move(3, 3)
+	g.Undo()
+	v := g.Variations()
+	if len(v) != 2 || *v[0].Point != (board.Point{X: 2, Y: 2}) {
+		t.Fatalf("expected both moves, main line first, got %v", v)
+	}
+}
@@ -0,0 +1,118 @@
+package game
+
+import (
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/sgf"
+)
+
+// Node is one position in the game tree. A node either plays a move (Color
+// set, Point nil for a pass) or sets up stones directly, like SGF's
+// AB/AW/AE/PL properties. The first child continues the main line; further
+// children are variations.
+type Node struct {
+	Parent   *Node
+	Children []*Node
+
+	Color board.Color
+	Point *board.Point
+
+	// Setup maps points to the stone placed there; board.Empty erases.
+	Setup map[board.Point]board.Color
+	// PL is the player to move after this node, board.Empty if unset.
+	PL board.Color
+
+	Comment string
+	// Extra keeps SGF properties VimGo does not interpret, so they survive
+	// a load/save round trip.
+	Extra []sgf.Property
+
+	// next is the child visited last, where Forward goes.
+	next *Node
+}
+
+// IsMove reports whether the node plays a move (or a pass).
+func (n *Node) IsMove() bool {
+	return n.Color != board.Empty
+}
+
+// Depth returns the number of nodes between n and the root.
+func (n *Node) Depth() int {
+	d := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		d++
+	}
+	return d
+}
+
+// MoveNumber returns the number of moves (including passes) played from the
+// root up to and including n. Setup nodes share the number of the move
+// before them.
+func (n *Node) MoveNumber() int {
+	count := 0
+	for p := n; p != nil; p = p.Parent {
+		if p.IsMove() {
+			count++
+		}
+	}
+	return count
+}
+
+// path returns the nodes from the root's first descendant down to n.
+func (n *Node) path() []*Node {
+	var nodes []*Node
+	for p := n; p.Parent != nil; p = p.Parent {
+		nodes = append(nodes, p)
+	}
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+	return nodes
+}
+
+// leaves returns the nodes of the subtree of n that have no children: the
+// ends of its line and of every variation in it.
+func (n *Node) leaves() []*Node {
+	if len(n.Children) == 0 {
+		return []*Node{n}
+	}
+	var leaves []*Node
+	for _, child := range n.Children {
+		leaves = append(leaves, child.leaves()...)
+	}
+	return leaves
+}
+
+func (n *Node) moveString() string {
+	color := "B"
+	if n.Color == board.White {
+		color = "W"
+	}
+	if n.Point == nil {
+		return sgf.EncodeMove(color, -1, -1)
+	}
+	return sgf.EncodeMove(color, n.Point.X, n.Point.Y)
+}
+
+func (n *Node) findMove(c board.Color, p *board.Point) *Node {
+	for _, child := range n.Children {
+		if child.Color != c {
+			continue
+		}
+		if p == nil && child.Point == nil {
+			return child
+		}
+		if p != nil && child.Point != nil && *p == *child.Point {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *Node) removeChild(child *Node) {
+	for i, c := range n.Children {
+		if c == child {
+			n.Children = append(n.Children[:i], n.Children[i+1:]...)
+			return
+		}
+	}
+}
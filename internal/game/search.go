@@ -0,0 +1,234 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// Query is a compiled search through the game tree. It matches one of:
+//
+//   - a move coordinate such as "Q16": the node playing there;
+//   - a pattern in brackets such as "[XO.|.X?]": rows separated by |, X and
+//     O for stones, . for an empty point and ? for anything. The pattern
+//     matches anywhere on the board in any of its eight orientations;
+//   - anything else: text in the node's comment, ignoring case unless the
+//     text has upper case letters.
+type Query struct {
+	point   *board.Point
+	pattern [][]byte
+	text    string
+	fold    bool
+}
+
+// ParseQuery compiles a search for a board of the given size.
+func ParseQuery(s string, size int) (*Query, error) {
+	if s == "" {
+		return nil, fmt.Errorf("E35: No previous regular expression")
+	}
+	if p, ok := parseCoordinate(s, size); ok {
+		return &Query{point: &p}, nil
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		rows := strings.Split(strings.ToUpper(s[1:len(s)-1]), "|")
+		var pattern [][]byte
+		for _, row := range rows {
+			if len(row) != len(rows[0]) || row == "" || strings.Trim(row, "XO.?") != "" {
+				return nil, fmt.Errorf("E486: Bad pattern %s: rows of X, O, . and ? of equal length", s)
+			}
+			pattern = append(pattern, []byte(row))
+		}
+		return &Query{pattern: pattern}, nil
+	}
+	return &Query{text: s, fold: strings.ToLower(s) == s}, nil
+}
+
+// parseCoordinate reads a coordinate as written by CoordinateToString.
+func parseCoordinate(s string, size int) (board.Point, bool) {
+	if len(s) < 2 {
+		return board.Point{}, false
+	}
+	col := unicode.ToUpper(rune(s[0]))
+	if col < 'A' || col > 'T' || col == 'I' {
+		return board.Point{}, false
+	}
+	x := int(col - 'A')
+	if col > 'I' {
+		x--
+	}
+	row, err := strconv.Atoi(s[1:])
+	if err != nil || row < 1 || row > size || x >= size {
+		return board.Point{}, false
+	}
+	return board.Point{X: x, Y: size - row}, true
+}
+
+// Match reports whether node n, with position b after it, matches, and the
+// points to highlight.
+func (q *Query) Match(n *Node, b *board.Board) ([]board.Point, bool) {
+	switch {
+	case q.point != nil:
+		if n.Point != nil && *n.Point == *q.point {
+			return []board.Point{*q.point}, true
+		}
+		return nil, false
+	case q.pattern != nil:
+		points := q.matchPattern(b)
+		return points, points != nil
+	}
+	comment := n.Comment
+	if q.fold {
+		comment = strings.ToLower(comment)
+	}
+	if !strings.Contains(comment, q.text) {
+		return nil, false
+	}
+	if n.Point != nil {
+		return []board.Point{*n.Point}, true
+	}
+	return nil, true
+}
+
+// matchPattern returns the stones and empty points of every place the
+// pattern matches in any orientation, or nil.
+func (q *Query) matchPattern(b *board.Board) []board.Point {
+	seen := make(map[board.Point]bool)
+	var points []board.Point
+	for _, p := range orientations(q.pattern) {
+		h, w := len(p), len(p[0])
+		for oy := 0; oy+h <= b.Size; oy++ {
+			for ox := 0; ox+w <= b.Size; ox++ {
+				if !patternAt(b, p, ox, oy) {
+					continue
+				}
+				for y, row := range p {
+					for x, cell := range row {
+						pt := board.Point{X: ox + x, Y: oy + y}
+						if cell != '?' && !seen[pt] {
+							seen[pt] = true
+							points = append(points, pt)
+						}
+					}
+				}
+			}
+		}
+	}
+	return points
+}
+
+func patternAt(b *board.Board, p [][]byte, ox, oy int) bool {
+	for y, row := range p {
+		for x, cell := range row {
+			c := b.At(ox+x, oy+y)
+			switch cell {
+			case 'X':
+				if c != board.Black {
+					return false
+				}
+			case 'O':
+				if c != board.White {
+					return false
+				}
+			case '.':
+				if c != board.Empty {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// orientations returns the rotations and reflections of a pattern.
+func orientations(p [][]byte) [][][]byte {
+	var out [][][]byte
+	for i := 0; i < 4; i++ {
+		out = append(out, p, mirror(p))
+		p = rotate(p)
+	}
+	return out
+}
+
+func rotate(p [][]byte) [][]byte {
+	h, w := len(p), len(p[0])
+	r := make([][]byte, w)
+	for y := range r {
+		r[y] = make([]byte, h)
+		for x := range r[y] {
+			r[y][x] = p[h-1-x][y]
+		}
+	}
+	return r
+}
+
+func mirror(p [][]byte) [][]byte {
+	m := make([][]byte, len(p))
+	for y, row := range p {
+		m[y] = make([]byte, len(row))
+		for x := range row {
+			m[y][x] = row[len(row)-1-x]
+		}
+	}
+	return m
+}
+
+// Search finds the next node matching q after the current one, in tree
+// order (each node before its children, the main line before variations),
+// or the previous one when backward is set, and returns nil if there is
+// none. The search wraps around the end of the tree, reported by wrapped.
+// A pattern matches where it appears, not at every later node that still
+// shows it.
+func (g *Game) Search(q *Query, backward bool) (n *Node, wrapped bool) {
+	nodes, boards := g.positions()
+	start := 0
+	for i, node := range nodes {
+		if node == g.Current {
+			start = i
+		}
+	}
+	for step := 1; step <= len(nodes); step++ {
+		i := start + step
+		if backward {
+			i = start - step
+		}
+		wrapped = i < 0 || i >= len(nodes)
+		n := nodes[(i%len(nodes)+len(nodes))%len(nodes)]
+		if _, ok := q.Match(n, boards[n]); !ok {
+			continue
+		}
+		if q.pattern != nil && n.Parent != nil {
+			if _, before := q.Match(n.Parent, boards[n.Parent]); before {
+				continue
+			}
+		}
+		return n, wrapped
+	}
+	return nil, false
+}
+
+// positions lists the nodes of the tree in order with the position after
+// each. Nodes whose moves are illegal are left out with their subtrees.
+func (g *Game) positions() ([]*Node, map[*Node]*board.Board) {
+	scratch := &Game{Board: board.New(g.Board.Size), Root: g.Root}
+	scratch.reset()
+	nodes := []*Node{g.Root}
+	boards := map[*Node]*board.Board{g.Root: scratch.Board}
+
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		for _, child := range n.Children {
+			if scratch.apply(child) != nil {
+				continue
+			}
+			nodes = append(nodes, child)
+			boards[child] = scratch.Board
+			walk(child)
+			_ = scratch.back()
+		}
+	}
+	walk(g.Root)
+	return nodes, boards
+}
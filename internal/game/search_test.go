@@ -0,0 +1,82 @@
+package game
+
+import "testing"
+
+func TestSearchCoordinateWithVariations(t *testing.T) {
+	g := NewGame(9)
+	g.Move(2, 2) // C7
+	g.Move(6, 6) // G3
+	g.GoToMove(1)
+	g.Move(6, 2) // G7, a variation at move 2
+	variation := g.Current
+	g.GoTo(g.Root)
+
+	q, err := ParseQuery("g7", 9)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	n, wrapped := g.Search(q, false)
+	if n != variation || wrapped {
+		t.Fatalf("expected to find the variation move without wrapping")
+	}
+
+	// Searching backward from the start wraps to the end of the tree.
+	q, _ = ParseQuery("C7", 9)
+	g.GoTo(variation)
+	n, wrapped = g.Search(q, true)
+	if n == nil || n.MoveNumber() != 1 || wrapped {
+		t.Fatalf("expected move 1 searching backward")
+	}
+	g.GoTo(n)
+	if n, wrapped = g.Search(q, true); n == nil || !wrapped {
+		t.Fatalf("expected a wrapped search to find move 1 again")
+	}
+}
+
+func TestSearchComment(t *testing.T) {
+	g := NewGame(9)
+	g.Move(2, 2)
+	g.Move(6, 6)
+	g.Current.Comment = "A nice Tesuji"
+	g.GoTo(g.Root)
+
+	q, _ := ParseQuery("tesuji", 9)
+	if n, _ := g.Search(q, false); n == nil || n.MoveNumber() != 2 {
+		t.Fatalf("lower case text should ignore case")
+	}
+	q, _ = ParseQuery("TESUJI", 9)
+	if n, _ := g.Search(q, false); n != nil {
+		t.Fatalf("upper case text should match case")
+	}
+}
+
+func TestSearchPatternAnyOrientation(t *testing.T) {
+	g := NewGame(9)
+	g.Move(4, 4) // B
+	g.Move(5, 4) // W, right of black
+	g.Move(0, 8) // B elsewhere
+
+	g.GoTo(g.Root)
+	// Written vertically: white above black. Found rotated at move 2.
+	q, err := ParseQuery("[o|x]", 9)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	n, _ := g.Search(q, false)
+	if n == nil || n.MoveNumber() != 2 {
+		t.Fatalf("expected the pattern to appear at move 2")
+	}
+	g.GoTo(n)
+	points, ok := q.Match(n, g.Board)
+	if !ok || len(points) != 2 {
+		t.Fatalf("expected two highlighted points, got %v", points)
+	}
+
+	// Move 3 still shows the pattern but is not where it appears.
+	if next, _ := g.Search(q, false); next != n {
+		t.Fatalf("expected the search to come back to move 2")
+	}
+	if _, err := ParseQuery("[XO|X]", 9); err == nil {
+		t.Fatalf("expected error for rows of different length")
+	}
+}
@@ -0,0 +1,111 @@
+package game
+
+import (
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// setupNode returns the node that position edits go to. Edits are merged
+// into the current node when it is a leaf without a move (the root of a
+// fresh game or an earlier setup node), which Undo can take back; otherwise
+// a new setup node is added after it, as SGF does not mix moves and setup
+// in one node.
+func (g *Game) setupNode() *Node {
+	if n := g.Current; !n.IsMove() && len(n.Children) == 0 {
+		e := edit{node: n, pl: n.PL, board: g.Board.Copy(), player: g.CurrentPlayer, rev: g.rev}
+		if n.Setup != nil {
+			e.setup = make(map[board.Point]board.Color, len(n.Setup))
+			for p, c := range n.Setup {
+				e.setup[p] = c
+			}
+		}
+		g.edits = append(g.edits, e)
+		return n
+	}
+	n := &Node{Parent: g.Current}
+	g.Current.Children = append(g.Current.Children, n)
+	g.changed()
+	// A node with no move and no setup cannot fail to apply.
+	_ = g.apply(n)
+	g.visited()
+	return n
+}
+
+// edit is the state of a node, the position and the tree's revision before
+// an edit merged into the node.
+type edit struct {
+	node   *Node
+	setup  map[board.Point]board.Color
+	pl     board.Color
+	board  *board.Board
+	player board.Color
+	rev    *revision
+}
+
+// undoEdit takes back the last edit merged into the current node.
+func (g *Game) undoEdit() {
+	e := g.edits[len(g.edits)-1]
+	g.edits = g.edits[:len(g.edits)-1]
+	e.node.Setup, e.node.PL = e.setup, e.pl
+	g.Board, g.CurrentPlayer = e.board, e.player
+	g.rev = e.rev
+}
+
+// Edit changes the given intersections without any rules checks, e.g. to
+// clear a region or paste a pattern. Empty erases. The changes are stored as
+// setup properties (AB/AW/AE) so they are saved with the game, and they do
+// not change the player to move.
+func (g *Game) Edit(changes map[board.Point]board.Color) error {
+	pending := make(map[board.Point]board.Color)
+	for p, c := range changes {
+		if g.Board.IsOnBoard(p.X, p.Y) && g.Board.At(p.X, p.Y) != c {
+			pending[p] = c
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	n := g.setupNode()
+	base := g.baseBoard(n)
+	if n.Setup == nil {
+		n.Setup = make(map[board.Point]board.Color)
+	}
+	for p, c := range pending {
+		// Drop setup entries that would not change the position.
+		if base.At(p.X, p.Y) == c {
+			delete(n.Setup, p)
+		} else {
+			n.Setup[p] = c
+		}
+		g.Board.Set(p.X, p.Y, c)
+	}
+	g.changed()
+	return nil
+}
+
+// ToggleStone places a stone of color c at p, or erases it if a stone of
+// that color is already there. It is the Insert mode b and w keys.
+func (g *Game) ToggleStone(p board.Point, c board.Color) error {
+	if g.Board.At(p.X, p.Y) == c {
+		c = board.Empty
+	}
+	return g.Edit(map[board.Point]board.Color{p: c})
+}
+
+// TogglePlayer switches the player to move and records it as PL.
+func (g *Game) TogglePlayer() {
+	n := g.setupNode()
+	g.CurrentPlayer = g.CurrentPlayer.Opposite()
+	n.PL = g.CurrentPlayer
+	g.changed()
+}
+
+// baseBoard returns the position at n before its own setup is applied. n must
+// be the current node and have no move, so the previous position on the
+// history stack differs from it only by the setup.
+func (g *Game) baseBoard(n *Node) *board.Board {
+	if n == g.Root {
+		return board.New(g.Board.Size)
+	}
+	return g.History[len(g.History)-1]
+}
@@ -0,0 +1,192 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestGame_ToggleStoneAtRootIsSetup(t *testing.T) {
+	g := NewGame(9)
+	p := board.Point{X: 2, Y: 2}
+
+	if err := g.ToggleStone(p, board.Black); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g.Board.At(2, 2) != board.Black {
+		t.Fatalf("expected black stone after toggle")
+	}
+	if g.Current != g.Root || g.Root.Setup[p] != board.Black {
+		t.Fatalf("expected the stone to be stored as root setup, got %+v", g.Root.Setup)
+	}
+
+	if err := g.ToggleStone(p, board.Black); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g.Board.At(2, 2) != board.Empty {
+		t.Fatalf("expected second toggle to erase the stone")
+	}
+	if len(g.Root.Setup) != 0 {
+		t.Fatalf("expected redundant setup entry to be dropped, got %+v", g.Root.Setup)
+	}
+}
+
+func TestGame_SetupAfterMoveAddsNode(t *testing.T) {
+	g := NewGame(9)
+	if err := g.Move(4, 4); err != nil {
+		t.Fatalf("unexpected move error: %v", err)
+	}
+	moveNode := g.Current
+
+	if err := g.Edit(map[board.Point]board.Color{{X: 4, Y: 4}: board.Empty}); err != nil {
+		t.Fatalf("unexpected edit error: %v", err)
+	}
+	if g.Current.Parent != moveNode || g.Current.IsMove() {
+		t.Fatalf("expected a new setup node after the move")
+	}
+	if g.Current.Setup[board.Point{X: 4, Y: 4}] != board.Empty {
+		t.Fatalf("expected AE entry for the erased stone, got %+v", g.Current.Setup)
+	}
+}
+
+func TestGame_UndoMergedSetup(t *testing.T) {
+	g := NewGame(9)
+	a, b := board.Point{X: 0, Y: 0}, board.Point{X: 1, Y: 0}
+	g.ToggleStone(a, board.Black)
+	g.Edit(map[board.Point]board.Color{a: board.Empty, b: board.White})
+	g.TogglePlayer()
+
+	if err := g.Undo(); err != nil || g.CurrentPlayer != board.Black || g.Root.PL != board.Empty {
+		t.Fatalf("expected Black to move again, got %v (%v)", g.CurrentPlayer, err)
+	}
+	if err := g.Undo(); err != nil || g.Board.At(0, 0) != board.Black || g.Board.At(1, 0) != board.Empty {
+		t.Fatalf("expected the edit taken back, got %v", err)
+	}
+	if len(g.Root.Setup) != 1 || g.Root.Setup[a] != board.Black {
+		t.Fatalf("expected the root setup taken back, got %+v", g.Root.Setup)
+	}
+	if err := g.Undo(); err != nil || g.Board.At(0, 0) != board.Empty || len(g.Root.Setup) != 0 {
+		t.Fatalf("expected an empty board, got %+v (%v)", g.Root.Setup, err)
+	}
+	if err := g.Undo(); err == nil {
+		t.Fatalf("expected nothing left to undo")
+	}
+
+	// Edits are only taken back while their node is current: after a move
+	// and its undo, Undo does not reach into the position it was played on.
+	g.ToggleStone(a, board.Black)
+	if err := g.Move(4, 4); err != nil {
+		t.Fatalf("unexpected move error: %v", err)
+	}
+	g.Undo()
+	if err := g.Undo(); err == nil || g.Board.At(0, 0) != board.Black {
+		t.Fatalf("expected the setup under the move kept, got %v", err)
+	}
+}
+
+func TestGame_UndoBackToSavedIsUnmodified(t *testing.T) {
+	// Edits go into the setup node at the end.
+	g, err := Load("(;GM[1]SZ[9];B[ee];AB[aa])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := g.Line()
+	g.GoTo(line[len(line)-1])
+	g.ToggleStone(board.Point{X: 1, Y: 0}, board.Black)
+	g.Undo()
+	if g.Modified() {
+		t.Fatalf("expected undoing the only edit to leave the game unmodified")
+	}
+
+	g.ToggleStone(board.Point{X: 1, Y: 0}, board.Black)
+	g.MarkSaved()
+	g.TogglePlayer()
+	g.ToggleStone(board.Point{X: 2, Y: 0}, board.White)
+	g.Undo()
+	if !g.Modified() {
+		t.Fatalf("expected modified with one edit after the save left")
+	}
+	g.Undo()
+	if g.Modified() {
+		t.Fatalf("expected undoing back to the save to leave the game unmodified")
+	}
+	g.Undo()
+	if !g.Modified() {
+		t.Fatalf("expected modified after undoing an edit saved")
+	}
+}
+
+func TestGame_SetupRoundTrip(t *testing.T) {
+	g := NewGame(9)
+	g.ToggleStone(board.Point{X: 0, Y: 0}, board.Black)
+	g.ToggleStone(board.Point{X: 1, Y: 0}, board.White)
+	g.TogglePlayer()
+
+	content := g.SGF()
+	for _, want := range []string{"AB[aa]", "AW[ba]", "PL[W]"} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected %s in %s", want, content)
+		}
+	}
+
+	loaded, err := Load(content)
+	if err != nil {
+		t.Fatalf("unexpected load error: %v", err)
+	}
+	if loaded.Board.At(0, 0) != board.Black || loaded.Board.At(1, 0) != board.White {
+		t.Fatalf("setup stones not restored")
+	}
+	if loaded.CurrentPlayer != board.White {
+		t.Fatalf("expected White to move, got %v", loaded.CurrentPlayer)
+	}
+}
+
+func TestLoadKeepsVariations(t *testing.T) {
+	g, err := Load("(;GM[1]SZ[9]PB[Alice];B[ee](;W[cc];B[gg])(;W[gc]C[try this]))")
+	if err != nil {
+		t.Fatalf("unexpected load error: %v", err)
+	}
+	if len(g.Moves) != 3 {
+		t.Fatalf("expected main line of 3 moves, got %v", g.Moves)
+	}
+	first := g.Root.Children[0]
+	if len(first.Children) != 2 {
+		t.Fatalf("expected 2 variations, got %d", len(first.Children))
+	}
+	if first.Children[1].Comment != "try this" {
+		t.Fatalf("expected comment on variation, got %q", first.Children[1].Comment)
+	}
+
+	out := g.SGF()
+	if !strings.Contains(out, "PB[Alice]") || !strings.Contains(out, "(;W[gc]C[try this])") {
+		t.Fatalf("round trip lost data: %s", out)
+	}
+}
+
+func TestLoadRejectsBadSizesAndPoints(t *testing.T) {
+	for _, content := range []string{
+		"(;SZ[0])",
+		"(;SZ[-9])",
+		"(;SZ[100000])",
+		"(;SZ[9];B[jj])",
+		"(;SZ[9];B[e!])",
+		"(;SZ[9]AB[aa][ai:aj])",
+		"(;SZ[9];B[ee](;W[cc])(;W[cz]))",
+	} {
+		if _, err := Load(content); err == nil {
+			t.Errorf("expected Load to reject %s", content)
+		}
+	}
+
+	g, err := Load("(;SZ[52]AB[ZZ];W[Aa])")
+	if err != nil {
+		t.Fatalf("unexpected load error: %v", err)
+	}
+	if g.Board.At(51, 51) != board.Black || g.Board.At(26, 0) != board.White {
+		t.Fatalf("expected stones past z on a 52x52 board")
+	}
+	if out := g.SGF(); !strings.Contains(out, "AB[ZZ]") || !strings.Contains(out, "W[Aa]") {
+		t.Fatalf("round trip lost the points past z: %s", out)
+	}
+}
@@ -0,0 +1,149 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/sgf"
+)
+
+// Load parses an SGF game and replays its main line to the end. Variations
+// are kept in the tree.
+func Load(content string) (*Game, error) {
+	root, err := sgf.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	size := 19
+	if v, ok := root.Get("SZ"); ok {
+		if size, err = strconv.Atoi(v); err != nil || size < 1 || size > sgf.MaxSize {
+			return nil, fmt.Errorf("invalid board size %q", v)
+		}
+	}
+
+	g := NewGame(size)
+	if err := convertNode(root, g.Root, size); err != nil {
+		return nil, err
+	}
+
+	end := g.Root
+	for len(end.Children) > 0 {
+		end = end.Children[0]
+	}
+	if err := g.GoTo(end); err != nil {
+		return nil, fmt.Errorf("failed to replay game: %v", err)
+	}
+	return g, nil
+}
+
+// rootProps are written by SGF itself and are not kept in Node.Extra.
+var rootProps = map[string]bool{"GM": true, "FF": true, "CA": true, "SZ": true}
+
+// convertNode fills in dst from src and its variations, played on a board
+// of the given size.
+func convertNode(src *sgf.Node, dst *Node, size int) error {
+	onBoard := func(v string, x, y int) error {
+		if x >= size || y >= size {
+			return fmt.Errorf("invalid point %q on a %dx%d board", v, size, size)
+		}
+		return nil
+	}
+	for _, prop := range src.Props {
+		switch prop.ID {
+		case "B", "W":
+			dst.Color = board.Black
+			if prop.ID == "W" {
+				dst.Color = board.White
+			}
+			// Both "" and, on boards up to 19x19, "tt" mean pass. On
+			// larger boards "tt" is the point (19, 19).
+			if v := prop.Values[0]; v != "" && (v != "tt" || size > 19) {
+				x, y, err := sgf.FromSGFCoord(v)
+				if err != nil {
+					return err
+				}
+				if err := onBoard(v, x, y); err != nil {
+					return err
+				}
+				dst.Point = &board.Point{X: x, Y: y}
+			}
+		case "AB", "AW", "AE":
+			points, err := sgf.ParsePoints(prop.Values)
+			if err != nil {
+				return err
+			}
+			color := map[string]board.Color{"AB": board.Black, "AW": board.White, "AE": board.Empty}[prop.ID]
+			if dst.Setup == nil {
+				dst.Setup = make(map[board.Point]board.Color)
+			}
+			for _, p := range points {
+				if err := onBoard(sgf.ToSGFCoord(p.X, p.Y), p.X, p.Y); err != nil {
+					return err
+				}
+				dst.Setup[p] = color
+			}
+		case "PL":
+			dst.PL = board.Black
+			if prop.Values[0] == "W" {
+				dst.PL = board.White
+			}
+		case "C":
+			dst.Comment = prop.Values[0]
+		default:
+			if dst.Parent == nil && rootProps[prop.ID] {
+				continue
+			}
+			dst.Extra = append(dst.Extra, prop)
+		}
+	}
+
+	for _, srcChild := range src.Children {
+		child := &Node{Parent: dst}
+		if err := convertNode(srcChild, child, size); err != nil {
+			return err
+		}
+		dst.Children = append(dst.Children, child)
+	}
+	return nil
+}
+
+// SGF serializes the whole game tree, including variations and setup.
+func (g *Game) SGF() string {
+	root := exportNode(g.Root)
+	root.Props = append(sgfHeader(g.Board.Size), root.Props...)
+	return sgf.Write(root)
+}
+
+func exportNode(n *Node) *sgf.Node {
+	out := &sgf.Node{}
+	if n.IsMove() {
+		move := n.moveString()
+		out.Set(move[:1], move[2:len(move)-1])
+	}
+
+	setup := map[board.Color][]board.Point{}
+	for p, c := range n.Setup {
+		setup[c] = append(setup[c], p)
+	}
+	out.Set("AB", sgf.EncodePoints(setup[board.Black])...)
+	out.Set("AW", sgf.EncodePoints(setup[board.White])...)
+	out.Set("AE", sgf.EncodePoints(setup[board.Empty])...)
+	switch n.PL {
+	case board.Black:
+		out.Set("PL", "B")
+	case board.White:
+		out.Set("PL", "W")
+	}
+
+	if n.Comment != "" {
+		out.Set("C", n.Comment)
+	}
+	out.Props = append(out.Props, n.Extra...)
+
+	for _, child := range n.Children {
+		out.Children = append(out.Children, exportNode(child))
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestLoadTTPass(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		pass    bool
+	}{
+		{"(;GM[1]SZ[19];B[tt])", true},
+		{"(;GM[1]SZ[9];B[])", true},
+		{"(;GM[1]SZ[21];B[tt])", false},
+	} {
+		g, err := Load(tc.content)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.content, err)
+		}
+		move := g.Root.Children[0]
+		if pass := move.Point == nil; pass != tc.pass {
+			t.Errorf("%s: expected pass=%v, got %v", tc.content, tc.pass, pass)
+		}
+		if !tc.pass && *move.Point != (board.Point{X: 19, Y: 19}) {
+			t.Errorf("%s: expected (19, 19), got %v", tc.content, *move.Point)
+		}
+	}
+
+	// The point survives a round trip on a large board.
+	g, _ := Load("(;GM[1]SZ[21];W[tt])")
+	loaded, err := Load(g.SGF())
+	if err != nil || loaded.Root.Children[0].Point == nil {
+		t.Fatalf("expected tt kept as a point, got %v", err)
+	}
+}
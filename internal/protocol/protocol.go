@@ -0,0 +1,284 @@
+// Package protocol is the JSON message protocol between the web server and
+// a native board in the browser. Every message is a JSON object whose
+// "type" names it. The server sends a Hello, then the game as State
+// snapshots after every change, Clock ticks, Chat, Left and Error messages;
+// clients send Requests to move, pass, ask for an undo or chat.
+// Spectators get a Played instead of a State when a move is all that
+// changed, and everyone gets the number of Viewers.
+//
+// Every connection first gets a Session, whose token the client sends
+// back with ?session= when it reconnects to take up where it left off.
+//
+// The protocol is versioned: clients ask for Subprotocol when opening the
+// websocket, and Hello tells the Version the server speaks.
+package protocol
+
+import (
+	"strings"
+	"time"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// Version is the protocol version described here.
+const Version = 1
+
+// Subprotocol is the websocket subprotocol of this Version.
+const Subprotocol = "vimgo.v1"
+
+// Message types. Undo is sent both ways: clients ask for or agree to an
+// undo with it, and the server tells who asked.
+const (
+	TypeHello   = "hello"
+	TypeState   = "state"
+	TypeClock   = "clock"
+	TypeChat    = "chat"
+	TypeUndo    = "undo"
+	TypeLeft    = "left"
+	TypeError   = "error"
+	TypePlayed  = "played"
+	TypeViewers = "viewers"
+	TypeSession = "session"
+	TypeMove    = "move"
+	TypePass    = "pass"
+)
+
+// Session gives the client the token of its session and, when the server
+// records it, the ID of the recording, played back at /replay/<id>.
+type Session struct {
+	Type   string `json:"type"`
+	Token  string `json:"token"`
+	Replay string `json:"replay,omitempty"`
+}
+
+// NewSession gives the client its session token.
+func NewSession(token string) Session {
+	return Session{Type: TypeSession, Token: token}
+}
+
+// Hello greets a client once connected.
+type Hello struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Room    string `json:"room"`
+	// Seat is "black", "white" or "" for a spectator.
+	Seat string `json:"seat"`
+	Name string `json:"name,omitempty"`
+}
+
+// NewHello greets name, sitting in seat of room.
+func NewHello(room, name string, seat board.Color) Hello {
+	return Hello{Type: TypeHello, Version: Version, Room: room, Seat: colorName(seat), Name: name}
+}
+
+// Point is an intersection counted from the top-left corner, from 0.
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Players holds a value for each player.
+type Players[T any] struct {
+	Black T `json:"black"`
+	White T `json:"white"`
+}
+
+// State is a snapshot of the game at its current node.
+type State struct {
+	Type string `json:"type"`
+	Size int    `json:"size"`
+	// Board has a row per line from the top, with "X" for black, "O"
+	// for white and "." for empty points.
+	Board []string `json:"board"`
+	// ToPlay is "black" or "white".
+	ToPlay   string          `json:"toPlay"`
+	Move     int             `json:"move"`
+	LastMove *Point          `json:"lastMove,omitempty"`
+	Captures Players[int]    `json:"captures"`
+	Names    Players[string] `json:"names"`
+	Result   string          `json:"result,omitempty"`
+	// Moves lists the moves to the current node as coordinates such as
+	// "D4", or "pass".
+	Moves   []string `json:"moves"`
+	Comment string   `json:"comment,omitempty"`
+}
+
+// NewState takes a snapshot of g.
+func NewState(g *game.Game) State {
+	size := g.Board.Size
+	s := State{
+		Type:     TypeState,
+		Size:     size,
+		ToPlay:   colorName(g.CurrentPlayer),
+		Move:     g.Current.MoveNumber(),
+		Captures: Players[int]{Black: g.BlackCaptures, White: g.WhiteCaptures},
+		Names:    Players[string]{Black: g.Info("PB"), White: g.Info("PW")},
+		Result:   g.Info("RE"),
+		Moves:    []string{},
+		Comment:  g.Current.Comment,
+	}
+	for y := 0; y < size; y++ {
+		var row strings.Builder
+		for x := 0; x < size; x++ {
+			switch g.Board.At(x, y) {
+			case board.Black:
+				row.WriteByte('X')
+			case board.White:
+				row.WriteByte('O')
+			default:
+				row.WriteByte('.')
+			}
+		}
+		s.Board = append(s.Board, row.String())
+	}
+	if p := g.LastMove; p != nil && g.Current.IsMove() {
+		s.LastMove = &Point{X: p.X, Y: p.Y}
+	}
+	for _, n := range g.Line()[1:] {
+		if n.MoveNumber() > s.Move {
+			break
+		}
+		if !n.IsMove() {
+			continue
+		}
+		if n.Point == nil {
+			s.Moves = append(s.Moves, "pass")
+		} else {
+			s.Moves = append(s.Moves, game.CoordinateToString(size, n.Point.X, n.Point.Y))
+		}
+	}
+	return s
+}
+
+// Played is a move added at the end of the game, by Color at Point or a
+// pass when Point is nil. Move is its number.
+type Played struct {
+	Type     string       `json:"type"`
+	Move     int          `json:"move"`
+	Color    string       `json:"color"`
+	Point    *Point       `json:"point,omitempty"`
+	Captures Players[int] `json:"captures"`
+}
+
+// NextMove returns the Played that brings a client from prev to next, or
+// false when next is not prev with one more move and nothing else changed.
+func NextMove(prev, next State) (Played, bool) {
+	if next.Size != prev.Size || next.Move != prev.Move+1 || len(next.Moves) != len(prev.Moves)+1 ||
+		next.Result != prev.Result || next.Names != prev.Names || next.Comment != prev.Comment {
+		return Played{}, false
+	}
+	for i, m := range prev.Moves {
+		if next.Moves[i] != m {
+			return Played{}, false
+		}
+	}
+	p := Played{Type: TypePlayed, Move: next.Move, Color: prev.ToPlay, Captures: next.Captures}
+	if next.Moves[len(prev.Moves)] != "pass" {
+		p.Point = next.LastMove
+	}
+	return p, true
+}
+
+// Viewers is the number of spectators in the room.
+type Viewers struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// NewViewers tells that n spectators watch the room.
+func NewViewers(n int) Viewers {
+	return Viewers{Type: TypeViewers, Count: n}
+}
+
+// Clock is the time both players have left, in seconds, and whose clock
+// runs: "black", "white" or "" when stopped.
+type Clock struct {
+	Type    string           `json:"type"`
+	Left    Players[float64] `json:"left"`
+	Running string           `json:"running"`
+}
+
+// NewClock reports the time left for black and white, with the clock of
+// running going.
+func NewClock(black, white time.Duration, running board.Color) Clock {
+	return Clock{
+		Type:    TypeClock,
+		Left:    Players[float64]{Black: tenths(black), White: tenths(white)},
+		Running: colorName(running),
+	}
+}
+
+// tenths is d in seconds, to a tenth of a second.
+func tenths(d time.Duration) float64 {
+	return d.Round(100 * time.Millisecond).Seconds()
+}
+
+// Chat is a message said by From, in seat Seat. From is empty for a
+// member who gave no name.
+type Chat struct {
+	Type string `json:"type"`
+	From string `json:"from,omitempty"`
+	Seat string `json:"seat"`
+	Text string `json:"text"`
+}
+
+// NewChat is text said by from, sitting in seat.
+func NewChat(from string, seat board.Color, text string) Chat {
+	return Chat{Type: TypeChat, From: from, Seat: colorName(seat), Text: text}
+}
+
+// Undo tells that the player in Seat asks to take back their last move.
+type Undo struct {
+	Type string `json:"type"`
+	Seat string `json:"seat"`
+	From string `json:"from,omitempty"`
+}
+
+// NewUndo tells that from, sitting in seat, asks for an undo.
+func NewUndo(from string, seat board.Color) Undo {
+	return Undo{Type: TypeUndo, Seat: colorName(seat), From: from}
+}
+
+// Left tells that From left Seat, which is free to take.
+type Left struct {
+	Type string `json:"type"`
+	Seat string `json:"seat"`
+	From string `json:"from,omitempty"`
+}
+
+// NewLeft tells that from left seat.
+func NewLeft(from string, seat board.Color) Left {
+	return Left{Type: TypeLeft, Seat: colorName(seat), From: from}
+}
+
+// Error reports a request that failed, with the request's ID.
+type Error struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// NewError reports err for the request with the given ID.
+func NewError(id string, err error) Error {
+	return Error{Type: TypeError, ID: id, Message: err.Error()}
+}
+
+// Request is a message from a client: a move at X, Y, a pass, an undo or
+// a chat message with Text. ID, if set, comes back in the Error the
+// request may cause.
+type Request struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+	Text string `json:"text,omitempty"`
+}
+
+// colorName names a seat or player: "black", "white", or "" for neither.
+func colorName(c board.Color) string {
+	if c == board.Empty {
+		return ""
+	}
+	return strings.ToLower(c.String())
+}
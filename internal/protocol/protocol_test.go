@@ -0,0 +1,114 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+func TestNewState(t *testing.T) {
+	g, err := game.Load("(;GM[1]SZ[5]PB[alice]PW[bob]C[start];B[bb];W[dd];B[];W[bc]C[hane])")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	s := NewState(g)
+	if s.Type != TypeState || s.Size != 5 || s.ToPlay != "black" || s.Move != 4 {
+		t.Fatalf("unexpected header %+v", s)
+	}
+	want := []string{".....", ".X...", ".O...", "...O.", "....."}
+	if strings.Join(s.Board, "/") != strings.Join(want, "/") {
+		t.Fatalf("expected board %v, got %v", want, s.Board)
+	}
+	if s.LastMove == nil || *s.LastMove != (Point{X: 1, Y: 2}) {
+		t.Fatalf("expected the last move at 1,2, got %v", s.LastMove)
+	}
+	if strings.Join(s.Moves, " ") != "B4 D2 pass B3" {
+		t.Fatalf("unexpected moves %v", s.Moves)
+	}
+	if s.Names.Black != "alice" || s.Names.White != "bob" || s.Comment != "hane" {
+		t.Fatalf("unexpected names or comment %+v", s)
+	}
+
+	// Earlier in the game, the later moves are left out.
+	g.GoToMove(1)
+	s = NewState(g)
+	if strings.Join(s.Moves, " ") != "B4" || s.ToPlay != "white" {
+		t.Fatalf("expected only the first move, got %v to play %s", s.Moves, s.ToPlay)
+	}
+	g.GoTo(g.Root)
+	if s := NewState(g); s.LastMove != nil || len(s.Moves) != 0 {
+		t.Fatalf("expected no moves at the start, got %+v", s)
+	}
+}
+
+func TestNextMove(t *testing.T) {
+	g, err := game.Load("(;GM[1]SZ[5];B[bb];W[dd];B[])")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	states := make([]State, 4)
+	for i := range states {
+		g.GoToMove(i)
+		states[i] = NewState(g)
+	}
+
+	p, ok := NextMove(states[1], states[2])
+	if !ok || p.Type != TypePlayed || p.Move != 2 || p.Color != "white" || p.Point == nil || *p.Point != (Point{X: 3, Y: 3}) {
+		t.Fatalf("expected white's move at 3,3, got %+v %v", p, ok)
+	}
+	if p, ok := NextMove(states[2], states[3]); !ok || p.Color != "black" || p.Point != nil {
+		t.Fatalf("expected black's pass, got %+v %v", p, ok)
+	}
+
+	// Anything but one more move needs the whole State.
+	if _, ok := NextMove(states[1], states[3]); ok {
+		t.Fatalf("expected two moves not to be one Played")
+	}
+	if _, ok := NextMove(states[2], states[1]); ok {
+		t.Fatalf("expected an undo not to be a Played")
+	}
+	over := states[3]
+	over.Result = "W+T"
+	if _, ok := NextMove(states[2], over); ok {
+		t.Fatalf("expected a result to need the whole State")
+	}
+}
+
+func TestMessagesEncode(t *testing.T) {
+	for _, tc := range []struct {
+		msg  any
+		want string
+	}{
+		{NewHello("abc", "alice", board.Black), `{"type":"hello","version":1,"room":"abc","seat":"black","name":"alice"}`},
+		{NewClock(90*time.Second, 1500*time.Millisecond, board.White), `{"type":"clock","left":{"black":90,"white":1.5},"running":"white"}`},
+		{NewChat("carol", board.Empty, "hi"), `{"type":"chat","from":"carol","seat":"","text":"hi"}`},
+		{NewUndo("bob", board.White), `{"type":"undo","seat":"white","from":"bob"}`},
+		{NewLeft("", board.Black), `{"type":"left","seat":"black"}`},
+		{NewError("7", errors.New("not your turn")), `{"type":"error","id":"7","message":"not your turn"}`},
+		{NewViewers(3), `{"type":"viewers","count":3}`},
+		{NewSession("f00d"), `{"type":"session","token":"f00d"}`},
+	} {
+		got, err := json.Marshal(tc.msg)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("expected %s, got %s", tc.want, got)
+		}
+	}
+}
+
+func TestRequestDecode(t *testing.T) {
+	var r Request
+	if err := json.Unmarshal([]byte(`{"type":"move","id":"1","x":3,"y":15}`), &r); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if r.Type != TypeMove || r.ID != "1" || r.X != 3 || r.Y != 15 {
+		t.Fatalf("unexpected request %+v", r)
+	}
+}
@@ -0,0 +1,641 @@
+// Package room keeps games played over the network. A room holds the one
+// authoritative game, two seats and any number of spectators, and sends
+// the game to every member after each move.
+package room
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// eventBuffer is how many events a member may fall behind by before the
+// room drops it.
+const eventBuffer = 16
+
+// maxChat is the longest chat message, in characters.
+const maxChat = 500
+
+var (
+	ErrSeatTaken   = errors.New("seat taken")
+	ErrWatching    = errors.New("watching, not playing")
+	ErrNotYourTurn = errors.New("not your turn")
+	ErrLeft        = errors.New("left the room")
+	ErrGameOver    = errors.New("game over")
+	ErrNoUndo      = errors.New("no move of yours to take back")
+	ErrChatClosed  = errors.New("chat is closed")
+	ErrChatTooLong = fmt.Errorf("chat message longer than %d characters", maxChat)
+	ErrChatTooSoon = errors.New("chatting too fast")
+)
+
+// Settings are chosen when a room is created.
+type Settings struct {
+	Size int
+	// MainTime is each player's time for the whole game; 0 plays without
+	// clocks.
+	MainTime time.Duration
+	// Chat says who may chat.
+	Chat ChatPolicy
+	// SlowChat is how long each member waits between two messages.
+	SlowChat time.Duration
+}
+
+// ChatPolicy says who may chat in a room.
+type ChatPolicy int
+
+const (
+	// ChatEveryone lets players and spectators chat.
+	ChatEveryone ChatPolicy = iota
+	// ChatPlayers lets only the players chat.
+	ChatPlayers
+	// ChatOff turns chat off.
+	ChatOff
+)
+
+// ParseChatPolicy reads a ChatPolicy named "everyone", "players" or
+// "off". An empty name is ChatEveryone.
+func ParseChatPolicy(name string) (ChatPolicy, error) {
+	switch name {
+	case "", "everyone":
+		return ChatEveryone, nil
+	case "players":
+		return ChatPlayers, nil
+	case "off":
+		return ChatOff, nil
+	}
+	return 0, fmt.Errorf("unknown chat policy %q", name)
+}
+
+// Hub keeps the open rooms by ID.
+type Hub struct {
+	mu    sync.Mutex
+	rooms map[string]*Room
+	// played counts the moves and passes played in the hub's rooms.
+	played atomic.Int64
+}
+
+// NewHub returns a hub without rooms.
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]*Room)}
+}
+
+// Create opens a room with a new game.
+func (h *Hub) Create(s Settings) *Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := newID()
+	for h.rooms[id] != nil {
+		id = newID()
+	}
+	r := &Room{
+		ID:       id,
+		Settings: s,
+		hub:      h,
+		game:     game.NewGame(s.Size),
+		seats:    make(map[board.Color]*Member),
+		members:  make(map[*Member]bool),
+		left:     map[board.Color]time.Duration{board.Black: s.MainTime, board.White: s.MainTime},
+		now:      time.Now,
+	}
+	if s.MainTime > 0 {
+		r.game.Root.Set("TM", seconds(s.MainTime))
+	}
+	h.rooms[id] = r
+	return r
+}
+
+// Get returns the room with the given ID.
+func (h *Hub) Get(id string) (*Room, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.rooms[id]
+	return r, ok
+}
+
+// Rooms returns the open rooms, in no particular order.
+func (h *Hub) Rooms() []*Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, r := range h.rooms {
+		rooms = append(rooms, r)
+	}
+	return rooms
+}
+
+// Played returns the number of moves and passes played in the hub's rooms,
+// open or closed, counting those taken back.
+func (h *Hub) Played() int64 {
+	return h.played.Load()
+}
+
+// Len returns the number of open rooms.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.rooms)
+}
+
+func (h *Hub) remove(r *Room) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.rooms, r.ID)
+}
+
+// idAlphabet leaves out letters and digits that are easily confused when
+// an ID is read out.
+const idAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// newID returns a short random room ID that is easy to share.
+func newID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = idAlphabet[int(b[i])%len(idAlphabet)]
+	}
+	return string(b)
+}
+
+// Room is one game played by the members in its two seats and watched by
+// the others.
+type Room struct {
+	ID       string
+	Settings Settings
+	hub      *Hub
+
+	mu      sync.Mutex
+	game    *game.Game
+	seats   map[board.Color]*Member
+	members map[*Member]bool
+	// undoFrom is the player asking to take back their last move.
+	undoFrom board.Color
+	// played counts the moves and passes played, taken back or not.
+	played int
+
+	// left is the time each player had when their clock last stopped.
+	// From the first move on, the clock of the player to move runs from
+	// since until stopClock is closed.
+	left      map[board.Color]time.Duration
+	since     time.Time
+	stopClock chan struct{}
+	now       func() time.Time
+}
+
+// Member is a player or spectator in a room. It receives an Event after
+// every change to the game until it leaves.
+type Member struct {
+	Name string
+	// Color is the member's seat, board.Empty for a spectator.
+	Color board.Color
+
+	room    *Room
+	events  chan Event
+	viewers chan int
+	// said is when the member last chatted.
+	said time.Time
+}
+
+// EventKind tells what an Event is about.
+type EventKind int
+
+const (
+	// GameChanged carries the whole game in SGF after a move, a pass or
+	// an undo, or when a player ran out of time.
+	GameChanged EventKind = iota
+	// ClockTick carries the clocks, every second while one runs.
+	ClockTick
+	// UndoRequested tells that the player of Color asks to take back
+	// their last move.
+	UndoRequested
+	// Chat carries Text said by From, of Color.
+	Chat
+	// Left tells that From left the seat of Color, or was dropped from
+	// it, and that the seat is free.
+	Left
+)
+
+// Event tells the members of a room what happened in it.
+type Event struct {
+	Kind  EventKind
+	SGF   string
+	Clock Clock
+	From  string
+	Color board.Color
+	Text  string
+}
+
+// Clock is the time both players have left, and whose clock runs.
+type Clock struct {
+	Black, White time.Duration
+	// Running is board.Empty while no clock runs.
+	Running board.Color
+}
+
+// Join adds a member called name. seat asks for the black or white seat;
+// board.Empty takes a free seat, or watches when both are taken. A player
+// who sits down first names the color in the game information.
+func (r *Room) Join(name string, seat board.Color) (*Member, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if seat == board.Empty {
+		for _, c := range []board.Color{board.Black, board.White} {
+			if r.seats[c] == nil {
+				seat = c
+				break
+			}
+		}
+	} else if r.seats[seat] != nil {
+		return nil, fmt.Errorf("%s %w", strings.ToLower(seat.String()), ErrSeatTaken)
+	}
+	m := r.add(name, seat)
+	if seat != board.Empty {
+		id := "PB"
+		if seat == board.White {
+			id = "PW"
+		}
+		if name != "" && r.game.Info(id) == "" {
+			r.game.Root.Set(id, name)
+		}
+	}
+	return m, nil
+}
+
+// Watch adds a spectator called name.
+func (r *Room) Watch(name string) *Member {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.add(name, board.Empty)
+}
+
+func (r *Room) add(name string, seat board.Color) *Member {
+	m := &Member{Name: name, Color: seat, room: r, events: make(chan Event, eventBuffer), viewers: make(chan int, 1)}
+	r.members[m] = true
+	if seat != board.Empty {
+		r.seats[seat] = m
+		m.viewers <- len(r.members) - len(r.seats)
+	} else {
+		r.countViewers()
+	}
+	return m
+}
+
+// countViewers tells every member how many spectators the room has,
+// replacing any count they have not read yet.
+func (r *Room) countViewers() {
+	n := len(r.members) - len(r.seats)
+	for m := range r.members {
+		select {
+		case <-m.viewers:
+		default:
+		}
+		m.viewers <- n
+	}
+}
+
+// SGF returns the room's game.
+func (r *Room) SGF() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.game.SGF()
+}
+
+// Size returns the size of the room's board.
+func (r *Room) Size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.game.Board.Size
+}
+
+// Played returns the number of moves and passes played in the room,
+// counting those taken back.
+func (r *Room) Played() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.played
+}
+
+// Players returns the number of members in seats and of spectators.
+func (r *Room) Players() (players, spectators int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.seats), len(r.members) - len(r.seats)
+}
+
+// Clock returns the players' clocks, or false if the room has none.
+func (r *Room) Clock() (Clock, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.clock(), r.Settings.MainTime > 0
+}
+
+// remaining is the time left on the running clock.
+func (c Clock) remaining() time.Duration {
+	if c.Running == board.White {
+		return c.White
+	}
+	return c.Black
+}
+
+func (r *Room) clock() Clock {
+	c := Clock{Black: r.left[board.Black], White: r.left[board.White]}
+	if r.stopClock == nil {
+		return c
+	}
+	c.Running = r.game.CurrentPlayer
+	used := r.now().Sub(r.since)
+	if c.Running == board.Black {
+		c.Black = max(c.Black-used, 0)
+	} else {
+		c.White = max(c.White-used, 0)
+	}
+	return c
+}
+
+// seated checks that m may act as a player in the room's game.
+func (r *Room) seated(m *Member) error {
+	switch {
+	case !r.members[m]:
+		return ErrLeft
+	case m.Color == board.Empty:
+		return ErrWatching
+	case r.game.Info("RE") != "":
+		return ErrGameOver
+	}
+	return nil
+}
+
+// play plays member m's stone at p, or passes when p is nil, if it is m's
+// turn and the rules allow the move, and sends the game to everyone. The
+// first move starts the clocks, and every move records the mover's time
+// left.
+func (r *Room) play(m *Member, p *board.Point) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.seated(m); err != nil {
+		return err
+	}
+	if m.Color != r.game.CurrentPlayer {
+		return ErrNotYourTurn
+	}
+	left := r.clock()
+	if r.stopClock != nil && left.remaining() <= 0 {
+		r.timeout(left)
+		return ErrGameOver
+	}
+	if p == nil {
+		r.game.Pass()
+	} else if err := r.game.Move(p.X, p.Y); err != nil {
+		return err
+	}
+	r.undoFrom = board.Empty
+	r.played++
+	r.hub.played.Add(1)
+	if r.Settings.MainTime > 0 {
+		r.left = map[board.Color]time.Duration{board.Black: left.Black, board.White: left.White}
+		id := "BL"
+		if m.Color == board.White {
+			id = "WL"
+		}
+		r.game.Current.Set(id, seconds(r.left[m.Color]))
+		r.since = r.now()
+		if r.stopClock == nil {
+			r.stopClock = make(chan struct{})
+			go r.runClock(r.stopClock)
+		}
+	}
+	r.broadcast(Event{Kind: GameChanged, SGF: r.game.SGF()})
+	return nil
+}
+
+// undo asks to take back m's last move, or, if the other player asked
+// for it, agrees and takes it back.
+func (r *Room) undo(m *Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.seated(m); err != nil {
+		return err
+	}
+	if r.undoFrom == m.Color.Opposite() {
+		r.undoFrom = board.Empty
+		left := r.clock()
+		if err := r.game.DeleteMoves(r.game.Current.MoveNumber(), r.game.Current.MoveNumber(), false); err != nil {
+			return err
+		}
+		r.left = map[board.Color]time.Duration{board.Black: left.Black, board.White: left.White}
+		r.since = r.now()
+		r.broadcast(Event{Kind: GameChanged, SGF: r.game.SGF()})
+		return nil
+	}
+	last := r.game.Current
+	if !last.IsMove() || last.Color != m.Color {
+		return ErrNoUndo
+	}
+	r.undoFrom = m.Color
+	r.broadcast(Event{Kind: UndoRequested, From: m.Name, Color: m.Color})
+	return nil
+}
+
+// say sends text from m to everyone in the room, if the room's settings
+// let m chat, and adds it to the comment of the game's current node.
+func (r *Room) say(m *Member, text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.members[m] {
+		return ErrLeft
+	}
+	switch {
+	case r.Settings.Chat == ChatOff:
+		return ErrChatClosed
+	case r.Settings.Chat == ChatPlayers && m.Color == board.Empty:
+		return fmt.Errorf("%w to spectators", ErrChatClosed)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(text) > maxChat {
+		return ErrChatTooLong
+	}
+	now := r.now()
+	if !m.said.IsZero() && now.Sub(m.said) < r.Settings.SlowChat {
+		return ErrChatTooSoon
+	}
+	m.said = now
+
+	line := Speaker(m.Name, m.Color) + ": " + text
+	if c := r.game.Current.Comment; c != "" {
+		line = strings.TrimRight(c, "\n") + "\n" + line
+	}
+	r.game.Current.Comment = line
+	r.broadcast(Event{Kind: Chat, From: m.Name, Color: m.Color, Text: text})
+	return nil
+}
+
+// Speaker names who chats, as in the game record: by name, or else by
+// seat.
+func Speaker(name string, seat board.Color) string {
+	switch {
+	case name != "":
+		return name
+	case seat == board.Empty:
+		return "Spectator"
+	}
+	return seat.String()
+}
+
+// runClock ticks the running clock every second until stop is closed or
+// the game ends.
+func (r *Room) runClock(stop <-chan struct{}) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			if !r.tick() {
+				return
+			}
+		}
+	}
+}
+
+// tick sends the clocks to everyone, or ends the game when the player to
+// move ran out of time. It reports whether the clock still runs.
+func (r *Room) tick() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopClock == nil {
+		return false
+	}
+	c := r.clock()
+	if c.remaining() > 0 {
+		r.broadcast(Event{Kind: ClockTick, Clock: c})
+		return true
+	}
+	r.timeout(c)
+	return false
+}
+
+// timeout ends the game, won by the other player, when the player whose
+// clock runs in c is out of time.
+func (r *Room) timeout(c Clock) {
+	r.left = map[board.Color]time.Duration{board.Black: c.Black, board.White: c.White}
+	r.game.Root.Set("RE", c.Running.Opposite().String()[:1]+"+T")
+	r.stop()
+	r.broadcast(Event{Kind: GameChanged, SGF: r.game.SGF()})
+}
+
+// stop stops the running clock.
+func (r *Room) stop() {
+	if r.stopClock != nil {
+		close(r.stopClock)
+		r.stopClock = nil
+	}
+}
+
+// seconds formats d for SGF time properties.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// Notice describes a Left event for people.
+func (ev Event) Notice() string {
+	seat := strings.ToLower(ev.Color.String())
+	return fmt.Sprintf("%s left, the %s seat is free", Speaker(ev.From, ev.Color), seat)
+}
+
+// broadcast sends ev to every member. A member too far behind to take it
+// is dropped as if it left, and sees its events channel closed.
+func (r *Room) broadcast(ev Event) {
+	var dropped []*Member
+	for m := range r.members {
+		select {
+		case m.events <- ev:
+		default:
+			dropped = append(dropped, m)
+		}
+	}
+	for _, m := range dropped {
+		// A member may have gone while telling another that it was
+		// dropped.
+		if r.members[m] {
+			r.leave(m)
+		}
+	}
+}
+
+// leave takes m out of the room, telling the others when it frees a seat.
+// The last member to leave closes the room.
+func (r *Room) leave(m *Member) {
+	delete(r.members, m)
+	close(m.events)
+	close(m.viewers)
+	if r.seats[m.Color] == m {
+		delete(r.seats, m.Color)
+		r.broadcast(Event{Kind: Left, From: m.Name, Color: m.Color})
+	} else {
+		r.countViewers()
+	}
+	if len(r.members) == 0 {
+		r.stop()
+		r.hub.remove(r)
+	}
+}
+
+// Events returns the events of m's room, closed when m leaves or falls
+// too far behind.
+func (m *Member) Events() <-chan Event {
+	return m.events
+}
+
+// Viewers returns the number of spectators in m's room: first when m
+// joins, then whenever it changes. Only the latest count is kept for a
+// member that does not read it, and the channel closes with Events.
+func (m *Member) Viewers() <-chan int {
+	return m.viewers
+}
+
+// Play asks to play a stone at p, or to pass when p is nil.
+func (m *Member) Play(p *board.Point) error {
+	return m.room.play(m, p)
+}
+
+// Undo asks to take back m's last move, or agrees to take back the other
+// player's when they asked for it. Any move in between drops the request.
+func (m *Member) Undo() error {
+	return m.room.undo(m)
+}
+
+// Say sends a chat message to everyone in the room.
+func (m *Member) Say(text string) error {
+	return m.room.say(m, text)
+}
+
+// Room returns the member's room.
+func (m *Member) Room() *Room {
+	return m.room
+}
+
+// Leave takes m out of its room, freeing its seat. The last member to
+// leave closes the room.
+func (m *Member) Leave() {
+	r := m.room
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.members[m] {
+		return
+	}
+	r.leave(m)
+}
@@ -0,0 +1,338 @@
+package room
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+func TestRoom_SeatsAndSpectators(t *testing.T) {
+	h := NewHub()
+	r := h.Create(Settings{Size: 9})
+	if got, ok := h.Get(r.ID); !ok || got != r {
+		t.Fatalf("expected to find room %q", r.ID)
+	}
+	if rooms := h.Rooms(); len(rooms) != 1 || rooms[0] != r {
+		t.Fatalf("expected room %q alone in the hub, got %v", r.ID, rooms)
+	}
+
+	black, err := r.Join("alice", board.Empty)
+	if err != nil || black.Color != board.Black {
+		t.Fatalf("expected the first player to sit at black, got %v %v", black, err)
+	}
+	if _, err := r.Join("mallory", board.Black); !errors.Is(err, ErrSeatTaken) {
+		t.Fatalf("expected the black seat to be taken, got %v", err)
+	}
+	white, err := r.Join("bob", board.Empty)
+	if err != nil || white.Color != board.White {
+		t.Fatalf("expected the second player to sit at white, got %v %v", white, err)
+	}
+	carol, err := r.Join("carol", board.Empty)
+	if err != nil || carol.Color != board.Empty {
+		t.Fatalf("expected a third player to watch, got %v %v", carol, err)
+	}
+	if players, spectators := r.Players(); players != 2 || spectators != 1 {
+		t.Fatalf("expected 2 players and 1 spectator, got %d and %d", players, spectators)
+	}
+
+	g, _ := game.Load(r.SGF())
+	if g.Info("PB") != "alice" || g.Info("PW") != "bob" {
+		t.Fatalf("expected the players' names in the game, got PB=%q PW=%q", g.Info("PB"), g.Info("PW"))
+	}
+
+	// A freed seat can be taken again.
+	black.Leave()
+	if m, err := r.Join("dave", board.Black); err != nil || m.Color != board.Black {
+		t.Fatalf("expected to sit at the freed black seat, got %v", err)
+	}
+}
+
+func TestRoom_PlayValidatesAndBroadcasts(t *testing.T) {
+	r := NewHub().Create(Settings{Size: 9})
+	black, _ := r.Join("alice", board.Black)
+	white, _ := r.Join("bob", board.White)
+	watcher := r.Watch("carol")
+
+	if err := white.Play(&board.Point{X: 2, Y: 2}); !errors.Is(err, ErrNotYourTurn) {
+		t.Fatalf("expected white to wait for black, got %v", err)
+	}
+	if err := watcher.Play(&board.Point{X: 2, Y: 2}); !errors.Is(err, ErrWatching) {
+		t.Fatalf("expected a spectator not to play, got %v", err)
+	}
+	if err := black.Play(&board.Point{X: 2, Y: 2}); err != nil {
+		t.Fatalf("black's move failed: %v", err)
+	}
+	if err := white.Play(&board.Point{X: 2, Y: 2}); err == nil {
+		t.Fatalf("expected a move on an occupied point to be rejected")
+	}
+	if err := white.Play(nil); err != nil {
+		t.Fatalf("white's pass failed: %v", err)
+	}
+
+	for _, m := range []*Member{black, white, watcher} {
+		var ev Event
+		for i := 0; i < 2; i++ {
+			ev = <-m.Events()
+		}
+		g, err := game.Load(ev.SGF)
+		if err != nil {
+			t.Fatalf("bad SGF in event: %v", err)
+		}
+		if g.Board.At(2, 2) != board.Black || g.Current.MoveNumber() != 2 {
+			t.Fatalf("%s got the wrong game: %s", m.Name, ev.SGF)
+		}
+	}
+}
+
+func TestRoom_SlowMemberIsDropped(t *testing.T) {
+	r := NewHub().Create(Settings{Size: 9})
+	black, _ := r.Join("alice", board.Black)
+	white, _ := r.Join("bob", board.White)
+	for i := 0; i <= eventBuffer; i++ {
+		m := black
+		if i%2 == 1 {
+			m = white
+		}
+		if err := m.Play(nil); err != nil {
+			t.Fatalf("pass %d failed: %v", i, err)
+		}
+		// White keeps up; black never reads.
+		<-white.Events()
+	}
+	n := 0
+	for range black.Events() {
+		n++
+	}
+	if n != eventBuffer {
+		t.Fatalf("expected %d events before black was dropped, got %d", eventBuffer, n)
+	}
+	if err := black.Play(nil); !errors.Is(err, ErrLeft) {
+		t.Fatalf("expected a dropped member to be out of the room, got %v", err)
+	}
+	if ev := <-white.Events(); ev.Kind != Left || ev.Color != board.Black || ev.From != "alice" {
+		t.Fatalf("expected white told that black's seat is free, got %+v", ev)
+	}
+	if m, err := r.Join("dave", board.Black); err != nil || m.Color != board.Black {
+		t.Fatalf("expected the dropped member's seat free, got %v", err)
+	}
+}
+
+func TestRoom_DroppingLastMemberClosesRoom(t *testing.T) {
+	h := NewHub()
+	r := h.Create(Settings{Size: 9, MainTime: time.Minute})
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+	black, _ := r.Join("alice", board.Black)
+	white, _ := r.Join("bob", board.White)
+	black.Play(&board.Point{X: 2, Y: 2})
+	white.Leave()
+
+	// Black never reads, and is dropped by the clock ticks, which stop
+	// with the room.
+	for ticks := 0; r.tick(); ticks++ {
+		if ticks > eventBuffer {
+			t.Fatalf("expected black dropped and the clock stopped")
+		}
+	}
+	if _, ok := h.Get(r.ID); ok || h.Len() != 0 {
+		t.Fatalf("expected the room to close with its last member dropped")
+	}
+}
+
+func TestRoom_ViewerCount(t *testing.T) {
+	r := NewHub().Create(Settings{Size: 9})
+	black, _ := r.Join("alice", board.Black)
+	if n := <-black.Viewers(); n != 0 {
+		t.Fatalf("expected no viewers yet, got %d", n)
+	}
+	carol := r.Watch("carol")
+	r.Watch("dave")
+	if n := <-black.Viewers(); n != 2 || len(black.Viewers()) != 0 {
+		t.Fatalf("expected only the latest count of 2, got %d", n)
+	}
+	if n := <-carol.Viewers(); n != 2 {
+		t.Fatalf("expected a viewer to count itself, got %d", n)
+	}
+	carol.Leave()
+	if n := <-black.Viewers(); n != 1 {
+		t.Fatalf("expected 1 viewer after one left, got %d", n)
+	}
+	if _, ok := <-carol.Viewers(); ok {
+		t.Fatalf("expected the count to close for a member who left")
+	}
+}
+
+func TestRoom_LastMemberClosesRoom(t *testing.T) {
+	h := NewHub()
+	r := h.Create(Settings{Size: 13})
+	a, _ := r.Join("alice", board.Empty)
+	b := r.Watch("bob")
+	a.Leave()
+	if _, ok := h.Get(r.ID); !ok {
+		t.Fatalf("room closed while a spectator was still in it")
+	}
+	b.Leave()
+	b.Leave()
+	if _, ok := h.Get(r.ID); ok || h.Len() != 0 {
+		t.Fatalf("expected the room to close with its last member")
+	}
+}
+
+func TestRoom_UndoNeedsTheOpponent(t *testing.T) {
+	h := NewHub()
+	r := h.Create(Settings{Size: 9})
+	black, _ := r.Join("alice", board.Black)
+	white, _ := r.Join("bob", board.White)
+	black.Play(&board.Point{X: 2, Y: 2})
+
+	if err := white.Undo(); !errors.Is(err, ErrNoUndo) {
+		t.Fatalf("expected white to have nothing to take back, got %v", err)
+	}
+	if err := black.Undo(); err != nil {
+		t.Fatalf("black's request failed: %v", err)
+	}
+	if err := white.Undo(); err != nil {
+		t.Fatalf("white's agreement failed: %v", err)
+	}
+
+	var kinds []EventKind
+	var last Event
+	for len(kinds) < 3 {
+		last = <-white.Events()
+		kinds = append(kinds, last.Kind)
+	}
+	if kinds[0] != GameChanged || kinds[1] != UndoRequested || kinds[2] != GameChanged {
+		t.Fatalf("unexpected events %v", kinds)
+	}
+	g, _ := game.Load(last.SGF)
+	if g.Current.MoveNumber() != 0 || g.Board.At(2, 2) != board.Empty || g.CurrentPlayer != board.Black {
+		t.Fatalf("expected the move taken back, got %s", last.SGF)
+	}
+
+	// A move in between drops the request.
+	black.Play(&board.Point{X: 2, Y: 2})
+	black.Undo()
+	white.Play(&board.Point{X: 6, Y: 6})
+	if err := white.Undo(); err != nil {
+		t.Fatalf("white's own request failed: %v", err)
+	}
+	if err := black.Undo(); err != nil || r.SGF() == "" {
+		t.Fatalf("black's agreement failed: %v", err)
+	}
+	g, _ = game.Load(r.SGF())
+	if g.Current.MoveNumber() != 1 || g.Board.At(2, 2) != board.Black {
+		t.Fatalf("expected only white's move taken back, got %s", r.SGF())
+	}
+	if r.Played() != 3 || h.Played() != 3 {
+		t.Fatalf("expected 3 moves played, taken back or not, got %d and %d in the hub", r.Played(), h.Played())
+	}
+}
+
+func TestRoom_Chat(t *testing.T) {
+	r := NewHub().Create(Settings{Size: 9})
+	black, _ := r.Join("alice", board.Black)
+	watcher := r.Watch("carol")
+	if err := watcher.Say("  good luck  "); err != nil {
+		t.Fatalf("Say failed: %v", err)
+	}
+	watcher.Say(" ")
+	ev := <-black.Events()
+	if ev.Kind != Chat || ev.From != "carol" || ev.Color != board.Empty || ev.Text != "good luck" {
+		t.Fatalf("unexpected chat event %+v", ev)
+	}
+	if len(black.Events()) != 0 {
+		t.Fatalf("expected an empty message not to be sent")
+	}
+}
+
+func TestRoom_ChatIsRecorded(t *testing.T) {
+	r := NewHub().Create(Settings{Size: 9})
+	black, _ := r.Join("alice", board.Black)
+	watcher := r.Watch("")
+	black.Say("hello")
+	black.Play(&board.Point{X: 2, Y: 2})
+	watcher.Say("nice")
+	black.Say("thanks")
+
+	g, _ := game.Load(r.SGF())
+	if c := g.Root.Comment; c != "alice: hello" {
+		t.Fatalf("expected the first message on the root, got %q", c)
+	}
+	if c := g.Current.Comment; c != "Spectator: nice\nalice: thanks" {
+		t.Fatalf("expected the later messages on the move, got %q", c)
+	}
+}
+
+func TestRoom_ChatModeration(t *testing.T) {
+	r := NewHub().Create(Settings{Size: 9, Chat: ChatPlayers, SlowChat: 3 * time.Second})
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+	black, _ := r.Join("alice", board.Black)
+	watcher := r.Watch("carol")
+
+	if err := watcher.Say("hi"); !errors.Is(err, ErrChatClosed) {
+		t.Fatalf("expected spectators not to chat, got %v", err)
+	}
+	if err := black.Say(strings.Repeat("a", maxChat+1)); !errors.Is(err, ErrChatTooLong) {
+		t.Fatalf("expected a long message to be refused, got %v", err)
+	}
+	if err := black.Say("hi"); err != nil {
+		t.Fatalf("Say failed: %v", err)
+	}
+	now = now.Add(time.Second)
+	if err := black.Say("again"); !errors.Is(err, ErrChatTooSoon) {
+		t.Fatalf("expected to wait between messages, got %v", err)
+	}
+	now = now.Add(2 * time.Second)
+	if err := black.Say("again"); err != nil {
+		t.Fatalf("Say after waiting failed: %v", err)
+	}
+
+	r.Settings.Chat = ChatOff
+	if err := black.Say("bye"); !errors.Is(err, ErrChatClosed) {
+		t.Fatalf("expected chat to be off, got %v", err)
+	}
+	if _, err := ParseChatPolicy("loud"); err == nil {
+		t.Fatalf("expected an unknown policy to fail")
+	}
+}
+
+func TestRoom_Clocks(t *testing.T) {
+	r := NewHub().Create(Settings{Size: 9, MainTime: time.Minute})
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+	black, _ := r.Join("alice", board.Black)
+	white, _ := r.Join("bob", board.White)
+
+	if c, ok := r.Clock(); !ok || c.Running != board.Empty || c.Black != time.Minute {
+		t.Fatalf("expected stopped clocks before the first move, got %+v", c)
+	}
+	black.Play(&board.Point{X: 2, Y: 2})
+	now = now.Add(20 * time.Second)
+	white.Play(&board.Point{X: 6, Y: 6})
+	now = now.Add(15 * time.Second)
+	c, _ := r.Clock()
+	if c.Running != board.Black || c.Black != 45*time.Second || c.White != 40*time.Second {
+		t.Fatalf("unexpected clocks %+v", c)
+	}
+	g, _ := game.Load(r.SGF())
+	if left, _, ok := g.TimeLeft(board.White); !ok || left != 40*time.Second {
+		t.Fatalf("expected white's time recorded with the move, got %v %v", left, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if r.tick() {
+		t.Fatalf("expected the clock to stop when black ran out of time")
+	}
+	if err := black.Play(&board.Point{X: 4, Y: 4}); !errors.Is(err, ErrGameOver) {
+		t.Fatalf("expected no moves after the game ended, got %v", err)
+	}
+	g, _ = game.Load(r.SGF())
+	if g.Info("RE") != "W+T" {
+		t.Fatalf("expected white to win on time, got %q", g.Info("RE"))
+	}
+}
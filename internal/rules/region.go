@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// RegionStats summarizes the stones, territory and liberties found inside a
+// rectangular region of the board.
+type RegionStats struct {
+	BlackStones    int
+	WhiteStones    int
+	BlackTerritory int
+	WhiteTerritory int
+	BlackLiberties int
+	WhiteLiberties int
+	Dame           int
+}
+
+// CountRegion computes RegionStats for r. Territory ownership is decided on
+// the whole board, so an empty point only counts when the enclosing area
+// touches a single color. Liberties are those of every group with a stone
+// inside r, counted once per point and color, and only when they lie in r.
+func CountRegion(b *board.Board, r board.Rect) RegionStats {
+	var stats RegionStats
+	visited := make([]bool, b.Size*b.Size)
+	owner := make([]board.Color, b.Size*b.Size)
+	blackLibs := make(map[board.Point]bool)
+	whiteLibs := make(map[board.Point]bool)
+
+	for _, p := range r.Points() {
+		if !b.IsOnBoard(p.X, p.Y) {
+			continue
+		}
+		switch b.At(p.X, p.Y) {
+		case board.Black:
+			stats.BlackStones++
+			for _, l := range GetGroup(b, p.X, p.Y).Liberties {
+				if r.Contains(l.X, l.Y) {
+					blackLibs[l] = true
+				}
+			}
+		case board.White:
+			stats.WhiteStones++
+			for _, l := range GetGroup(b, p.X, p.Y).Liberties {
+				if r.Contains(l.X, l.Y) {
+					whiteLibs[l] = true
+				}
+			}
+		default:
+			idx := p.Y*b.Size + p.X
+			if !visited[idx] {
+				points, color := getTerritory(b, p.X, p.Y, visited)
+				for _, t := range points {
+					owner[t.Y*b.Size+t.X] = color
+				}
+			}
+			switch owner[idx] {
+			case board.Black:
+				stats.BlackTerritory++
+			case board.White:
+				stats.WhiteTerritory++
+			default:
+				stats.Dame++
+			}
+		}
+	}
+
+	stats.BlackLiberties = len(blackLibs)
+	stats.WhiteLiberties = len(whiteLibs)
+	return stats
+}
@@ -0,0 +1,32 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestCountRegion(t *testing.T) {
+	b := board.New(5)
+	// Black encloses the corner point (0,0); White sits in the far corner.
+	b.Set(0, 1, board.Black)
+	b.Set(1, 0, board.Black)
+	b.Set(4, 4, board.White)
+
+	stats := CountRegion(b, board.NewRect(board.Point{X: 0, Y: 0}, board.Point{X: 1, Y: 1}))
+
+	if stats.BlackStones != 2 || stats.WhiteStones != 0 {
+		t.Fatalf("stone counts mismatch: %+v", stats)
+	}
+	if stats.BlackTerritory != 1 {
+		t.Fatalf("expected 1 point of black territory, got %d", stats.BlackTerritory)
+	}
+	// (1,1) is part of the big shared area, so it is dame.
+	if stats.Dame != 1 {
+		t.Fatalf("expected 1 dame point, got %d", stats.Dame)
+	}
+	// Liberties inside the region: (0,0) and (1,1).
+	if stats.BlackLiberties != 2 {
+		t.Fatalf("expected 2 black liberties in region, got %d", stats.BlackLiberties)
+	}
+}
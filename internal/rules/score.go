@@ -61,6 +61,37 @@ func CountScore(b *board.Board, method string, blackCaptures, whiteCaptures int,
 	return Score{Black: blackScore, White: whiteScore}
 }
 
+// TerritoryAt returns the empty region containing (x, y) and its owner
+// (Empty if the region touches both colors or none). It returns nil if the
+// point is occupied.
+func TerritoryAt(b *board.Board, x, y int) ([]board.Point, board.Color) {
+	if !b.IsOnBoard(x, y) || b.At(x, y) != board.Empty {
+		return nil, board.Empty
+	}
+	visited := make([]bool, b.Size*b.Size)
+	return getTerritory(b, x, y, visited)
+}
+
+// RemoveDead returns a copy of b without the stones marked dead, together
+// with the number of dead black and white stones. Dead stones count as
+// prisoners for their opponent under territory scoring.
+func RemoveDead(b *board.Board, dead map[board.Point]bool) (*board.Board, int, int) {
+	alive := b.Copy()
+	blackDead, whiteDead := 0, 0
+	for p := range dead {
+		switch alive.At(p.X, p.Y) {
+		case board.Black:
+			blackDead++
+		case board.White:
+			whiteDead++
+		default:
+			continue
+		}
+		alive.Set(p.X, p.Y, board.Empty)
+	}
+	return alive, blackDead, whiteDead
+}
+
 // getTerritory performs flood fill to find connected empty points and determines ownership.
 // Returns the list of points and the owner (Black, White, or Empty if shared/dame).
 func getTerritory(b *board.Board, startX, startY int, visited []bool) ([]board.Point, board.Color) {
@@ -71,3 +71,41 @@ func TestCountScoreUnknownMethodFallsBackToChinese(t *testing.T) {
 		t.Fatalf("white score mismatch: got %.1f want 1.0", score.White)
 	}
 }
+
+func TestRemoveDeadGivesTerritoryAndPrisoners(t *testing.T) {
+	b := board.New(5)
+	// A black wall on column 1 with a dead white stone in its territory.
+	for y := 0; y < 5; y++ {
+		b.Set(1, y, board.Black)
+	}
+	b.Set(0, 2, board.White)
+
+	alive, blackDead, whiteDead := RemoveDead(b, map[board.Point]bool{{X: 0, Y: 2}: true})
+	if blackDead != 0 || whiteDead != 1 {
+		t.Fatalf("dead counts mismatch: black %d white %d", blackDead, whiteDead)
+	}
+	if b.At(0, 2) != board.White {
+		t.Fatalf("RemoveDead must not modify the original board")
+	}
+
+	score := CountScore(alive, "japanese", whiteDead, blackDead, 0)
+	// 5 points on the left, 15 on the right, plus one prisoner.
+	if score.Black != 21.0 {
+		t.Fatalf("black score mismatch: got %.1f want 21.0", score.Black)
+	}
+}
+
+func TestTerritoryAt(t *testing.T) {
+	b := board.New(3)
+	b.Set(1, 0, board.Black)
+	b.Set(1, 1, board.Black)
+	b.Set(1, 2, board.Black)
+
+	points, owner := TerritoryAt(b, 0, 0)
+	if len(points) != 3 || owner != board.Black {
+		t.Fatalf("expected 3 black points, got %d owned by %v", len(points), owner)
+	}
+	if points, _ := TerritoryAt(b, 1, 1); points != nil {
+		t.Fatalf("expected no territory on a stone, got %v", points)
+	}
+}
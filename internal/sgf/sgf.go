@@ -2,15 +2,29 @@ package sgf
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/vimgo/vimgo/internal/board"
 )
 
+// MaxSize is the largest board size SGF coordinates reach: a to z for the
+// first 26 lines, A to Z for the next.
+const MaxSize = 52
+
 // ToSGFCoord converts x, y coordinates to SGF style (e.g., 0,0 -> "aa").
 func ToSGFCoord(x, y int) string {
 	if x < 0 || y < 0 {
 		return ""
 	}
-	return string(rune('a'+x)) + string(rune('a'+y))
+	return string(coordLetter(x)) + string(coordLetter(y))
+}
+
+func coordLetter(i int) rune {
+	if i >= 26 {
+		return rune('A' + i - 26)
+	}
+	return rune('a' + i)
 }
 
 // FromSGFCoord converts SGF style coordinates to x, y (e.g., "pd" -> 15, 3).
@@ -18,11 +32,23 @@ func FromSGFCoord(coord string) (int, int, error) {
 	if len(coord) != 2 {
 		return -1, -1, fmt.Errorf("invalid SGF coord length: %s", coord)
 	}
-	x := int(coord[0] - 'a')
-	y := int(coord[1] - 'a')
+	x, y := coordIndex(coord[0]), coordIndex(coord[1])
+	if x < 0 || y < 0 {
+		return -1, -1, fmt.Errorf("invalid SGF coord: %s", coord)
+	}
 	return x, y, nil
 }
 
+func coordIndex(c byte) int {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return int(c - 'a')
+	case 'A' <= c && c <= 'Z':
+		return int(c-'A') + 26
+	}
+	return -1
+}
+
 // EncodeMove creates an SGF move string, e.g., "B[pd]" or "W[aa]".
 func EncodeMove(color string, x, y int) string {
 	if x < 0 || y < 0 {
@@ -31,65 +57,250 @@ func EncodeMove(color string, x, y int) string {
 	return fmt.Sprintf("%s[%s]", color, ToSGFCoord(x, y))
 }
 
-// SimpleSGFWriter creates a basic SGF string from a sequence of moves.
-func SimpleSGFWriter(size int, moves []string) string {
-	var sb strings.Builder
-	sb.WriteString("(;GM[1]FF[4]CA[UTF-8]")
-	sb.WriteString(fmt.Sprintf("SZ[%d]", size))
-	for _, m := range moves {
-		sb.WriteString(";")
-		sb.WriteString(m)
+// Property is a single SGF property such as AB[aa][bb].
+type Property struct {
+	ID     string
+	Values []string
+}
+
+// Node is a parsed SGF node with its properties in file order. Children
+// holds the variations; the first child is the main line.
+type Node struct {
+	Props    []Property
+	Children []*Node
+}
+
+// Get returns the first value of property id.
+func (n *Node) Get(id string) (string, bool) {
+	for _, p := range n.Props {
+		if p.ID == id && len(p.Values) > 0 {
+			return p.Values[0], true
+		}
 	}
-	sb.WriteString(")")
-	return sb.String()
+	return "", false
 }
 
-// ParseSGF parses a simple SGF string and returns the board size and a list of moves.
-func ParseSGF(content string) (int, []string, error) {
-	size := 19
-	var moves []string
-
-	inVal := false
-	propKey := ""
-	valBuf := ""
-	
-	// Normalize content
-	content = strings.TrimSpace(content)
-	
-	for i := 0; i < len(content); i++ {
-		char := content[i]
-		
-		if inVal {
-			if char == ']' {
-				inVal = false
-				// Process property
-				switch propKey {
-				case "SZ":
-					fmt.Sscanf(valBuf, "%d", &size)
-				case "B", "W":
-					moves = append(moves, fmt.Sprintf("%s[%s]", propKey, valBuf))
-				case "AB", "AW": 
-					// Setup stones, treat as moves for visual simplicity or handle separately? 
-					// For now, let's just focus on B/W moves.
-				}
+// Set replaces property id with the given values, or removes it when none
+// are given.
+func (n *Node) Set(id string, values ...string) {
+	for i, p := range n.Props {
+		if p.ID == id {
+			if len(values) == 0 {
+				n.Props = append(n.Props[:i], n.Props[i+1:]...)
 			} else {
-				valBuf += string(char)
+				n.Props[i].Values = values
 			}
+			return
+		}
+	}
+	if len(values) > 0 {
+		n.Props = append(n.Props, Property{ID: id, Values: values})
+	}
+}
+
+// ParsePoints decodes a list of point values, expanding compressed
+// rectangles such as "aa:cc".
+func ParsePoints(values []string) ([]board.Point, error) {
+	var points []board.Point
+	for _, v := range values {
+		from, to, isRange := strings.Cut(v, ":")
+		x0, y0, err := FromSGFCoord(from)
+		if err != nil {
+			return nil, err
+		}
+		if !isRange {
+			points = append(points, board.Point{X: x0, Y: y0})
 			continue
 		}
+		x1, y1, err := FromSGFCoord(to)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, board.NewRect(board.Point{X: x0, Y: y0}, board.Point{X: x1, Y: y1}).Points()...)
+	}
+	return points, nil
+}
+
+// EncodePoints encodes points as property values in row-major order.
+func EncodePoints(points []board.Point) []string {
+	sorted := append([]board.Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Y != sorted[j].Y {
+			return sorted[i].Y < sorted[j].Y
+		}
+		return sorted[i].X < sorted[j].X
+	})
+	values := make([]string, len(sorted))
+	for i, p := range sorted {
+		values[i] = ToSGFCoord(p.X, p.Y)
+	}
+	return values
+}
+
+// Parse reads the first game tree of an SGF collection.
+func Parse(content string) (*Node, error) {
+	p := &parser{src: content}
+	p.skipSpace()
+	if !p.consume('(') {
+		return nil, fmt.Errorf("sgf: expected '(' at offset %d", p.pos)
+	}
+	root, err := p.parseTree()
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, fmt.Errorf("sgf: empty game tree")
+	}
+	return root, nil
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] <= ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseTree parses a sequence of nodes and its variations after the opening
+// parenthesis, returning the first node of the sequence.
+func (p *parser) parseTree() (*Node, error) {
+	var first, last *Node
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("sgf: unexpected end of input")
+		}
+		switch p.src[p.pos] {
+		case ';':
+			p.pos++
+			n, err := p.parseNode()
+			if err != nil {
+				return nil, err
+			}
+			if last == nil {
+				first = n
+			} else {
+				last.Children = append(last.Children, n)
+			}
+			last = n
+		case '(':
+			p.pos++
+			child, err := p.parseTree()
+			if err != nil {
+				return nil, err
+			}
+			if last == nil {
+				return nil, fmt.Errorf("sgf: variation before first node at offset %d", p.pos)
+			}
+			if child != nil {
+				last.Children = append(last.Children, child)
+			}
+		case ')':
+			p.pos++
+			return first, nil
+		default:
+			return nil, fmt.Errorf("sgf: unexpected %q at offset %d", p.src[p.pos], p.pos)
+		}
+	}
+}
 
-		switch char {
-		case '[':
-			inVal = true
-			valBuf = ""
-		case '(', ')', ';':
-			propKey = ""
+func (p *parser) parseNode() (*Node, error) {
+	n := &Node{}
+	for {
+		p.skipSpace()
+		start := p.pos
+		for p.pos < len(p.src) && p.src[p.pos] >= 'A' && p.src[p.pos] <= 'Z' {
+			p.pos++
+		}
+		if start == p.pos {
+			return n, nil
+		}
+		prop := Property{ID: p.src[start:p.pos]}
+		for {
+			p.skipSpace()
+			if !p.consume('[') {
+				break
+			}
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			prop.Values = append(prop.Values, v)
+		}
+		if len(prop.Values) == 0 {
+			return nil, fmt.Errorf("sgf: property %s without value", prop.ID)
+		}
+		n.Props = append(n.Props, prop)
+	}
+}
+
+func (p *parser) parseValue() (string, error) {
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case '\\':
+			if p.pos < len(p.src) {
+				sb.WriteByte(p.src[p.pos])
+				p.pos++
+			}
+		case ']':
+			return sb.String(), nil
 		default:
-			if char > ' ' { 
-				propKey += string(char)
+			sb.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("sgf: unterminated property value")
+}
+
+// Write serializes the tree rooted at root as an SGF collection.
+func Write(root *Node) string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	writeSequence(&sb, root)
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func writeSequence(sb *strings.Builder, n *Node) {
+	for {
+		sb.WriteString(";")
+		for _, prop := range n.Props {
+			sb.WriteString(prop.ID)
+			for _, v := range prop.Values {
+				sb.WriteString("[")
+				sb.WriteString(escape(v))
+				sb.WriteString("]")
 			}
 		}
+		if len(n.Children) != 1 {
+			break
+		}
+		n = n.Children[0]
 	}
+	if len(n.Children) > 1 {
+		for _, child := range n.Children {
+			sb.WriteString("\n(")
+			writeSequence(sb, child)
+			sb.WriteString(")")
+		}
+	}
+}
 
-	return size, moves, nil
+func escape(v string) string {
+	v = strings.ReplaceAll(v, "\\", "\\\\")
+	return strings.ReplaceAll(v, "]", "\\]")
 }
@@ -0,0 +1,58 @@
+package sgf
+
+import "testing"
+
+func TestParseEscapesAndVariations(t *testing.T) {
+	root, err := Parse(`(;SZ[9]C[a \] b];B[aa](;W[bb])(;W[cc]))`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c, _ := root.Get("C"); c != "a ] b" {
+		t.Fatalf("expected escaped comment, got %q", c)
+	}
+	move := root.Children[0]
+	if len(move.Children) != 2 {
+		t.Fatalf("expected 2 variations, got %d", len(move.Children))
+	}
+
+	if out := Write(root); out != `(;SZ[9]C[a \] b];B[aa]
+(;W[bb])
+(;W[cc]))` {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestParsePointsExpandsRanges(t *testing.T) {
+	points, err := ParsePoints([]string{"aa:bb", "dd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(points) != 5 {
+		t.Fatalf("expected 5 points, got %v", points)
+	}
+}
+
+func TestCoords(t *testing.T) {
+	for _, tc := range []struct {
+		coord string
+		x, y  int
+	}{
+		{"aa", 0, 0},
+		{"pd", 15, 3},
+		{"zA", 25, 26},
+		{"ZZ", 51, 51},
+	} {
+		x, y, err := FromSGFCoord(tc.coord)
+		if err != nil || x != tc.x || y != tc.y {
+			t.Errorf("FromSGFCoord(%q) = %d, %d, %v", tc.coord, x, y, err)
+		}
+		if got := ToSGFCoord(tc.x, tc.y); got != tc.coord {
+			t.Errorf("ToSGFCoord(%d, %d) = %q, expected %q", tc.x, tc.y, got, tc.coord)
+		}
+	}
+	for _, bad := range []string{"a", "a1", "[a", "é"} {
+		if _, _, err := FromSGFCoord(bad); err == nil {
+			t.Errorf("expected FromSGFCoord(%q) to fail", bad)
+		}
+	}
+}
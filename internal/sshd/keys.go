@@ -0,0 +1,67 @@
+package sshd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// LoadHostKey reads the server's private key from path, generating an
+// ed25519 key there on the first run.
+func LoadHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "vimgo host key")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+		log.Printf("generated host key %s", path)
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// Authorize lets in the users whose public key is in the authorized_keys
+// file at path. The file is read again for every attempt, so that keys
+// added or removed take effect without a restart. It is meant for
+// ssh.ServerConfig's PublicKeyCallback.
+func Authorize(path string) func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+	return func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("authorized keys: %v", err)
+			return nil, err
+		}
+		for len(data) > 0 {
+			authorized, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+			if err != nil {
+				// No more keys; what is left is comments and blank lines.
+				break
+			}
+			if bytes.Equal(authorized.Marshal(), key.Marshal()) {
+				return &ssh.Permissions{
+					Extensions: map[string]string{"fingerprint": ssh.FingerprintSHA256(key)},
+				}, nil
+			}
+			data = rest
+		}
+		return nil, fmt.Errorf("unknown public key for %s", conn.User())
+	}
+}
@@ -0,0 +1,78 @@
+package sshd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLoadHostKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "host_key")
+	first, err := LoadHostKey(path)
+	if err != nil {
+		t.Fatalf("generating the host key failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected the key saved with mode 0600, got %v", err)
+	}
+	again, err := LoadHostKey(path)
+	if err != nil {
+		t.Fatalf("loading the host key failed: %v", err)
+	}
+	if !bytes.Equal(first.PublicKey().Marshal(), again.PublicKey().Marshal()) {
+		t.Fatalf("expected the saved key to be loaded again")
+	}
+}
+
+// user is the metadata of a connection by a user, which is all Authorize
+// looks at.
+type user struct {
+	ssh.ConnMetadata
+	name string
+}
+
+func (u user) User() string { return u.name }
+
+func TestAuthorize(t *testing.T) {
+	newKey := func() ssh.PublicKey {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ssh.NewPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	alice, mallory := newKey(), newKey()
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	if err := os.WriteFile(path, append([]byte("# players\n\n"), ssh.MarshalAuthorizedKey(alice)...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := Authorize(path)
+	perms, err := check(user{name: "alice"}, alice)
+	if err != nil || perms.Extensions["fingerprint"] != ssh.FingerprintSHA256(alice) {
+		t.Fatalf("expected alice let in, got %v", err)
+	}
+	if _, err := check(user{name: "mallory"}, mallory); err == nil {
+		t.Fatalf("expected mallory kept out")
+	}
+
+	// The file is read again for every attempt.
+	if err := os.WriteFile(path, ssh.MarshalAuthorizedKey(mallory), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := check(user{name: "mallory"}, mallory); err != nil {
+		t.Fatalf("expected mallory let in once added, got %v", err)
+	}
+	if _, err := check(user{name: "alice"}, alice); err == nil {
+		t.Fatalf("expected alice kept out once removed")
+	}
+}
@@ -0,0 +1,333 @@
+// Package sshd serves VimGo over ssh. Each session runs a terminal.Model
+// of its own, for a game of the player's own or in a room of a room.Hub,
+// which may be shared with the web server's players.
+package sshd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/vimgo/vimgo/internal/asciicast"
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/room"
+	"github.com/vimgo/vimgo/internal/ui/terminal"
+	"golang.org/x/crypto/ssh"
+)
+
+// Usage lists the commands a client may run, as ssh's remote command.
+const Usage = `  ssh -t -p 2222 host new [9|13|19]              to open a room and sit in it
+  ssh -t -p 2222 host join <room> [black|white]  to sit in a room
+  ssh -t -p 2222 host watch <room>               to watch a room
+`
+
+// Server runs VimGo for the clients of ssh listeners. Rooms are opened
+// in and joined from Hub. Size is the board size of solo games and of
+// rooms opened without one. Sessions are recorded in RecordDir, if set.
+type Server struct {
+	Hub       *room.Hub
+	Size      int
+	RecordDir string
+}
+
+// Serve accepts connections on ln and serves them with config until ln is
+// closed.
+func (srv *Server) Serve(ln net.Listener, config *ssh.ServerConfig) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.serve(conn, config)
+	}
+}
+
+// serve carries out the ssh handshake on conn and serves its session
+// channels; other channels are refused.
+func (srv *Server) serve(conn net.Conn, config *ssh.ServerConfig) {
+	sc, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		log.Printf("ssh handshake with %s: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	log.Printf("%s@%s connected (%s)", sc.User(), sc.RemoteAddr(), sc.Permissions.Extensions["fingerprint"])
+	defer log.Printf("%s@%s disconnected", sc.User(), sc.RemoteAddr())
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			_ = nc.Reject(ssh.UnknownChannelType, "only sessions are served")
+			continue
+		}
+		ch, reqs, err := nc.Accept()
+		if err != nil {
+			log.Printf("ssh session of %s: %v", sc.User(), err)
+			continue
+		}
+		go srv.session(ch, reqs, sc.User())
+	}
+}
+
+// ptyRequest is the payload of a "pty-req" request, RFC 4254 6.2.
+type ptyRequest struct {
+	Term          string
+	Columns, Rows uint32
+	Width, Height uint32
+	Modes         string
+}
+
+// windowChange is the payload of a "window-change" request, RFC 4254 6.7:
+// the resize a browser sends over the websocket.
+type windowChange struct {
+	Columns, Rows uint32
+	Width, Height uint32
+}
+
+// session serves one ssh session: it waits for the shell or command the
+// client asks for and runs it in the terminal the client asked for
+// before, passing on the changes of its size.
+func (srv *Server) session(ch ssh.Channel, reqs <-chan *ssh.Request, user string) {
+	var (
+		term string
+		size tea.WindowSizeMsg
+		p    *tea.Program
+		cast *asciicast.Recorder
+	)
+	for req := range reqs {
+		ok := false
+		switch req.Type {
+		case "pty-req":
+			var pr ptyRequest
+			if ssh.Unmarshal(req.Payload, &pr) == nil {
+				term, ok = pr.Term, true
+				size = tea.WindowSizeMsg{Width: int(pr.Columns), Height: int(pr.Rows)}
+			}
+		case "window-change":
+			var wc windowChange
+			if ssh.Unmarshal(req.Payload, &wc) == nil {
+				ok = true
+				size = tea.WindowSizeMsg{Width: int(wc.Columns), Height: int(wc.Rows)}
+				if p != nil {
+					p.Send(size)
+				}
+				if cast != nil {
+					_ = cast.Resize(size.Width, size.Height)
+				}
+			}
+		case "shell", "exec":
+			var args []string
+			if req.Type == "exec" {
+				var cmd struct{ Command string }
+				if ssh.Unmarshal(req.Payload, &cmd) != nil {
+					break
+				}
+				args = strings.Fields(cmd.Command)
+			}
+			if p != nil {
+				break
+			}
+			ok = true
+			_ = req.Reply(ok, nil)
+			if term == "" {
+				exit(ch, 1, "VimGo needs a terminal: connect with ssh -t\n")
+				continue
+			}
+			m, member, err := srv.open(args, user)
+			if err != nil {
+				exit(ch, 1, err.Error()+"\n")
+				continue
+			}
+			title := "VimGo"
+			if member != nil {
+				title += " room " + member.Room().ID
+			}
+			var replay string
+			cast, replay = srv.record(title, term, size)
+			p = run(ch, m, member, term, size, cast, replay)
+			continue
+		}
+		_ = req.Reply(ok, nil)
+	}
+	// The client went away.
+	if p != nil {
+		p.Quit()
+	}
+}
+
+// open sets up VimGo for the command args run by user: a game of their
+// own without a command, or one bound to the room of member. Either way
+// the server's files are out of reach.
+func (srv *Server) open(args []string, user string) (terminal.Model, *room.Member, error) {
+	if len(args) == 0 {
+		m := terminal.NewModel(srv.Size)
+		m.NoFiles = true
+		return m, nil, nil
+	}
+	switch cmd, args := args[0], args[1:]; {
+	case cmd == "new" && len(args) <= 1:
+		size := srv.Size
+		if len(args) == 1 {
+			if _, err := fmt.Sscan(args[0], &size); err != nil || (size != 9 && size != 13 && size != 19) {
+				return terminal.Model{}, nil, fmt.Errorf("invalid board size %q", args[0])
+			}
+		}
+		rm := srv.Hub.Create(room.Settings{Size: size})
+		log.Printf("%s opened room %s", user, rm.ID)
+		return srv.join(rm.Join(user, board.Black))
+	case cmd == "join" && (len(args) == 1 || len(args) == 2):
+		rm, ok := srv.Hub.Get(args[0])
+		if !ok {
+			return terminal.Model{}, nil, fmt.Errorf("no room %q", args[0])
+		}
+		seat := board.Empty
+		if len(args) == 2 {
+			switch args[1] {
+			case "black", "b":
+				seat = board.Black
+			case "white", "w":
+				seat = board.White
+			default:
+				return terminal.Model{}, nil, fmt.Errorf("no seat %q: black or white", args[1])
+			}
+		}
+		return srv.join(rm.Join(user, seat))
+	case cmd == "watch" && len(args) == 1:
+		rm, ok := srv.Hub.Get(args[0])
+		if !ok {
+			return terminal.Model{}, nil, fmt.Errorf("no room %q", args[0])
+		}
+		return srv.join(rm.Watch(user), nil)
+	}
+	return terminal.Model{}, nil, fmt.Errorf("usage:\n%s", strings.TrimSuffix(Usage, "\n"))
+}
+
+// join binds VimGo to the room of member, once it joined.
+func (srv *Server) join(member *room.Member, err error) (terminal.Model, *room.Member, error) {
+	if err != nil {
+		return terminal.Model{}, nil, err
+	}
+	rm := member.Room()
+	m := terminal.NewModel(rm.Size())
+	m.NoFiles = true
+	if err := m.OpenRemote(member, roomTitle(member), rm.SGF()); err != nil {
+		member.Leave()
+		return terminal.Model{}, nil, err
+	}
+	return m, member, nil
+}
+
+// record starts the recording of a session titled title, in a terminal
+// of type term and size, returning it with its ID; it returns nil when
+// the server does not record.
+func (srv *Server) record(title, term string, size tea.WindowSizeMsg) (*asciicast.Recorder, string) {
+	if srv.RecordDir == "" {
+		return nil, ""
+	}
+	cast, id, err := asciicast.Create(srv.RecordDir, asciicast.Header{
+		Width:  size.Width,
+		Height: size.Height,
+		Title:  title,
+		Env:    map[string]string{"TERM": term},
+	})
+	if err != nil {
+		log.Printf("recording %s: %v", title, err)
+		return nil, ""
+	}
+	log.Printf("recording %s as %s", title, id)
+	return cast, id
+}
+
+// recorded is the output of a recorded session, written to both the
+// client and the recording.
+type recorded struct {
+	ssh.Channel
+	cast *asciicast.Recorder
+}
+
+func (r recorded) Write(p []byte) (int, error) {
+	_ = r.cast.Output(p)
+	return r.Channel.Write(p)
+}
+
+// run starts the program for m on ch, in a terminal of type term and
+// size, and ends the session when it ended. A member's room events are
+// passed on to the program, and the member leaves with it. The output
+// goes to cast as well, if not nil, which is closed at the end.
+func run(ch ssh.Channel, m terminal.Model, member *room.Member, term string, size tea.WindowSizeMsg, cast *asciicast.Recorder, replay string) *tea.Program {
+	var out io.Writer = ch
+	if cast != nil {
+		out = recorded{Channel: ch, cast: cast}
+	}
+	// Yanks to the "+ register reach the client's clipboard.
+	m.Clipboard = out
+	p := tea.NewProgram(m,
+		tea.WithInput(ch),
+		tea.WithOutput(out),
+		tea.WithEnvironment([]string{"TERM=" + term}),
+		tea.WithoutSignalHandler(),
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+	if member != nil {
+		go func() {
+			for ev := range member.Events() {
+				switch ev.Kind {
+				case room.GameChanged:
+					p.Send(terminal.RemoteUpdate{Remote: member, SGF: ev.SGF})
+				case room.Chat:
+					p.Send(terminal.RemoteChat{Remote: member, From: room.Speaker(ev.From, ev.Color), Text: ev.Text})
+				case room.Left:
+					p.Send(terminal.RemoteChat{Remote: member, Text: ev.Notice()})
+				}
+			}
+			// The room dropped us.
+			p.Quit()
+		}()
+	}
+	go func() {
+		// The output is no terminal of the server's, so the program
+		// does not learn its size by itself.
+		if size.Width > 0 && size.Height > 0 {
+			p.Send(size)
+		}
+	}()
+	go func() {
+		status := 0
+		if _, err := p.Run(); err != nil {
+			log.Printf("vimgo: %v", err)
+			status = 1
+		}
+		if member != nil {
+			member.Leave()
+		}
+		msg := ""
+		if cast != nil {
+			cast.Close()
+			msg = fmt.Sprintf("recorded as /replay/%s\n", replay)
+		}
+		exit(ch, status, msg)
+	}()
+	return p
+}
+
+// exit ends the session with status, after writing msg to the client's
+// stderr.
+func exit(ch ssh.Channel, status int, msg string) {
+	if msg != "" {
+		_, _ = io.WriteString(ch.Stderr(), strings.ReplaceAll(msg, "\n", "\r\n"))
+	}
+	_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+	ch.Close()
+}
+
+// roomTitle names the room's buffer after the room and the member's seat.
+func roomTitle(m *room.Member) string {
+	seat := "watching"
+	if m.Color != board.Empty {
+		seat = strings.ToLower(m.Color.String())
+	}
+	return fmt.Sprintf("room %s (%s)", m.Room().ID, seat)
+}
@@ -0,0 +1,327 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// Buffer is an open game with its file name. Each buffer keeps its own
+// game tree and modified flag, and remembers the cursor while hidden.
+type Buffer struct {
+	Number   int
+	Game     *game.Game
+	Filename string
+	CursorX  int
+	CursorY  int
+	// Remote is the game with others the buffer shows, called title; see
+	// OpenRemote.
+	Remote Remote
+	title  string
+	// chat is the latest of the remote's chat.
+	chat []string
+}
+
+// Name returns the file name for display.
+func (b *Buffer) Name() string {
+	if b.Filename == "" && b.title != "" {
+		return b.title
+	}
+	if b.Filename == "" {
+		return "[No Name]"
+	}
+	return b.Filename
+}
+
+var errNoWrite = fmt.Errorf("E37: No write since last change (add ! to override)")
+
+// defaultFilename is used by :w and :e before the game has a file name.
+const defaultFilename = "game.sgf"
+
+func (m *Model) addBuffer(g *game.Game, filename string) *Buffer {
+	m.lastBufNum++
+	b := &Buffer{
+		Number:   m.lastBufNum,
+		Game:     g,
+		Filename: filename,
+		CursorX:  g.Board.Size / 2,
+		CursorY:  g.Board.Size / 2,
+	}
+	m.Buffers = append(m.Buffers, b)
+	return b
+}
+
+// switchTo makes b the current buffer, saving the cursor of the one left
+// behind, which becomes the alternate buffer.
+func (m *Model) switchTo(b *Buffer) {
+	if b == m.buf {
+		return
+	}
+	if m.buf != nil {
+		m.buf.CursorX, m.buf.CursorY = m.Handler.CursorX, m.Handler.CursorY
+		m.alt = m.buf
+	}
+	m.buf = b
+	m.Game = b.Game
+	m.Handler.BoardSize = b.Game.Board.Size
+	m.Handler.CursorX, m.Handler.CursorY = b.CursorX, b.CursorY
+	m.leaveScoring()
+}
+
+// OpenFiles opens each file in its own buffer and shows the first one. It
+// is used for file names given on the command line.
+func (m *Model) OpenFiles(filenames []string) error {
+	var first *Buffer
+	for _, name := range filenames {
+		if err := m.edit([]string{name}, false); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if first == nil {
+			first = m.buf
+		}
+	}
+	if first != nil {
+		m.switchTo(first)
+	}
+	return nil
+}
+
+func (m Model) saveSGF(filename string) error {
+	return os.WriteFile(filename, []byte(m.Game.SGF()), 0644)
+}
+
+func loadSGF(filename string) (*game.Game, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return game.Load(string(content))
+}
+
+// write implements :w. Writing to the buffer's own file (or giving a
+// nameless buffer its first name) clears the modified flag; writing
+// elsewhere saves a copy. Only the buffer's own file is overwritten
+// without bang: any other existing file, including the one a nameless
+// buffer would take, is refused.
+func (m *Model) write(args []string, bang bool) error {
+	filename := m.buf.Filename
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	if filename == "" {
+		filename = defaultFilename
+	}
+	own := m.buf.Filename == "" || filename == m.buf.Filename
+	if filename != m.buf.Filename && !bang {
+		if _, err := os.Stat(filename); err == nil {
+			return fmt.Errorf("E13: File exists (add ! to override)")
+		}
+	}
+
+	if err := m.saveSGF(filename); err != nil {
+		return err
+	}
+	if own {
+		m.buf.Filename = filename
+		m.Game.MarkSaved()
+	}
+	m.Message = fmt.Sprintf("%q written", filename)
+	return nil
+}
+
+// writeAll writes every modified buffer that has a file name.
+func (m *Model) writeAll() error {
+	// Point the model at each buffer in turn without going through
+	// switchTo, so the cursor and alternate buffer are left alone.
+	current := m.buf
+	defer func() { m.buf, m.Game = current, current.Game }()
+	for _, b := range m.Buffers {
+		if !b.Game.Modified() || (b.Remote != nil && b.Filename == "") {
+			continue
+		}
+		if b.Filename == "" {
+			return fmt.Errorf("E141: No file name for buffer %d", b.Number)
+		}
+		m.buf, m.Game = b, b.Game
+		if err := m.write(nil, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkModified returns an error naming a buffer with unsaved changes, the
+// current one first. Remote games are kept by the remote.
+func (m *Model) checkModified() error {
+	if m.Game.Modified() && m.buf.Remote == nil {
+		return errNoWrite
+	}
+	for _, b := range m.Buffers {
+		if b.Game.Modified() && b.Remote == nil {
+			return fmt.Errorf("E162: No write since last change for buffer %q", b.Name())
+		}
+	}
+	return nil
+}
+
+// edit implements :e. A file that is already open switches to its buffer;
+// :e# switches to the alternate buffer. Without a file name the current
+// file is reloaded, which discards unsaved changes only with bang.
+func (m *Model) edit(args []string, bang bool) error {
+	filename := m.buf.Filename
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	if filename == "#" {
+		if m.alt == nil {
+			return fmt.Errorf("E23: No alternate file")
+		}
+		m.switchTo(m.alt)
+		return nil
+	}
+	if filename == "" {
+		filename = defaultFilename
+	}
+
+	reload := m.buf.Remote == nil && (filename == m.buf.Filename || (m.buf.Filename == "" && len(args) == 0))
+	if !reload {
+		for _, b := range m.Buffers {
+			if b.Filename == filename {
+				m.switchTo(b)
+				return nil
+			}
+		}
+	}
+	if reload && m.Game.Modified() && !bang {
+		return errNoWrite
+	}
+
+	g, err := loadSGF(filename)
+	if err != nil {
+		return err
+	}
+
+	// Reloading, or replacing the untouched buffer of a fresh start, keeps
+	// the buffer; anything else opens a new one.
+	if reload || (m.buf.Filename == "" && m.buf.Remote == nil && !m.Game.Modified()) {
+		m.buf.Game, m.buf.Filename = g, filename
+		m.Game = g
+		m.Handler.BoardSize = g.Board.Size
+		m.Handler.CursorX, m.Handler.CursorY = g.Board.Size/2, g.Board.Size/2
+		m.leaveScoring()
+		return nil
+	}
+	m.switchTo(m.addBuffer(g, filename))
+	return nil
+}
+
+// findBuffer resolves a buffer argument: a number, "%" for the current
+// buffer, "#" for the alternate one, or part of a file name.
+func (m *Model) findBuffer(arg string) (*Buffer, error) {
+	switch arg {
+	case "%":
+		return m.buf, nil
+	case "#":
+		if m.alt == nil {
+			return nil, fmt.Errorf("E23: No alternate file")
+		}
+		return m.alt, nil
+	}
+	if n, err := strconv.Atoi(arg); err == nil {
+		for _, b := range m.Buffers {
+			if b.Number == n {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("E86: Buffer %d does not exist", n)
+	}
+
+	var found *Buffer
+	for _, b := range m.Buffers {
+		if strings.Contains(b.Filename, arg) {
+			if found != nil {
+				return nil, fmt.Errorf("E93: More than one match for %s", arg)
+			}
+			found = b
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("E94: No matching buffer for %s", arg)
+	}
+	return found, nil
+}
+
+// cycleBuffer moves dir buffers forward or backward in the list, wrapping
+// around like :bnext and :bprevious.
+func (m *Model) cycleBuffer(dir int) {
+	for i, b := range m.Buffers {
+		if b == m.buf {
+			n := len(m.Buffers)
+			m.switchTo(m.Buffers[((i+dir)%n+n)%n])
+			return
+		}
+	}
+}
+
+// deleteBuffer closes b. Closing the last buffer leaves an empty one.
+func (m *Model) deleteBuffer(b *Buffer, bang bool) error {
+	if b.Game.Modified() && !bang {
+		return fmt.Errorf("E89: No write since last change for buffer %d (add ! to override)", b.Number)
+	}
+
+	idx := 0
+	for i, other := range m.Buffers {
+		if other == b {
+			idx = i
+		}
+	}
+	m.Buffers = append(m.Buffers[:idx], m.Buffers[idx+1:]...)
+	if m.alt == b {
+		m.alt = nil
+	}
+	if b != m.buf {
+		m.replaceBuffer(b)
+		return nil
+	}
+
+	if len(m.Buffers) == 0 {
+		m.addBuffer(game.NewGame(b.Game.Board.Size), "")
+	}
+	next := m.alt
+	if next == nil {
+		next = m.Buffers[min(idx, len(m.Buffers)-1)]
+	}
+	m.buf = nil
+	m.switchTo(next)
+	m.alt = nil
+	m.replaceBuffer(b)
+	return nil
+}
+
+// listBuffers formats the buffer list for :ls, marking the current buffer
+// with % and the alternate one with #.
+func (m *Model) listBuffers() string {
+	var lines []string
+	for _, b := range m.Buffers {
+		flag := " "
+		switch b {
+		case m.buf:
+			flag = "%a"
+		case m.alt:
+			flag = "# "
+		}
+		if len(flag) == 1 {
+			flag += " "
+		}
+		modified := " "
+		if b.Game.Modified() {
+			modified = "+"
+		}
+		lines = append(lines, fmt.Sprintf("%3d %s %s %-20q move %d",
+			b.Number, flag, modified, b.Name(), b.Game.Current.MoveNumber()))
+	}
+	return strings.Join(lines, "\n")
+}
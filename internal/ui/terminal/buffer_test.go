@@ -0,0 +1,111 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// writeGame writes a 9x9 game with the given number of moves to a file in
+// dir.
+func writeGame(t *testing.T, dir, name string, moves int) string {
+	t.Helper()
+	g := game.NewGame(9)
+	for i := 0; i < moves; i++ {
+		g.Move(i, i)
+	}
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, []byte(g.SGF()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestBuffers(t *testing.T) {
+	dir := t.TempDir()
+	a := writeGame(t, dir, "a.sgf", 1)
+	b := writeGame(t, dir, "b.sgf", 2)
+
+	m := NewModel(9)
+	if err := m.edit([]string{"#"}, false); err == nil || !strings.HasPrefix(err.Error(), "E23") {
+		t.Fatalf("expected E23 without an alternate buffer, got %v", err)
+	}
+	// The untouched buffer of a fresh start is taken over.
+	command(t, &m, "e "+a)
+	if len(m.Buffers) != 1 || m.buf.Filename != a {
+		t.Fatalf("expected a.sgf in the first buffer")
+	}
+	m = press(t, m, "l")
+	command(t, &m, "e "+b)
+	if len(m.Buffers) != 2 || m.buf.Number != 2 {
+		t.Fatalf("expected b.sgf in a second buffer")
+	}
+
+	// :e# goes back, to the cursor left there.
+	command(t, &m, "e #")
+	if m.buf.Filename != a || m.Handler.CursorX != 5 {
+		t.Fatalf("expected a.sgf back with its cursor, got %s at x=%d", m.buf.Filename, m.Handler.CursorX)
+	}
+	command(t, &m, "ls")
+	lines := strings.Split(m.Message, "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "1 %a") || !strings.Contains(lines[1], "2 #") {
+		t.Fatalf("unexpected :ls\n%s", m.Message)
+	}
+
+	command(t, &m, "bnext")
+	command(t, &m, "bnext")
+	if m.buf.Number != 1 {
+		t.Fatalf("expected :bnext to wrap around")
+	}
+	command(t, &m, "bprevious")
+	if m.buf.Number != 2 {
+		t.Fatalf("expected :bprevious to wrap around")
+	}
+	command(t, &m, "buffer a.sgf")
+	if m.buf.Number != 1 {
+		t.Fatalf("expected :b to find a buffer by name")
+	}
+	for arg, code := range map[string]string{"7": "E86", "nothing": "E94", ".sgf": "E93"} {
+		if m.handleCommand("buffer " + arg); m.Error == nil || !strings.HasPrefix(m.Error.Error(), code) {
+			t.Errorf(":b %s: expected %s, got %v", arg, code, m.Error)
+		}
+	}
+}
+
+func TestModifiedBuffers(t *testing.T) {
+	dir := t.TempDir()
+	a := writeGame(t, dir, "a.sgf", 0)
+	b := writeGame(t, dir, "b.sgf", 0)
+
+	m := NewModel(9)
+	command(t, &m, "e "+a)
+	m = press(t, m, "x")
+	command(t, &m, "e "+b)
+
+	// Quitting names the buffer with changes; deleting it needs !.
+	if m.handleCommand("quit"); m.Error == nil || !strings.HasPrefix(m.Error.Error(), "E162") {
+		t.Fatalf("expected E162 for the hidden buffer, got %v", m.Error)
+	}
+	if m.handleCommand("bdelete 1"); m.Error == nil || !strings.HasPrefix(m.Error.Error(), "E89") {
+		t.Fatalf("expected E89 for the modified buffer, got %v", m.Error)
+	}
+	if m.handleCommand("quit!") == nil {
+		t.Fatalf("expected :q! to quit")
+	}
+
+	command(t, &m, "wall")
+	if m.Buffers[0].Game.Modified() || m.buf.Filename != b {
+		t.Fatalf("expected :wall to save buffer 1 and stay in buffer 2")
+	}
+	command(t, &m, "bdelete 1")
+	if len(m.Buffers) != 1 || m.alt != nil {
+		t.Fatalf("expected buffer 1 gone")
+	}
+	command(t, &m, "bdelete")
+	if len(m.Buffers) != 1 || m.buf.Filename != "" {
+		t.Fatalf("expected an empty buffer after closing the last one")
+	}
+}
@@ -0,0 +1,370 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/diagram"
+	"github.com/vimgo/vimgo/internal/ex"
+	"github.com/vimgo/vimgo/internal/game"
+	"github.com/vimgo/vimgo/internal/rules"
+	"github.com/vimgo/vimgo/internal/vim"
+)
+
+// commands lists the ex commands. Order matters for abbreviations: the
+// first command matching a prefix wins.
+var commands = ex.NewRegistry(
+	ex.Spec{Name: "q[uit]", Bang: true},
+	ex.Spec{Name: "qa[ll]", Bang: true},
+	ex.Spec{Name: "e[dit]", Bang: true, Complete: ex.CompleteFile},
+	ex.Spec{Name: "ls"},
+	ex.Spec{Name: "buffers"},
+	ex.Spec{Name: "files"},
+	ex.Spec{Name: "b[uffer]"},
+	ex.Spec{Name: "bn[ext]"},
+	ex.Spec{Name: "bp[revious]"},
+	ex.Spec{Name: "bN[ext]"},
+	ex.Spec{Name: "bd[elete]", Bang: true},
+	ex.Spec{Name: "sp[lit]", Complete: ex.CompleteFile},
+	ex.Spec{Name: "vs[plit]", Complete: ex.CompleteFile},
+	ex.Spec{Name: "clo[se]"},
+	ex.Spec{Name: "on[ly]"},
+	ex.Spec{Name: "winc[md]"},
+	ex.Spec{Name: "w[rite]", Bang: true, Complete: ex.CompleteFile},
+	ex.Spec{Name: "wq", Bang: true, Complete: ex.CompleteFile},
+	ex.Spec{Name: "wa[ll]"},
+	ex.Spec{Name: "wqa[ll]"},
+	ex.Spec{Name: "x[it]", Bang: true, Complete: ex.CompleteFile},
+	ex.Spec{Name: "xa[ll]"},
+	ex.Spec{Name: "y[ank]", Range: true},
+	ex.Spec{Name: "reg[isters]"},
+	ex.Spec{Name: "di[splay]"},
+	ex.Spec{Name: "u[ndo]"},
+	ex.Spec{Name: "pa[ss]"},
+	ex.Spec{Name: "d[elete]", Bang: true, Range: true},
+	ex.Spec{Name: "c[oordinates]"},
+	ex.Spec{Name: "coords"},
+	ex.Spec{Name: "cou[nt]", Range: true},
+	ex.Spec{Name: "exp[ort]", Range: true, Complete: ex.CompleteFile},
+	ex.Spec{Name: "sc[ore]"},
+	ex.Spec{Name: "sa[y]"},
+	ex.Spec{Name: "se[t]", Complete: ex.CompleteOption},
+	ex.Spec{Name: "colo[rscheme]"},
+	ex.Spec{Name: "noh[lsearch]"},
+	ex.Spec{Name: "so[urce]", Complete: ex.CompleteFile},
+	ex.Spec{Name: "nm[ap]"},
+	ex.Spec{Name: "nn[oremap]"},
+	ex.Spec{Name: "nun[map]"},
+	ex.Spec{Name: "cm[ap]"},
+	ex.Spec{Name: "cno[remap]"},
+	ex.Spec{Name: "cu[nmap]"},
+	ex.Spec{Name: "h[elp]", Complete: ex.CompleteCommand},
+	ex.Spec{Name: "?"},
+)
+
+// filelessCommands are the commands completed with NoFiles.
+var filelessCommands = commands.WithoutFiles()
+
+var errNoFiles = fmt.Errorf("E145: Files cannot be read or written in this session")
+
+func newHandler(size int) *vim.Handler {
+	h := vim.NewHandler(size)
+	h.Complete = func(line string) (int, []string) {
+		return commands.Complete(line, optionNames())
+	}
+	return h
+}
+
+// handleCommand runs a command line. Commands chained with | run in order
+// until one fails or quits.
+func (m *Model) handleCommand(line string) tea.Cmd {
+	m.Error = nil
+	m.Message = ""
+
+	cmds, err := ex.Parse(line)
+	if err != nil {
+		m.Error = err
+		return nil
+	}
+	for _, cmd := range cmds {
+		teaCmd, err := m.execute(cmd)
+		if err != nil {
+			m.Error = err
+			return nil
+		}
+		if teaCmd != nil {
+			return teaCmd
+		}
+	}
+	return nil
+}
+
+func (m *Model) execute(cmd ex.Command) (tea.Cmd, error) {
+	// A range on its own jumps to that move, like :42 goes to line 42.
+	if cmd.Name == "" {
+		if cmd.Range == nil || cmd.Range.Visual {
+			return nil, nil
+		}
+		_, end, err := cmd.Range.Resolve(m.Game.Current.MoveNumber(), m.Game.LastMoveNumber())
+		if err != nil {
+			return nil, err
+		}
+		m.leaveScoring()
+		return nil, m.Game.GoToMove(end)
+	}
+
+	if _, err := commands.Check(&cmd); err != nil {
+		return nil, err
+	}
+	args := cmd.Args()
+	if m.NoFiles && usesFiles(cmd.Name, args) {
+		return nil, errNoFiles
+	}
+
+	switch cmd.Name {
+	case "quit", "qall":
+		if m.ShowHelp {
+			m.ShowHelp = false
+			return nil, nil
+		}
+		// With several windows :q closes the current one.
+		if cmd.Name == "quit" && len(m.layout.windows()) > 1 {
+			return nil, m.closeWindow(m.win)
+		}
+		if !cmd.Bang {
+			if err := m.checkModified(); err != nil {
+				return nil, err
+			}
+		}
+		return tea.Quit, nil
+	case "edit":
+		return nil, m.edit(args, cmd.Bang)
+	case "write":
+		return nil, m.write(args, cmd.Bang)
+	case "wall":
+		return nil, m.writeAll()
+	case "wq":
+		if err := m.write(args, cmd.Bang); err != nil {
+			return nil, err
+		}
+		if len(m.layout.windows()) > 1 {
+			return nil, m.closeWindow(m.win)
+		}
+		if err := m.checkModified(); err != nil {
+			return nil, err
+		}
+		return tea.Quit, nil
+	case "xit":
+		// Like :wq, but only writes when there are changes.
+		if m.Game.Modified() || len(args) > 0 {
+			if err := m.write(args, cmd.Bang); err != nil {
+				return nil, err
+			}
+		}
+		if len(m.layout.windows()) > 1 {
+			return nil, m.closeWindow(m.win)
+		}
+		if err := m.checkModified(); err != nil {
+			return nil, err
+		}
+		return tea.Quit, nil
+	case "wqall", "xall":
+		if err := m.writeAll(); err != nil {
+			return nil, err
+		}
+		return tea.Quit, nil
+	case "ls", "buffers", "files":
+		m.Message = m.listBuffers()
+	case "buffer":
+		if len(args) == 0 {
+			break
+		}
+		b, err := m.findBuffer(args[0])
+		if err != nil {
+			return nil, err
+		}
+		m.switchTo(b)
+	case "bnext":
+		m.cycleBuffer(1)
+	case "bprevious", "bNext":
+		m.cycleBuffer(-1)
+	case "bdelete":
+		b := m.buf
+		if len(args) > 0 {
+			var err error
+			if b, err = m.findBuffer(args[0]); err != nil {
+				return nil, err
+			}
+		}
+		return nil, m.deleteBuffer(b, cmd.Bang)
+	case "split", "vsplit":
+		return nil, m.splitWindow(cmd.Name == "vsplit", args)
+	case "close":
+		return nil, m.closeWindow(m.win)
+	case "only":
+		m.onlyWindow()
+	case "wincmd":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("E471: Argument required")
+		}
+		return nil, m.wincmd(args[0])
+	case "yank":
+		return nil, m.yank(cmd.Range, args)
+	case "registers", "display":
+		m.Message = m.listRegisters(strings.Join(args, ""))
+	case "undo":
+		m.leaveScoring()
+		return nil, m.Game.Undo()
+	case "pass":
+		m.leaveScoring()
+		return nil, m.play(nil)
+	case "say":
+		return nil, m.say(cmd.Arg)
+	case "delete":
+		return nil, m.deleteRange(cmd.Range, cmd.Bang)
+	case "coordinates", "coords":
+		m.ShowCoords = !m.ShowCoords
+	case "help", "?":
+		m.ShowHelp = true
+	case "score":
+		method := m.Rules
+		if len(args) > 0 {
+			method = args[0]
+		}
+		if method == "off" {
+			m.leaveScoring()
+			break
+		}
+		m.enterScoring(method)
+	case "count":
+		region, err := m.visualRegion(cmd.Range)
+		if err != nil {
+			return nil, err
+		}
+		if region == nil {
+			m.Message = fmt.Sprintf("captures: B %d W %d", m.Game.BlackCaptures, m.Game.WhiteCaptures)
+			break
+		}
+		stats := rules.CountRegion(m.Game.Board, *region)
+		m.Message = fmt.Sprintf("B %d stones %d terr %d libs | W %d stones %d terr %d libs | dame %d",
+			stats.BlackStones, stats.BlackTerritory, stats.BlackLiberties,
+			stats.WhiteStones, stats.WhiteTerritory, stats.WhiteLiberties, stats.Dame)
+	case "export":
+		region, err := m.visualRegion(cmd.Range)
+		if err != nil {
+			return nil, err
+		}
+		filename := "diagram.txt"
+		if len(args) > 0 {
+			filename = args[0]
+		}
+		r := board.NewRect(board.Point{}, board.Point{X: m.Game.Board.Size - 1, Y: m.Game.Board.Size - 1})
+		if region != nil {
+			r = *region
+		}
+		if err := os.WriteFile(filename, []byte(diagram.ASCII(m.Game.Board, r)), 0644); err != nil {
+			return nil, err
+		}
+		m.Message = fmt.Sprintf("%dx%d diagram written to %s", r.Width(), r.Height(), filename)
+	case "set":
+		return nil, m.setOptions(args)
+	case "colorscheme":
+		return nil, m.colorscheme(args)
+	case "nohlsearch":
+		m.hlSearch = false
+	case "source":
+		return nil, m.source(args)
+	case "nmap", "nnoremap":
+		return nil, m.mapKeys(vim.Normal, cmd.Arg, cmd.Name == "nnoremap")
+	case "cmap", "cnoremap":
+		return nil, m.mapKeys(vim.Command, cmd.Arg, cmd.Name == "cnoremap")
+	case "nunmap":
+		return nil, m.unmapKeys(vim.Normal, cmd.Arg)
+	case "cunmap":
+		return nil, m.unmapKeys(vim.Command, cmd.Arg)
+	}
+	return nil, nil
+}
+
+// usesFiles reports whether the command name with args reads or writes a
+// file: :e# and :split without a file name only show open buffers. Theme
+// files are refused by LoadTheme, which every way of setting a theme uses.
+func usesFiles(name string, args []string) bool {
+	switch name {
+	case "write", "wq", "xit", "wall", "wqall", "xall", "export", "source":
+		return true
+	case "edit":
+		return len(args) == 0 || args[0] != "#"
+	case "split", "vsplit":
+		return len(args) > 0
+	}
+	return false
+}
+
+// visualRegion returns the selection for a '<,'> range, nil without a
+// range, and an error for a move range.
+func (m *Model) visualRegion(r *ex.Range) (*board.Rect, error) {
+	if r == nil {
+		return nil, nil
+	}
+	if !r.Visual {
+		return nil, fmt.Errorf("E16: Invalid range: this command takes '<,'>")
+	}
+	sel := m.Handler.LastSelection
+	return &sel, nil
+}
+
+// yank implements :yank: '<,'>y yanks the selection, otherwise moves are
+// yanked, by default the current one.
+func (m *Model) yank(r *ex.Range, args []string) error {
+	name := ""
+	if len(args) > 0 {
+		if !vim.IsRegister(args[0]) {
+			return fmt.Errorf("E488: Trailing characters: %s", args[0])
+		}
+		name = args[0]
+	}
+	if r != nil && r.Visual {
+		return m.applyOperator(&vim.Action{Value: "y", Region: m.Handler.LastSelection, Register: name})
+	}
+	current := m.Game.Current.MoveNumber()
+	start, end := current, current
+	if r != nil {
+		var err error
+		if start, end, err = r.Resolve(current, m.Game.LastMoveNumber()); err != nil {
+			return err
+		}
+	}
+	return m.yankMoves(start, end, name)
+}
+
+// deleteRange clears the selection for '<,'>d, if it was made in Insert
+// mode, and otherwise deletes moves, by default the current one. Variations
+// branching off them go too, but only with bang.
+func (m *Model) deleteRange(r *ex.Range, bang bool) error {
+	if r != nil && r.Visual {
+		return m.applyOperator(&vim.Action{Value: "d", Region: m.Handler.LastSelection, Visual: true})
+	}
+	m.leaveScoring()
+	current := m.Game.Current.MoveNumber()
+	start, end := current, current
+	if r != nil {
+		var err error
+		start, end, err = r.Resolve(current, m.Game.LastMoveNumber())
+		if err != nil {
+			return err
+		}
+	}
+	if err := m.Game.DeleteMoves(start, end, bang); err != nil {
+		if errors.Is(err, game.ErrVariations) {
+			return fmt.Errorf("%v (add ! to override)", err)
+		}
+		return err
+	}
+	m.Message = fmt.Sprintf("%d moves deleted", end-start+1)
+	return nil
+}
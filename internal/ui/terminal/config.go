@@ -0,0 +1,108 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vimgo/vimgo/internal/ex"
+	"github.com/vimgo/vimgo/internal/vim"
+)
+
+// DefaultRCFile returns the path of the startup file, ~/.vimgorc.
+func DefaultRCFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".vimgorc"
+	}
+	return filepath.Join(home, ".vimgorc")
+}
+
+// Source runs each line of filename as an ex command, like Vim's :source.
+// Blank lines and lines starting with " are skipped. Errors do not stop
+// the file; they are returned together, with their line numbers. The file
+// is remembered for :source without an argument.
+func (m *Model) Source(filename string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	m.rcFile = filename
+
+	var errs []error
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), ":")
+		if line == "" || strings.HasPrefix(line, `"`) {
+			continue
+		}
+		cmds, err := ex.Parse(line)
+		for _, cmd := range cmds {
+			if err != nil {
+				break
+			}
+			// Quitting from a configuration file is ignored.
+			_, err = m.execute(cmd)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s line %d: %v", filename, i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// source implements :source, which reads the startup file again by default.
+func (m *Model) source(args []string) error {
+	filename := m.rcFile
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	if filename == "" {
+		return fmt.Errorf("E471: Argument required")
+	}
+	return m.Source(filename)
+}
+
+// mapKeys implements :nmap, :nnoremap, :cmap and :cnoremap. Without a
+// right-hand side it lists the mappings starting with lhs.
+func (m *Model) mapKeys(mode vim.Mode, arg string, noremap bool) error {
+	lhsText, rhsText, _ := strings.Cut(arg, " ")
+	rhsText = strings.TrimSpace(rhsText)
+
+	var lhs []string
+	if lhsText != "" {
+		var err error
+		if lhs, err = vim.ParseKeys(lhsText); err != nil {
+			return err
+		}
+	}
+	if rhsText == "" {
+		lines := m.Handler.Mappings(mode, lhs)
+		if len(lines) == 0 {
+			m.Message = "No mapping found"
+			return nil
+		}
+		m.Message = strings.Join(lines, "\n")
+		return nil
+	}
+
+	rhs, err := vim.ParseKeys(rhsText)
+	if err != nil {
+		return err
+	}
+	m.Handler.Map(mode, lhs, rhs, noremap)
+	return nil
+}
+
+// unmapKeys implements :nunmap and :cunmap.
+func (m *Model) unmapKeys(mode vim.Mode, arg string) error {
+	lhs, err := vim.ParseKeys(arg)
+	if err != nil {
+		return err
+	}
+	if !m.Handler.Unmap(mode, lhs) {
+		return fmt.Errorf("E31: No such mapping")
+	}
+	return nil
+}
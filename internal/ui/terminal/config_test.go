@@ -0,0 +1,50 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestSourceRCFile(t *testing.T) {
+	rc := filepath.Join(t.TempDir(), "vimgorc")
+	content := strings.Join([]string{
+		`" a comment`,
+		"set coords komi=5.5",
+		"",
+		":nnoremap Q x",
+		"set nosuch",
+		"nmap zz Q",
+		"quit",
+	}, "\n")
+	if err := os.WriteFile(rc, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel(9)
+	err := m.Source(rc)
+	if err == nil || !strings.Contains(err.Error(), "line 5: E518") {
+		t.Fatalf("expected the bad line reported with its number, got %v", err)
+	}
+	if strings.Count(err.Error(), "\n") != 0 {
+		t.Fatalf("expected only line 5 reported, got %v", err)
+	}
+	if !m.ShowCoords || m.Komi != 5.5 {
+		t.Fatalf("expected the lines after an error still run")
+	}
+
+	// zz maps to Q, which maps on to x.
+	m = press(t, m, "z", "z")
+	if m.Game.Board.At(4, 4) != board.Black {
+		t.Fatalf("expected zz to play through the mappings")
+	}
+
+	command(t, &m, "set nocoords")
+	m.handleCommand("source")
+	if !m.ShowCoords {
+		t.Fatalf("expected :source to read the file again")
+	}
+}
@@ -0,0 +1,416 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/diagram"
+	"github.com/vimgo/vimgo/internal/vim"
+)
+
+// density is how much room an intersection takes on screen. :set density
+// chooses one, or "auto" the largest that fits the window.
+type density int
+
+const (
+	// densityFull draws an intersection every 4 columns and 2 rows, with
+	// grid lines in between.
+	densityFull density = iota
+	// densityCompact draws one row of the board per line, an intersection
+	// every 2 columns.
+	densityCompact
+	// densityHalf draws colored half blocks: one column per intersection
+	// and two rows of the board per line.
+	densityHalf
+)
+
+var densityNames = []string{"full", "compact", "half"}
+
+func (d density) String() string {
+	return densityNames[d]
+}
+
+// gap is the number of grid line characters between two intersections of
+// a row.
+func (d density) gap() int {
+	switch d {
+	case densityFull:
+		return 3
+	case densityCompact:
+		return 1
+	}
+	return 0
+}
+
+// cols and rows are the size of n by n intersections, without coordinates.
+func (d density) cols(n int) int {
+	return n + (n-1)*d.gap()
+}
+
+func (d density) rows(n int) int {
+	switch d {
+	case densityFull:
+		return 2*n - 1
+	case densityHalf:
+		return (n + 1) / 2
+	}
+	return n
+}
+
+// fit returns how many intersections fit in cols columns and rows rows.
+func (d density) fit(cols, rows int) (int, int) {
+	x := (cols + d.gap()) / (d.gap() + 1)
+	switch d {
+	case densityFull:
+		return x, (rows + 1) / 2
+	case densityHalf:
+		return x, 2 * rows
+	}
+	return x, rows
+}
+
+// pointAt returns the intersection at column col and row row of the board
+// drawing, counted from the first intersection, and whether there is one
+// that close. A half-block line holds two rows; its upper one is returned.
+func (d density) pointAt(col, row int) (board.Point, bool) {
+	step := d.gap() + 1
+	if col < -step/2 || row < 0 && !(d == densityFull && row == -1) {
+		return board.Point{}, false
+	}
+	p := board.Point{X: (col + step/2) / step, Y: row}
+	switch d {
+	case densityFull:
+		p.Y = (row + 1) / 2
+	case densityHalf:
+		p.Y = 2 * row
+	}
+	return p, true
+}
+
+// padding is the vertical padding inside the board's box. The smaller
+// densities save the rows.
+func (d density) padding() int {
+	if d == densityFull {
+		return 1
+	}
+	return 0
+}
+
+// The board's box adds a border and a column of padding on each side, and
+// coordinates a gutter of gutterWidth columns and a row on each side.
+const (
+	boxWidth    = 2 + 2
+	gutterWidth = 3
+)
+
+// boardLayout picks the density for window w showing a board of the given
+// size, and the part of the board to draw: all of it when it fits,
+// otherwise a viewport around the cursor.
+func (m Model) boardLayout(w *Window, size int) (density, board.Rect) {
+	rects := make(map[*Window]rect)
+	m.layout.rects(rect{0, 0, 1, 1}, rects)
+	r := rects[w]
+	cols := int(float64(m.boardWidth())*(r.x1-r.x0)) - boxWidth
+	rows := int(float64(m.boardHeight())*(r.y1-r.y0)) - 2
+	if m.layout.win == nil {
+		rows-- // status line
+	}
+	if m.ShowCoords {
+		cols -= 2 * gutterWidth
+		rows -= 2
+	}
+
+	candidates := []density{densityFull, densityCompact, densityHalf}
+	if m.Profile == termenv.Ascii {
+		// Half blocks need colors.
+		candidates = candidates[:2]
+	}
+	if d, ok := parseDensity(m.Density); ok {
+		candidates = []density{d}
+	}
+	var d density
+	for _, d = range candidates {
+		if d.cols(size) <= cols && d.rows(size)+2*d.padding() <= rows {
+			return d, board.NewRect(board.Point{}, board.Point{X: size - 1, Y: size - 1})
+		}
+	}
+
+	// Even the smallest does not fit: follow the cursor.
+	width, height := d.fit(cols, rows-2*d.padding())
+	width, height = min(max(width, 1), size), min(max(height, 1), size)
+	cursor := board.Point{X: w.CursorX, Y: w.CursorY}
+	if w == m.win {
+		cursor = board.Point{X: m.Handler.CursorX, Y: m.Handler.CursorY}
+	}
+	x0 := min(max(cursor.X-width/2, 0), size-width)
+	y0 := min(max(cursor.Y-height/2, 0), size-height)
+	return d, board.NewRect(board.Point{X: x0, Y: y0}, board.Point{X: x0 + width - 1, Y: y0 + height - 1})
+}
+
+func parseDensity(s string) (density, bool) {
+	for i, name := range densityNames {
+		if s == name {
+			return density(i), true
+		}
+	}
+	return 0, false
+}
+
+// boardMarks is what a window's board shows besides the stones.
+type boardMarks struct {
+	focused   bool
+	cursor    board.Point
+	selection *board.Rect
+	matches   map[board.Point]bool
+	owners    map[board.Point]board.Color
+	labels    map[board.Point]string
+	ghosts    map[board.Point]board.Color
+	flash     map[board.Point]bool
+	dead      map[board.Point]bool
+	last      *board.Point
+}
+
+// gridLines draws the part view of the board with box drawing characters
+// at the full or compact density.
+func (m Model) gridLines(b *board.Board, view board.Rect, d density, mk boardMarks, captured board.Color) []string {
+	st := m.st
+	gl := st.glyphs
+	size := b.Size
+	spacer := st.base.Render(strings.Repeat(" ", d.gap()))
+	labelWidth := func(x, y int) int {
+		return max(0, len(mk.labels[board.Point{X: x, Y: y}])-1)
+	}
+
+	var lines []string
+	columns := func() string {
+		var s strings.Builder
+		s.WriteString(st.base.Render("   ")) // Space for left numbers
+		for x := view.Min.X; x <= view.Max.X; x++ {
+			s.WriteString(st.coords.Render(diagram.ColumnLabel(x)))
+			if x < view.Max.X {
+				s.WriteString(spacer)
+			}
+		}
+		return s.String()
+	}
+	if m.ShowCoords {
+		lines = append(lines, columns())
+	}
+
+	for y := view.Min.Y; y <= view.Max.Y; y++ {
+		var row strings.Builder
+		if m.ShowCoords {
+			row.WriteString(st.coords.Render(fmt.Sprintf("%2d ", size-y)))
+		}
+		for x := view.Min.X; x <= view.Max.X; x++ {
+			row.WriteString(m.renderCell(b, x, y, mk, captured))
+
+			// Grid line to the next intersection, shortened by two-digit
+			// move numbers: they extend to the right, or to the left on
+			// the last column.
+			if x < view.Max.X {
+				n := d.gap() - labelWidth(x, y)
+				if x+1 == view.Max.X {
+					n -= labelWidth(x+1, y)
+				}
+				row.WriteString(st.grid.Render(strings.Repeat(gl.Horizontal, max(n, 0))))
+			}
+		}
+		if m.ShowCoords {
+			row.WriteString(st.coords.Render(fmt.Sprintf(" %-2d", size-y)))
+		}
+		lines = append(lines, row.String())
+
+		// Vertical connection row (for square look)
+		if d == densityFull && y < view.Max.Y {
+			var between strings.Builder
+			if m.ShowCoords {
+				between.WriteString(st.base.Render("   "))
+			}
+			for x := view.Min.X; x <= view.Max.X; x++ {
+				between.WriteString(st.grid.Render(gl.Vertical))
+				if x < view.Max.X {
+					between.WriteString(spacer)
+				}
+			}
+			lines = append(lines, between.String())
+		}
+	}
+
+	if m.ShowCoords {
+		lines = append(lines, columns())
+	}
+	return lines
+}
+
+// renderCell draws the intersection at x, y with its stone or grid glyph
+// and the highlights on it.
+func (m Model) renderCell(b *board.Board, x, y int, mk boardMarks, captured board.Color) string {
+	st := m.st
+	gl := st.glyphs
+	size := b.Size
+
+	// Determine grid character based on position
+	char := gl.Cross
+	switch {
+	case y == 0 && x == 0:
+		char = gl.TopLeft
+	case y == 0 && x == size-1:
+		char = gl.TopRight
+	case y == 0:
+		char = gl.TeeTop
+	case y == size-1 && x == 0:
+		char = gl.BottomLeft
+	case y == size-1 && x == size-1:
+		char = gl.BottomRight
+	case y == size-1:
+		char = gl.TeeBottom
+	case x == 0:
+		char = gl.TeeLeft
+	case x == size-1:
+		char = gl.TeeRight
+	}
+
+	text, style := char, st.grid
+	// Star points (Hoshi)
+	if b.IsStar(x, y) {
+		text, style = gl.Star, st.star
+	}
+
+	c := b.At(x, y)
+	pt := board.Point{X: x, Y: y}
+	if c != board.Empty {
+		text, style = m.stoneGlyph(c), st.stone(c)
+		if label := mk.labels[pt]; label != "" {
+			text = label
+			if c == board.Black {
+				style = st.label.Inherit(style)
+			}
+		}
+		if mk.last != nil && *mk.last == pt {
+			style = st.lastMove.Inherit(style)
+		}
+		if mk.dead[pt] {
+			style = st.dead.Inherit(style)
+		}
+	} else if mk.flash[pt] {
+		text, style = m.stoneGlyph(captured), st.flash.Inherit(st.stone(captured))
+	} else if ghost, ok := mk.ghosts[pt]; ok {
+		text, style = m.stoneGlyph(ghost), st.ghost
+	} else if owner := mk.owners[pt]; owner != board.Empty {
+		text, style = st.owner(owner)
+	}
+
+	if hl, ok := m.highlight(pt, mk); ok {
+		style = hl.Inherit(style)
+	}
+	return style.Render(text)
+}
+
+// highlight returns the background style of the cursor, the selection or
+// a search match at p.
+func (m Model) highlight(p board.Point, mk boardMarks) (lipgloss.Style, bool) {
+	switch {
+	case p == mk.cursor && mk.focused:
+		return m.st.cursor, true
+	case p == mk.cursor:
+		return m.st.inactive, true
+	case mk.selection != nil && mk.selection.Contains(p.X, p.Y):
+		return m.st.selection, true
+	case mk.matches[p]:
+		return m.st.search, true
+	}
+	return lipgloss.Style{}, false
+}
+
+// halfBlockLines draws the part view of the board as colored upper half
+// blocks, two rows of intersections per line. Move numbers and the last
+// move marker need glyphs and are left out.
+func (m Model) halfBlockLines(b *board.Board, view board.Rect, mk boardMarks, captured board.Color) []string {
+	st := m.st
+	size := b.Size
+	color := func(x, y int) lipgloss.TerminalColor {
+		p := board.Point{X: x, Y: y}
+		if hl, ok := m.highlight(p, mk); ok {
+			return hl.GetBackground()
+		}
+		c := b.At(x, y)
+		switch {
+		case c != board.Empty && mk.dead[p]:
+			return st.dead.GetForeground()
+		case c != board.Empty:
+			return stoneColor(st, c)
+		case mk.flash[p]:
+			return st.flash.GetBackground()
+		case mk.ghosts[p] != board.Empty:
+			return st.ghost.GetForeground()
+		case mk.owners[p] != board.Empty:
+			_, style := st.owner(mk.owners[p])
+			return style.GetForeground()
+		case b.IsStar(x, y):
+			return st.star.GetForeground()
+		}
+		if bg := st.base.GetBackground(); bg != (lipgloss.NoColor{}) {
+			return bg
+		}
+		return st.grid.GetForeground()
+	}
+
+	var lines []string
+	columns := func() string {
+		var s strings.Builder
+		s.WriteString(st.base.Render("   "))
+		for x := view.Min.X; x <= view.Max.X; x++ {
+			s.WriteString(st.coords.Render(diagram.ColumnLabel(x)))
+		}
+		return s.String()
+	}
+	if m.ShowCoords {
+		lines = append(lines, columns())
+	}
+	for y := view.Min.Y; y <= view.Max.Y; y += 2 {
+		var row strings.Builder
+		if m.ShowCoords {
+			row.WriteString(st.coords.Render(fmt.Sprintf("%2d ", size-y)))
+		}
+		for x := view.Min.X; x <= view.Max.X; x++ {
+			style := lipgloss.NewStyle().Foreground(color(x, y))
+			if y < view.Max.Y {
+				style = style.Background(color(x, y+1))
+			}
+			row.WriteString(style.Render("▀"))
+		}
+		if m.ShowCoords && y < view.Max.Y {
+			row.WriteString(st.coords.Render(fmt.Sprintf(" %-2d", size-y-1)))
+		}
+		lines = append(lines, row.String())
+	}
+	if m.ShowCoords {
+		lines = append(lines, columns())
+	}
+	return lines
+}
+
+// stoneColor is the color of a stone as a half block. A theme that leaves
+// stones in the terminal's colors gets black and bright white.
+func stoneColor(st styles, c board.Color) lipgloss.TerminalColor {
+	if fg := st.stone(c).GetForeground(); fg != (lipgloss.NoColor{}) {
+		return fg
+	}
+	if c == board.White {
+		return lipgloss.Color("15")
+	}
+	return lipgloss.Color("0")
+}
+
+// visualSelection returns the selection while in Visual mode.
+func (m Model) visualSelection() *board.Rect {
+	if m.Handler.Mode != vim.Visual {
+		return nil
+	}
+	sel := m.Handler.Selection()
+	return &sel
+}
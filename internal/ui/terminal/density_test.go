@@ -0,0 +1,84 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestDensityFitsTheWindow(t *testing.T) {
+	for _, tc := range []struct {
+		width, height int
+		profile       termenv.Profile
+		want          density
+		viewport      bool
+	}{
+		{160, 50, termenv.TrueColor, densityFull, false},
+		{100, 30, termenv.TrueColor, densityCompact, false},
+		{60, 20, termenv.TrueColor, densityHalf, false},
+		{60, 20, termenv.Ascii, densityCompact, true},
+		{30, 10, termenv.TrueColor, densityHalf, true},
+	} {
+		m := resize(NewModel(19), tc.width, tc.height)
+		m.Profile = tc.profile
+		m.Handler.CursorX, m.Handler.CursorY = 18, 0
+		d, view := m.boardLayout(m.win, 19)
+		if d != tc.want {
+			t.Errorf("%dx%d: expected %v, got %v", tc.width, tc.height, tc.want, d)
+		}
+		whole := view == board.NewRect(board.Point{}, board.Point{X: 18, Y: 18})
+		if whole == tc.viewport {
+			t.Errorf("%dx%d: expected viewport=%v, got %v", tc.width, tc.height, tc.viewport, view)
+		}
+		if !view.Contains(18, 0) {
+			t.Errorf("%dx%d: expected the viewport %v to follow the cursor", tc.width, tc.height, view)
+		}
+		for i, line := range strings.Split(m.View(), "\n") {
+			if w := lipgloss.Width(line); w > tc.width {
+				t.Errorf("%dx%d: line %d is %d columns wide", tc.width, tc.height, i, w)
+			}
+		}
+	}
+}
+
+func TestDensityOption(t *testing.T) {
+	m := resize(NewModel(19), 160, 50)
+	command(t, &m, "set density=half")
+	if d, _ := m.boardLayout(m.win, 19); d != densityHalf {
+		t.Fatalf("expected :set density=half to force half blocks, got %v", d)
+	}
+	if m.handleCommand("set density=tiny"); m.Error == nil || m.Density != "half" {
+		t.Fatalf("expected an unknown density refused")
+	}
+}
+
+func TestDensityPointAt(t *testing.T) {
+	for _, d := range []density{densityFull, densityCompact, densityHalf} {
+		step := d.gap() + 1
+		for _, p := range []board.Point{{X: 0, Y: 0}, {X: 3, Y: 4}, {X: 8, Y: 8}} {
+			row := p.Y
+			switch d {
+			case densityFull:
+				row = 2 * p.Y
+			case densityHalf:
+				row = p.Y / 2
+			}
+			want := p
+			if d == densityHalf {
+				want.Y -= p.Y % 2
+			}
+			// The intersection, and the grid line half a step to its left.
+			for _, col := range []int{p.X * step, p.X*step - step/2} {
+				if got, ok := d.pointAt(col, row); !ok || got != want {
+					t.Errorf("%v: expected %v at (%d, %d), got %v", d, want, col, row, got)
+				}
+			}
+		}
+		if _, ok := d.pointAt(-step, 0); ok {
+			t.Errorf("%v: expected nothing left of the board", d)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package terminal
+
+import (
+	"math"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/vim"
+)
+
+// doubleClick is the longest time between the clicks of a double-click.
+const doubleClick = 400 * time.Millisecond
+
+// click remembers the last click to recognise a double-click.
+type click struct {
+	at    time.Time
+	win   *Window
+	point board.Point
+}
+
+// handleMouse moves the cursor to the clicked intersection, focusing its
+// window. A double-click places a stone and a click on a variation move of
+// the current node goes there. The wheel steps backward and forward
+// through the moves.
+func (m *Model) handleMouse(msg tea.MouseMsg) {
+	if m.ShowHelp || m.Handler.Mode == vim.Command {
+		return
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.leaveScoring()
+		_ = m.Game.Undo()
+		return
+	case tea.MouseButtonWheelDown:
+		m.leaveScoring()
+		_ = m.Game.Forward()
+		return
+	}
+	if msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress {
+		return
+	}
+
+	w, p, ok := m.hitTest(msg.X, msg.Y)
+	if !ok {
+		return
+	}
+	m.focus(w)
+	m.Handler.CursorX, m.Handler.CursorY = p.X, p.Y
+	m.Error = nil
+
+	now := time.Now()
+	double := m.lastClick.win == w && m.lastClick.point == p && now.Sub(m.lastClick.at) <= doubleClick
+	m.lastClick = click{at: now, win: w, point: p}
+	if m.Handler.Mode != vim.Normal {
+		return
+	}
+	for _, child := range m.Game.Variations() {
+		if *child.Point == p {
+			m.leaveScoring()
+			m.Error = m.Game.GoTo(child)
+			return
+		}
+	}
+	if double {
+		m.lastClick = click{}
+		m.leaveScoring()
+		m.Error = m.play(&p)
+	}
+}
+
+// hitTest finds the window and intersection at screen column x and row y,
+// laid out as View does.
+func (m *Model) hitTest(x, y int) (*Window, board.Point, bool) {
+	layout := m.renderMain()
+	left := centerOffset(m.Width, lipgloss.Width(layout))
+	top := 1 + centerOffset(m.boardHeight(), lipgloss.Height(layout))
+
+	origins := make(map[*Window]board.Point)
+	m.windowOrigins(m.layout, left, top, origins)
+	for w, o := range origins {
+		size := m.windowGame(w).Board.Size
+		d, view := m.boardLayout(w, size)
+		col, row := x-o.X-boxWidth/2, y-o.Y-1-d.padding()
+		if m.ShowCoords {
+			col -= gutterWidth
+			row--
+		}
+		p, ok := d.pointAt(col, row)
+		p.X, p.Y = p.X+view.Min.X, p.Y+view.Min.Y
+		if ok && view.Contains(p.X, p.Y) {
+			return w, p, true
+		}
+	}
+	return nil, board.Point{}, false
+}
+
+// windowOrigins records the top-left corner of each window's board in s,
+// which renderLayout draws at column x and row y.
+func (m *Model) windowOrigins(s *split, x, y int, out map[*Window]board.Point) {
+	if s.win != nil {
+		out[s.win] = board.Point{X: x, Y: y}
+		return
+	}
+	for _, c := range s.children {
+		m.windowOrigins(c, x, y, out)
+		view := m.renderLayout(c)
+		if s.vertical {
+			x += lipgloss.Width(view)
+		} else {
+			y += lipgloss.Height(view)
+		}
+	}
+}
+
+// centerOffset is where lipgloss.Place puts content of length n centered
+// in length total.
+func centerOffset(total, n int) int {
+	gap := total - n
+	if gap <= 0 {
+		return 0
+	}
+	return gap - int(math.Round(float64(gap)*0.5))
+}
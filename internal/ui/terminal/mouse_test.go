@@ -0,0 +1,134 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// resize gives m a screen of the given size, without the side panel.
+func resize(m Model, width, height int) Model {
+	next, _ := m.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	m = next.(Model)
+	m.Panel = false
+	return m
+}
+
+// glyphAt returns the screen cells of glyph in m's view.
+func glyphAt(m Model, glyph string) [][2]int {
+	var cells [][2]int
+	for y, line := range strings.Split(m.View(), "\n") {
+		for i := strings.Index(line, glyph); i >= 0; {
+			cells = append(cells, [2]int{lipgloss.Width(line[:i]), y})
+			next := strings.Index(line[i+len(glyph):], glyph)
+			if next < 0 {
+				break
+			}
+			i += len(glyph) + next
+		}
+	}
+	return cells
+}
+
+// clickAt presses the left button at column x and row y.
+func clickAt(m Model, x, y int) Model {
+	next, _ := m.Update(tea.MouseMsg{X: x, Y: y, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+	return next.(Model)
+}
+
+// wheel turns the mouse wheel one step.
+func wheel(m Model, button tea.MouseButton) Model {
+	next, _ := m.Update(tea.MouseMsg{Button: button, Action: tea.MouseActionPress})
+	return next.(Model)
+}
+
+func TestMouseHitsTheStoneDrawn(t *testing.T) {
+	for _, tc := range []struct {
+		density string
+		coords  bool
+	}{{"full", false}, {"full", true}, {"compact", false}, {"compact", true}} {
+		m := resize(NewModel(9), 120, 40)
+		m.Density, m.ShowCoords = tc.density, tc.coords
+		m.Handler.CursorX, m.Handler.CursorY = 2, 6
+		m = press(t, m, "x")
+		m.Handler.CursorX, m.Handler.CursorY = 7, 1
+
+		cells := glyphAt(m, m.Stones[0])
+		if len(cells) != 1 {
+			t.Fatalf("expected one black stone on screen, found %d", len(cells))
+		}
+		m = clickAt(m, cells[0][0], cells[0][1])
+		if m.Handler.CursorX != 2 || m.Handler.CursorY != 6 {
+			t.Errorf("%s, coords=%v: expected the click at (2, 6), got (%d, %d)",
+				tc.density, tc.coords, m.Handler.CursorX, m.Handler.CursorY)
+		}
+	}
+}
+
+func TestMouseFocusesWindows(t *testing.T) {
+	m := resize(NewModel(9), 160, 40)
+	m = press(t, m, "x")
+	command(t, &m, "vsplit")
+	left, right := m.layout.windows()[0], m.layout.windows()[1]
+
+	cells := glyphAt(m, m.Stones[0])
+	if len(cells) != 2 || cells[0][0] > cells[1][0] {
+		t.Fatalf("expected the stone in both windows, found %v", cells)
+	}
+	m = clickAt(m, cells[1][0], cells[1][1])
+	if m.win != right {
+		t.Fatalf("expected a click in the right window to focus it")
+	}
+	m = clickAt(m, cells[0][0], cells[0][1])
+	if m.win != left {
+		t.Fatalf("expected a click in the left window to focus it")
+	}
+	if m = clickAt(m, 0, 0); m.win != left || m.Handler.CursorX != 4 {
+		t.Fatalf("expected a click off the boards to do nothing")
+	}
+}
+
+func TestMouseDoubleClickAndWheel(t *testing.T) {
+	m := resize(NewModel(9), 120, 40)
+	m.Density = "full"
+	m = press(t, m, "x")
+	cell := glyphAt(m, m.Stones[0])[0]
+	x, y := cell[0], cell[1]
+
+	// Double-clicking next to the stone plays there.
+	step := densityFull.gap() + 1
+	x += step
+	m = clickAt(m, x, y)
+	if m.Game.Current.MoveNumber() != 1 {
+		t.Fatalf("expected a single click not to play")
+	}
+	m = clickAt(m, x, y)
+	if m.Game.Board.At(5, 4) != board.White {
+		t.Fatalf("expected a double-click to play white at (5, 4)")
+	}
+
+	m = wheel(m, tea.MouseButtonWheelUp)
+	m = wheel(m, tea.MouseButtonWheelUp)
+	if m.Game.Current != m.Game.Root {
+		t.Fatalf("expected the wheel to step back to the start")
+	}
+	m = wheel(m, tea.MouseButtonWheelDown)
+	if m.Game.Current.MoveNumber() != 1 {
+		t.Fatalf("expected the wheel to step forward")
+	}
+
+	// With a second variation, a click on either move goes there.
+	m = clickAt(m, x+step, y)
+	m = clickAt(m, x+step, y)
+	m = wheel(m, tea.MouseButtonWheelUp)
+	if len(m.Game.Variations()) != 2 {
+		t.Fatalf("expected two variations after move 1")
+	}
+	m = clickAt(m, x, y)
+	if m.Game.Current.MoveNumber() != 2 || *m.Game.Current.Point != (board.Point{X: 5, Y: 4}) {
+		t.Fatalf("expected a click on the variation to go there")
+	}
+}
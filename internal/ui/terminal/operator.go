@@ -0,0 +1,161 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/rules"
+	"github.com/vimgo/vimgo/internal/vim"
+)
+
+// errNotEditing refuses to clear a Visual selection made outside Insert mode,
+// where stones are only placed by playing.
+var errNotEditing = fmt.Errorf("not in edit mode (use i, then v)")
+
+// applyOperator runs an operator from the vim grammar on its text object or
+// region.
+func (m *Model) applyOperator(a *vim.Action) error {
+	points, err := m.resolveTarget(a)
+	if err != nil {
+		return err
+	}
+
+	switch a.Value {
+	case "d", "c":
+		if a.Visual && !m.Handler.VisualInsert {
+			return errNotEditing
+		}
+		// The stones are deleted even if the clipboard could not be set.
+		stored := m.store(a.Register, m.yankPoints(points), true)
+		changes := make(map[board.Point]board.Color)
+		for _, p := range points {
+			changes[p] = board.Empty
+		}
+		m.leaveScoring()
+		if err := m.Game.Edit(changes); err != nil {
+			return err
+		}
+		return stored
+	case "y":
+		r := m.yankPoints(points)
+		if len(points) == m.Game.Board.Size*m.Game.Board.Size {
+			// The whole board, as with yy, is shared as a position.
+			r.Text = m.Game.PositionSGF()
+		}
+		m.Message = fmt.Sprintf("yanked %dx%d region", r.Pattern.Width, r.Pattern.Height)
+		return m.store(a.Register, r, false)
+	case "m":
+		if !m.Scoring {
+			return fmt.Errorf("not in scoring phase (use :score)")
+		}
+		for _, p := range points {
+			if m.Game.Board.At(p.X, p.Y) == board.Empty {
+				continue
+			}
+			if m.Dead[p] {
+				delete(m.Dead, p)
+			} else {
+				m.Dead[p] = true
+			}
+		}
+		m.updateScore()
+	}
+	return nil
+}
+
+// resolveTarget lists the intersections an operator applies to.
+func (m *Model) resolveTarget(a *vim.Action) ([]board.Point, error) {
+	if a.Object == "" {
+		return a.Region.Points(), nil
+	}
+
+	b := m.Game.Board
+	x, y := m.Handler.CursorX, m.Handler.CursorY
+	switch a.Object {
+	case "ig", "ag":
+		g := rules.GetGroup(b, x, y)
+		if g == nil {
+			return nil, fmt.Errorf("no group under cursor")
+		}
+		if a.Object == "ag" {
+			return append(g.Stones, g.Liberties...), nil
+		}
+		return g.Stones, nil
+	case "it", "at":
+		points, _ := rules.TerritoryAt(b, x, y)
+		if points == nil {
+			return nil, fmt.Errorf("no territory under cursor")
+		}
+		if a.Object == "at" {
+			return append(points, borderStones(b, points)...), nil
+		}
+		return points, nil
+	}
+	return nil, fmt.Errorf("unknown text object: %s", a.Object)
+}
+
+// borderStones returns the stones adjacent to an empty region.
+func borderStones(b *board.Board, region []board.Point) []board.Point {
+	seen := make(map[board.Point]bool)
+	var stones []board.Point
+	for _, p := range region {
+		for _, a := range []board.Point{{X: p.X + 1, Y: p.Y}, {X: p.X - 1, Y: p.Y}, {X: p.X, Y: p.Y + 1}, {X: p.X, Y: p.Y - 1}} {
+			if b.IsOnBoard(a.X, a.Y) && b.At(a.X, a.Y) != board.Empty && !seen[a] {
+				seen[a] = true
+				stones = append(stones, a)
+			}
+		}
+	}
+	return stones
+}
+
+// yankPoints makes a register of the stones at points.
+func (m *Model) yankPoints(points []board.Point) *Register {
+	return patternRegister(extractPoints(m.Game.Board, points), boundingRect(points), m.Game.Board.Size)
+}
+
+func boundingRect(points []board.Point) board.Rect {
+	r := board.NewRect(points[0], points[0])
+	for _, p := range points[1:] {
+		r.Min.X, r.Min.Y = min(r.Min.X, p.X), min(r.Min.Y, p.Y)
+		r.Max.X, r.Max.Y = max(r.Max.X, p.X), max(r.Max.Y, p.Y)
+	}
+	return r
+}
+
+// extractPoints yanks the bounding box of points; intersections inside the
+// box that are not part of points are left empty in the pattern.
+func extractPoints(b *board.Board, points []board.Point) *board.Pattern {
+	r := boundingRect(points)
+	full := b.Extract(r)
+	pattern := &board.Pattern{Width: full.Width, Height: full.Height, Cells: make([]board.Color, len(full.Cells))}
+	for _, p := range points {
+		i := (p.Y-r.Min.Y)*pattern.Width + (p.X - r.Min.X)
+		pattern.Cells[i] = full.Cells[i]
+	}
+	return pattern
+}
+
+// enterScoring starts (or refreshes) the scoring phase, where groups can be
+// marked dead with the m operator before the score is counted.
+func (m *Model) enterScoring(method string) {
+	if !m.Scoring {
+		m.Scoring = true
+		m.Dead = make(map[board.Point]bool)
+	}
+	m.ScoreMethod = method
+	m.updateScore()
+}
+
+func (m *Model) leaveScoring() {
+	m.Scoring = false
+	m.Dead = nil
+	m.ScoreText = ""
+}
+
+// updateScore recounts the position with the dead stones removed.
+func (m *Model) updateScore() {
+	alive, blackDead, whiteDead := rules.RemoveDead(m.Game.Board, m.Dead)
+	score := rules.CountScore(alive, m.ScoreMethod, m.Game.BlackCaptures+whiteDead, m.Game.WhiteCaptures+blackDead, m.Komi)
+	m.ScoreText = fmt.Sprintf("[W %.1f B %.1f]", score.White, score.Black)
+}
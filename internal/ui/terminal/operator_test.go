@@ -0,0 +1,118 @@
+package terminal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/vim"
+)
+
+func TestVisualDeleteOnlyWhileEditing(t *testing.T) {
+	m := NewModel(9)
+	m.Game.Move(4, 4)
+	m.Game.Move(5, 4)
+
+	// A selection made while playing is not cleared, by d, c or :'<,'>d.
+	for _, keys := range [][]string{{"v", "l", "d"}, {"v", "l", "x"}, {"v", "l", "c"}, {"v", "l", ":", "d", "enter"}} {
+		m.Handler.CursorX = 4
+		m = press(t, m, keys...)
+		if !errors.Is(m.Error, errNotEditing) {
+			t.Errorf("%v: expected %v, got %v", keys, errNotEditing, m.Error)
+		}
+		if m.Game.Board.At(4, 4) != board.Black || m.Game.Board.At(5, 4) != board.White {
+			t.Fatalf("%v: expected the stones kept", keys)
+		}
+		if m.Handler.Mode != vim.Normal {
+			t.Fatalf("%v: expected Normal mode, got %v", keys, m.Handler.Mode)
+		}
+	}
+
+	// Started from Insert mode, it is, and Insert mode goes on.
+	m.Handler.CursorX = 4
+	m = press(t, m, "i", "v", "l", "d")
+	if m.Error != nil || m.Game.Board.At(4, 4) != board.Empty || m.Game.Board.At(5, 4) != board.Empty {
+		t.Fatalf("expected the selection cleared, got %v", m.Error)
+	}
+	if m.Handler.Mode != vim.Insert {
+		t.Fatalf("expected Insert mode, got %v", m.Handler.Mode)
+	}
+	m = press(t, m, "esc", "u")
+	if m.Game.Board.At(4, 4) != board.Black {
+		t.Fatalf("expected u to take the clearing back")
+	}
+}
+
+func TestVisualYankAndPaste(t *testing.T) {
+	m := NewModel(9)
+	m.Game.Move(4, 4)
+	m.Game.Move(5, 4)
+
+	m = press(t, m, "v", "l", "y")
+	if m.Error != nil || m.Message != "yanked 2x1 region" {
+		t.Fatalf("expected a 2x1 region yanked, got %q and %v", m.Message, m.Error)
+	}
+	m = press(t, m, "h", "j", "j", "p")
+	if m.Game.Board.At(4, 6) != board.Black || m.Game.Board.At(5, 6) != board.White {
+		t.Fatalf("expected the region pasted at the cursor")
+	}
+	// The stones of the first selection are still there.
+	if m.Game.Board.At(4, 4) != board.Black {
+		t.Fatalf("expected y to keep the stones")
+	}
+}
+
+func TestOperatorsOnTextObjects(t *testing.T) {
+	m := NewModel(9)
+	m.Game.Move(4, 4)
+	m.Game.Move(2, 2)
+	m.Game.Move(5, 4)
+
+	// yig yanks the group under the cursor, yag its liberties too.
+	m = press(t, m, "y", "i", "g")
+	if r := m.Registers["0"]; r == nil || r.Pattern.Width != 2 || r.Pattern.Height != 1 {
+		t.Fatalf("expected the 2x1 group yanked, got %+v", r)
+	}
+	m = press(t, m, "y", "a", "g")
+	if r := m.Registers["0"]; r == nil || r.Pattern.Width != 4 || r.Pattern.Height != 3 {
+		t.Fatalf("expected the group and its liberties yanked, got %+v", r)
+	}
+
+	// m marks dead stones only while scoring.
+	m = press(t, m, "m", "i", "g")
+	if m.Error == nil {
+		t.Fatalf("expected m refused outside scoring")
+	}
+	command(t, &m, "score")
+	before := m.ScoreText
+	m = press(t, m, "m", "i", "g")
+	if !m.Dead[board.Point{X: 4, Y: 4}] || !m.Dead[board.Point{X: 5, Y: 4}] || m.ScoreText == before {
+		t.Fatalf("expected the group marked dead and the score counted again")
+	}
+
+	// dig clears the group, into register 1.
+	m = press(t, m, "d", "i", "g")
+	if m.Game.Board.At(4, 4) != board.Empty || m.Game.Board.At(5, 4) != board.Empty || m.Game.Board.At(2, 2) != board.White {
+		t.Fatalf("expected only the group under the cursor cleared")
+	}
+	if m.Scoring || m.Registers["1"] == nil || m.Registers["1"].Pattern.Width != 2 {
+		t.Fatalf("expected the deleted group in register 1, with scoring left")
+	}
+	m = press(t, m, "d", "i", "g")
+	if m.Error == nil {
+		t.Fatalf("expected an error without a group under the cursor")
+	}
+}
+
+func TestOperatorWithMotion(t *testing.T) {
+	m := NewModel(9)
+	m.Game.Move(4, 4)
+	m.Game.Move(6, 4)
+	m = press(t, m, "y", "2", "l")
+	if r := m.Registers["0"]; r == nil || r.Pattern.Width != 3 || r.Pattern.At(0, 0) != board.Black || r.Pattern.At(2, 0) != board.White {
+		t.Fatalf("expected the three points to the right yanked, got %+v", r)
+	}
+	if m.Handler.CursorX != 4 {
+		t.Fatalf("expected the cursor to stay, got x=%d", m.Handler.CursorX)
+	}
+}
@@ -0,0 +1,178 @@
+package terminal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// option is a setting changed with :set. Boolean options accept name,
+// noname, name! and name?; others take name=value.
+type option struct {
+	boolean bool
+	get     func(m *Model) string
+	set     func(m *Model, value string) error
+}
+
+var options = map[string]option{
+	"coords":     boolOption(func(m *Model) *bool { return &m.ShowCoords }),
+	"scrollbind": boolOption(func(m *Model) *bool { return &m.ScrollBind }),
+	"ownership":  boolOption(func(m *Model) *bool { return &m.Ownership }),
+	"autosave":   boolOption(func(m *Model) *bool { return &m.AutoSave }),
+	"numbers":    boolOption(func(m *Model) *bool { return &m.Numbers }),
+	"panel":      boolOption(func(m *Model) *bool { return &m.Panel }),
+	"komi": {
+		get: func(m *Model) string { return strconv.FormatFloat(m.Komi, 'g', -1, 64) },
+		set: func(m *Model, value string) error {
+			komi, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("not a number")
+			}
+			m.Komi = komi
+			if m.Scoring {
+				m.updateScore()
+			}
+			return nil
+		},
+	},
+	"rules": {
+		get: func(m *Model) string { return m.Rules },
+		set: func(m *Model, value string) error {
+			if value != "chinese" && value != "japanese" {
+				return fmt.Errorf("expected chinese or japanese")
+			}
+			m.Rules = value
+			return nil
+		},
+	},
+	"theme": {
+		get: func(m *Model) string { return m.Theme },
+		set: func(m *Model, value string) error { return m.colorscheme([]string{value}) },
+	},
+	"density": {
+		get: func(m *Model) string { return m.Density },
+		set: func(m *Model, value string) error {
+			if _, ok := parseDensity(value); !ok && value != "auto" {
+				return fmt.Errorf("expected auto, full, compact or half")
+			}
+			m.Density = value
+			return nil
+		},
+	},
+	// timeoutlen is in milliseconds, as in Vim.
+	"timeoutlen": {
+		get: func(m *Model) string { return strconv.FormatInt(m.TimeoutLen.Milliseconds(), 10) },
+		set: func(m *Model, value string) error {
+			ms, err := strconv.Atoi(value)
+			if err != nil || ms < 0 {
+				return fmt.Errorf("expected milliseconds")
+			}
+			m.TimeoutLen = time.Duration(ms) * time.Millisecond
+			return nil
+		},
+	},
+	// stones takes two glyphs, black first: "stones=XO" or "stones=@,O".
+	"stones": {
+		get: func(m *Model) string { return m.Stones[0] + "," + m.Stones[1] },
+		set: func(m *Model, value string) error {
+			glyphs := strings.Split(value, ",")
+			if len(glyphs) == 1 {
+				glyphs = strings.Split(value, "")
+			}
+			if len(glyphs) != 2 || glyphs[0] == "" || glyphs[1] == "" {
+				return fmt.Errorf("expected two glyphs")
+			}
+			m.Stones = [2]string{glyphs[0], glyphs[1]}
+			return nil
+		},
+	},
+}
+
+func boolOption(field func(m *Model) *bool) option {
+	return option{
+		boolean: true,
+		get: func(m *Model) string {
+			if *field(m) {
+				return "on"
+			}
+			return "off"
+		},
+		set: func(m *Model, value string) error {
+			*field(m) = value == "on"
+			return nil
+		},
+	}
+}
+
+func optionNames() []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setOptions implements :set. Without arguments it shows every option.
+func (m *Model) setOptions(args []string) error {
+	if len(args) == 0 {
+		var parts []string
+		for _, name := range optionNames() {
+			parts = append(parts, formatOption(m, name))
+		}
+		m.Message = strings.Join(parts, "  ")
+		return nil
+	}
+
+	var shown []string
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		query := strings.HasSuffix(name, "?")
+		toggle := strings.HasSuffix(name, "!")
+		name = strings.TrimRight(name, "?!")
+
+		opt, ok := options[name]
+		negate := false
+		if !ok && strings.HasPrefix(name, "no") {
+			opt, ok = options[name[2:]]
+			negate, name = true, name[2:]
+		}
+		if !ok {
+			return fmt.Errorf("E518: Unknown option: %s", arg)
+		}
+		if negate && !opt.boolean {
+			return fmt.Errorf("E474: Invalid argument: %s", arg)
+		}
+
+		switch {
+		case query || (!opt.boolean && !hasValue):
+			shown = append(shown, formatOption(m, name))
+			continue
+		case opt.boolean && hasValue:
+			return fmt.Errorf("E474: Invalid argument: %s", arg)
+		case opt.boolean:
+			value = "on"
+			if negate || (toggle && opt.get(m) == "on") {
+				value = "off"
+			}
+		}
+		if err := opt.set(m, value); err != nil {
+			return fmt.Errorf("E474: Invalid argument: %s: %w", arg, err)
+		}
+	}
+	m.Message = strings.Join(shown, "  ")
+	return nil
+}
+
+func formatOption(m *Model, name string) string {
+	opt := options[name]
+	if !opt.boolean {
+		return name + "=" + opt.get(m)
+	}
+	if opt.get(m) == "on" {
+		return name
+	}
+	return "no" + name
+}
@@ -0,0 +1,98 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestSetOptions(t *testing.T) {
+	m := NewModel(9)
+	command(t, &m, "set coords")
+	if !m.ShowCoords {
+		t.Fatalf("expected :set coords to turn it on")
+	}
+	command(t, &m, "set nocoords numbers!")
+	if m.ShowCoords || !m.Numbers {
+		t.Fatalf("expected :set nocoords numbers! to turn coords off and toggle numbers")
+	}
+	command(t, &m, "set numbers! komi=7.5 rules=japanese stones=XO")
+	if m.Numbers || m.Komi != 7.5 || m.Rules != "japanese" || m.Stones != [2]string{"X", "O"} {
+		t.Fatalf("expected the values set, got komi=%v rules=%s stones=%v", m.Komi, m.Rules, m.Stones)
+	}
+	command(t, &m, "set stones=@,()")
+	if m.Stones != [2]string{"@", "()"} {
+		t.Fatalf("expected comma separated glyphs, got %v", m.Stones)
+	}
+
+	command(t, &m, "set komi coords? numbers?")
+	if m.Message != "komi=7.5  nocoords  nonumbers" {
+		t.Fatalf("unexpected query result %q", m.Message)
+	}
+	command(t, &m, "set")
+	for _, want := range []string{"autosave", "density=auto", "komi=7.5", "rules=japanese"} {
+		if !strings.Contains(m.Message, want) {
+			t.Errorf("expected %q listed by :set, got %q", want, m.Message)
+		}
+	}
+
+	for line, code := range map[string]string{
+		"set nosuch":       "E518",
+		"set coords=yes":   "E474",
+		"set komi=lots":    "E474",
+		"set rules=ing":    "E474",
+		"set stones=XYZ":   "E474",
+		"set density=tiny": "E474",
+	} {
+		if m.handleCommand(line); m.Error == nil || !strings.HasPrefix(m.Error.Error(), code) {
+			t.Errorf(":%s: expected %s, got %v", line, code, m.Error)
+		}
+	}
+	if m.Komi != 7.5 || m.Rules != "japanese" {
+		t.Fatalf("expected bad values to leave the options alone")
+	}
+}
+
+func TestNoPrefixNeedsABooleanOption(t *testing.T) {
+	m := NewModel(9)
+	for _, line := range []string{"set nokomi", "set nostones", "set notimeoutlen?"} {
+		if m.handleCommand(line); m.Error == nil || !strings.HasPrefix(m.Error.Error(), "E474") {
+			t.Errorf(":%s: expected E474, got %v", line, m.Error)
+		}
+	}
+	if m.Message != "" {
+		t.Fatalf("expected nothing shown, got %q", m.Message)
+	}
+}
+
+func TestMappingTimeout(t *testing.T) {
+	m := NewModel(9)
+	command(t, &m, "nnoremap jxx lx")
+	command(t, &m, "set timeoutlen=50")
+	if m.TimeoutLen != 50*time.Millisecond {
+		t.Fatalf("expected timeoutlen in milliseconds, got %v", m.TimeoutLen)
+	}
+
+	var cmd tea.Cmd
+	for _, k := range []string{"j", "x"} {
+		var next tea.Model
+		next, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = next.(Model)
+	}
+	if cmd == nil || m.Game.Current != m.Game.Root {
+		t.Fatalf("expected jx to wait for jxx with a timer")
+	}
+	// The second key restarted the timer; the first one's is stale.
+	next, _ := m.Update(typeaheadTimeout{ID: m.typeaheadID - 1})
+	if m = next.(Model); !m.Handler.Waiting() {
+		t.Fatalf("expected a stale timer ignored")
+	}
+	next, _ = m.Update(typeaheadTimeout{ID: m.typeaheadID})
+	m = next.(Model)
+	if m.Handler.Waiting() || m.Game.Board.At(4, 5) != board.Black {
+		t.Fatalf("expected j and x typed unmapped after the timeout")
+	}
+}
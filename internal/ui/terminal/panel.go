@@ -0,0 +1,133 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// The side panel is panelWidth columns wide, box included, and only shown
+// when the screen leaves at least panelMinBoard columns for the boards.
+// It shows the last panelChat lines of a room's chat.
+const (
+	panelWidth    = 32
+	panelMinBoard = 48
+	panelChat     = 8
+)
+
+// showPanel reports whether the side panel is shown beside the boards.
+func (m Model) showPanel() bool {
+	return m.Panel && m.Width-panelWidth >= panelMinBoard
+}
+
+// boardWidth is the width left for the boards.
+func (m Model) boardWidth() int {
+	if m.showPanel() {
+		return m.Width - panelWidth
+	}
+	return m.Width
+}
+
+// renderMain draws the boards and, beside them, the side panel.
+func (m Model) renderMain() string {
+	boards := m.renderLayout(m.layout)
+	if !m.showPanel() {
+		return boards
+	}
+	panel := m.renderPanel(lipgloss.Height(boards), max(m.boardHeight(), lipgloss.Height(boards)))
+	return lipgloss.JoinHorizontal(lipgloss.Top, boards, panel)
+}
+
+// renderPanel draws the side panel for the current game: the players with
+// their prisoners and clocks, the result, the chat of a game played with
+// others and the comment of the current node, scrolled with Ctrl-E and
+// Ctrl-Y. It is at least height rows high,
+// as high as the boards, and grows with the comment up to limit rows.
+func (m Model) renderPanel(height, limit int) string {
+	g := m.Game
+	inner := panelWidth - boxWidth
+	bold := m.st.base.Bold(true)
+
+	var lines []string
+	for _, c := range []board.Color{board.Black, board.White} {
+		name, rank, captures := g.Info("PB"), g.Info("BR"), g.BlackCaptures
+		if c == board.White {
+			name, rank, captures = g.Info("PW"), g.Info("WR"), g.WhiteCaptures
+		}
+		if name == "" {
+			name = c.String()
+		}
+		if rank != "" {
+			name += " " + rank
+		}
+		turn := " "
+		if g.CurrentPlayer == c {
+			turn = "▸"
+		}
+		lines = append(lines, turn+m.st.stone(c).Render(m.stoneGlyph(c))+" "+bold.Render(name))
+		info := fmt.Sprintf("  Prisoners %d", captures)
+		if left, periods, ok := g.TimeLeft(c); ok {
+			info += "  " + formatClock(left, periods)
+		}
+		lines = append(lines, info)
+	}
+	if result := g.Info("RE"); result != "" {
+		lines = append(lines, "", "Result "+bold.Render(result))
+	}
+	lines = append(lines, "", fmt.Sprintf("Move %d of %d", g.Current.MoveNumber(), g.LastMoveNumber()))
+
+	if m.buf.Remote != nil {
+		lines = append(lines, "", bold.Render("Chat"))
+		var chat []string
+		for _, line := range m.buf.chat {
+			chat = append(chat, strings.Split(lipgloss.NewStyle().Width(inner).Render(line), "\n")...)
+		}
+		if len(chat) == 0 {
+			chat = []string{":say to chat"}
+		}
+		lines = append(lines, chat[max(len(chat)-panelChat, 0):]...)
+	}
+
+	if comment := strings.TrimSpace(g.Current.Comment); comment != "" {
+		lines = append(lines, "", bold.Render("Comment"))
+		wrapped := strings.Split(lipgloss.NewStyle().Width(inner).Render(comment), "\n")
+		room := max(limit-2-len(lines), 1)
+		scroll := 0
+		if m.commentNode == g.Current {
+			scroll = min(m.commentScroll, max(len(wrapped)-room, 0))
+		}
+		end := min(scroll+room, len(wrapped))
+		lines = append(lines, wrapped[scroll:end]...)
+	}
+
+	return m.st.box.Padding(0, 1).
+		Width(panelWidth - 2).
+		Height(max(height-2, len(lines))).
+		Render(strings.Join(lines, "\n"))
+}
+
+// scrollComment scrolls the comment in the side panel n lines down, or up
+// when n is negative. Moving to another node scrolls back to the top.
+func (m *Model) scrollComment(n int) {
+	if m.commentNode != m.Game.Current {
+		m.commentNode, m.commentScroll = m.Game.Current, 0
+	}
+	m.commentScroll = max(m.commentScroll+n, 0)
+}
+
+// formatClock writes time left as h:mm:ss or m:ss, with the byo-yomi
+// periods or stones left in parentheses.
+func formatClock(left time.Duration, periods int) string {
+	s := int(left.Round(time.Second).Seconds())
+	clock := fmt.Sprintf("%d:%02d", s/60, s%60)
+	if s >= 3600 {
+		clock = fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	if periods > 0 {
+		clock += fmt.Sprintf(" (%d)", periods)
+	}
+	return clock
+}
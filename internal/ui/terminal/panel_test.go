@@ -0,0 +1,82 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPanelShowsTheGame(t *testing.T) {
+	m := resize(NewModel(9), 120, 40)
+	m.Panel = true
+	m.Game.Root.Set("PB", "Shusaku")
+	m.Game.Root.Set("BR", "4p")
+	m.Game.Root.Set("RE", "B+2")
+	m.Game.Root.Set("TM", "600")
+	m = press(t, m, "x")
+
+	view := m.View()
+	for _, want := range []string{"Shusaku 4p", "White", "Prisoners 0  10:00", "Result B+2", "Move 1 of 1"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the panel", want)
+		}
+	}
+
+	command(t, &m, "set nopanel")
+	if strings.Contains(m.View(), "Shusaku") {
+		t.Errorf("expected :set nopanel to hide the panel")
+	}
+	command(t, &m, "set panel")
+	if m = resize(m, panelWidth+panelMinBoard-1, 40); m.showPanel() {
+		t.Errorf("expected no panel without room for the boards")
+	}
+}
+
+func TestPanelScrollsTheComment(t *testing.T) {
+	m := resize(NewModel(9), 120, 30)
+	m.Panel = true
+	m = press(t, m, "x")
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("line %02d", i))
+	}
+	comment := strings.Join(lines, "\n")
+	m.Game.Current.Comment = comment
+
+	if view := m.View(); !strings.Contains(view, "line 01") || strings.Contains(view, "line 40") {
+		t.Fatalf("expected the top of a long comment")
+	}
+	m = press(t, m, "ctrl+e", "ctrl+e")
+	if view := m.View(); strings.Contains(view, "line 02") || !strings.Contains(view, "line 03") {
+		t.Fatalf("expected Ctrl-E to scroll the comment")
+	}
+	m = press(t, m, "ctrl+y", "ctrl+y", "ctrl+y")
+	if !strings.Contains(m.View(), "line 01") {
+		t.Fatalf("expected Ctrl-Y to stop at the top")
+	}
+
+	// Another node starts at the top of its comment.
+	m = press(t, m, "ctrl+e", "u")
+	m.Game.Root.Comment = comment
+	if !strings.Contains(m.View(), "line 01") {
+		t.Fatalf("expected another node's comment from the top")
+	}
+}
+
+func TestFormatClock(t *testing.T) {
+	for _, tc := range []struct {
+		left    time.Duration
+		periods int
+		want    string
+	}{
+		{0, 0, "0:00"},
+		{59500 * time.Millisecond, 0, "1:00"},
+		{10 * time.Minute, 3, "10:00 (3)"},
+		{time.Hour + 2*time.Minute + 3*time.Second, 0, "1:02:03"},
+	} {
+		if got := formatClock(tc.left, tc.periods); got != tc.want {
+			t.Errorf("formatClock(%v, %d) = %q, want %q", tc.left, tc.periods, got, tc.want)
+		}
+	}
+}
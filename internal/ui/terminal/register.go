@@ -0,0 +1,179 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/diagram"
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// Register holds yanked content: a board pattern, or moves copied with
+// :yank together with their setup and comments.
+type Register struct {
+	Pattern *board.Pattern
+	Moves   []*game.Node
+	// Text is the content as a diagram or as SGF. It is what goes to the
+	// system clipboard.
+	Text string
+}
+
+// registerOrder is the order :registers lists them in.
+const registerOrder = `"0123456789abcdefghijklmnopqrstuvwxyz-+*`
+
+// maxSummary limits the width of a :registers line.
+const maxSummary = 72
+
+// patternRegister makes a register from a pattern taken at r, with an
+// ASCII diagram of it as text.
+func patternRegister(p *board.Pattern, r board.Rect, size int) *Register {
+	b := board.New(size)
+	for y := 0; y < p.Height; y++ {
+		for x := 0; x < p.Width; x++ {
+			b.Set(r.Min.X+x, r.Min.Y+y, p.At(x, y))
+		}
+	}
+	return &Register{Pattern: p, Text: diagram.ASCII(b, r)}
+}
+
+// store puts r into register name, like Vim: the unnamed register always
+// follows, yanks without a name also go to 0 and deletions to 1, shifting
+// older ones up to 9. Upper case names append moves. + and * also copy
+// the text to the system clipboard, which is the only part that can fail.
+func (m *Model) store(name string, r *Register, deleted bool) error {
+	switch {
+	case name == "_":
+		return nil
+	case name == "" || name == `"`:
+		if deleted {
+			for i := 9; i > 1; i-- {
+				m.Registers[string(rune('0'+i))] = m.Registers[string(rune('0'+i-1))]
+			}
+			m.Registers["1"] = r
+		} else {
+			m.Registers["0"] = r
+		}
+	case name >= "A" && name <= "Z":
+		name = strings.ToLower(name)
+		if old := m.Registers[name]; old != nil && old.Moves != nil && r.Moves != nil {
+			moves := append(append([]*game.Node{}, old.Moves...), r.Moves...)
+			r = &Register{Moves: moves, Text: game.LineSGF(m.Game.Board.Size, moves)}
+		}
+		m.Registers[name] = r
+	default:
+		m.Registers[name] = r
+	}
+	m.Registers[`"`] = r
+
+	if name == "+" || name == "*" {
+		return m.copyToClipboard(name, r.Text)
+	}
+	return nil
+}
+
+// register returns the contents of register name for pasting.
+func (m *Model) register(name string) (*Register, error) {
+	if name == "" {
+		name = `"`
+	}
+	r := m.Registers[strings.ToLower(name)]
+	if r == nil {
+		return nil, fmt.Errorf("E353: Nothing in register %s", name)
+	}
+	return r, nil
+}
+
+// copyToClipboard sets the terminal's clipboard with an OSC 52 escape
+// sequence, which also works over SSH. "*" is the primary selection.
+func (m *Model) copyToClipboard(name, text string) error {
+	if m.Clipboard == nil {
+		return nil
+	}
+	seq := osc52.New(text)
+	if name == "*" {
+		seq = seq.Primary()
+	}
+	if m.Tmux {
+		seq = seq.Tmux()
+	}
+	if _, err := seq.WriteTo(m.Clipboard); err != nil {
+		return err
+	}
+	m.Message = fmt.Sprintf("%d bytes copied to the clipboard", len(text))
+	return nil
+}
+
+// yankMoves implements :[range]yank [x] for moves; the default range is the
+// current move.
+func (m *Model) yankMoves(from, to int, name string) error {
+	moves, err := m.Game.CopyMoves(from, to)
+	if err != nil {
+		return err
+	}
+	m.Message = fmt.Sprintf("%d nodes yanked", len(moves))
+	return m.store(name, &Register{Moves: moves, Text: game.LineSGF(m.Game.Board.Size, moves)}, false)
+}
+
+// listRegisters formats the registers for :registers, or only the given
+// ones.
+func (m *Model) listRegisters(names string) string {
+	if names == "" {
+		names = registerOrder
+	}
+	lines := []string{"Name  Content"}
+	for _, c := range names {
+		name := string(c)
+		r := m.Registers[strings.ToLower(name)]
+		if r == nil {
+			continue
+		}
+		summary := []rune(m.summary(r))
+		if len(summary) > maxSummary {
+			summary = append(summary[:maxSummary-1], '…')
+		}
+		lines = append(lines, fmt.Sprintf(`"%s    %s`, name, string(summary)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// summary describes a register on one line.
+func (m *Model) summary(r *Register) string {
+	if r.Moves == nil {
+		var rows []string
+		for y := 0; y < r.Pattern.Height; y++ {
+			var row strings.Builder
+			for x := 0; x < r.Pattern.Width; x++ {
+				switch r.Pattern.At(x, y) {
+				case board.Black:
+					row.WriteByte('X')
+				case board.White:
+					row.WriteByte('O')
+				default:
+					row.WriteByte('.')
+				}
+			}
+			rows = append(rows, row.String())
+		}
+		return fmt.Sprintf("%dx%d  %s", r.Pattern.Width, r.Pattern.Height, strings.Join(rows, "/"))
+	}
+
+	var moves []string
+	for _, n := range r.Moves {
+		if !n.IsMove() {
+			moves = append(moves, "setup")
+			continue
+		}
+		color := "B"
+		if n.Color == board.White {
+			color = "W"
+		}
+		point := "pass"
+		if n.Point != nil {
+			point = game.CoordinateToString(m.Game.Board.Size, n.Point.X, n.Point.Y)
+		}
+		moves = append(moves, color+" "+point)
+	}
+	return strings.Join(moves, ", ")
+}
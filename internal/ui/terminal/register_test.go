@@ -0,0 +1,111 @@
+package terminal
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func TestNamedRegisters(t *testing.T) {
+	m := NewModel(9)
+	m.Game.Move(4, 4)
+
+	m = press(t, m, `"`, "a", "y", "i", "g")
+	if m.Registers["a"] == nil || m.Registers[`"`] != m.Registers["a"] || m.Registers["0"] != nil {
+		t.Fatalf("expected the yank in a and the unnamed register only")
+	}
+	m = press(t, m, `"`, "_", "d", "i", "g")
+	if m.Registers[`"`] != m.Registers["a"] || m.Registers["1"] != nil {
+		t.Fatalf("expected the black hole register to keep the others")
+	}
+	m = press(t, m, "l", `"`, "a", "p")
+	if m.Game.Board.At(5, 4) != board.Black {
+		t.Fatalf("expected register a pasted at the cursor")
+	}
+	m = press(t, m, `"`, "q", "p")
+	if m.Error == nil || !strings.HasPrefix(m.Error.Error(), "E353") {
+		t.Fatalf("expected E353 for an empty register, got %v", m.Error)
+	}
+}
+
+func TestDeletionsShiftNumberedRegisters(t *testing.T) {
+	m := NewModel(9)
+	for x := 0; x < 6; x += 2 {
+		m.Game.Move(x, 0)
+		m.Game.Pass()
+	}
+	m.Handler.CursorY = 0
+	var deleted []*Register
+	for x := 0; x < 6; x += 2 {
+		m.Handler.CursorX = x
+		m = press(t, m, "d", "i", "g")
+		deleted = append(deleted, m.Registers["1"])
+	}
+	for i, r := range []*Register{deleted[2], deleted[1], deleted[0]} {
+		if name := string(rune('1' + i)); m.Registers[name] != r || r == nil {
+			t.Fatalf("expected deletion %d in register %s", 3-i, name)
+		}
+	}
+	if m.Registers[`"`] != deleted[2] {
+		t.Fatalf("expected the unnamed register to follow the last deletion")
+	}
+}
+
+func TestYankMovesAppends(t *testing.T) {
+	m := NewModel(9)
+	m.Game.Move(2, 2)
+	m.Game.Move(6, 6)
+	m.Game.Move(2, 6)
+
+	command(t, &m, "1,2yank b")
+	command(t, &m, "3yank B")
+	r := m.Registers["b"]
+	if r == nil || len(r.Moves) != 3 {
+		t.Fatalf("expected three moves in register b, got %+v", r)
+	}
+	command(t, &m, `registers b`)
+	if !strings.Contains(m.Message, `"b    B C7, W G3, B C3`) {
+		t.Fatalf("unexpected :registers listing %q", m.Message)
+	}
+
+	// Pasted at the start, the moves are already there.
+	m.Game.GoTo(m.Game.Root)
+	m = press(t, m, `"`, "b", "p")
+	if m.Error != nil || m.Game.Current.MoveNumber() != 3 || len(m.Game.Root.Children) != 1 {
+		t.Fatalf("expected the moves replayed on the same line, got %v", m.Error)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("closed") }
+
+func TestClipboardRegister(t *testing.T) {
+	m := NewModel(9)
+	m.Game.Move(4, 4)
+	var out bytes.Buffer
+	m.Clipboard = &out
+
+	m = press(t, m, `"`, "+", "y", "i", "g")
+	if m.Error != nil || !strings.HasPrefix(out.String(), "\x1b]52;c;") {
+		t.Fatalf("expected an OSC 52 sequence, got %q and %v", out.String(), m.Error)
+	}
+	out.Reset()
+	m.Tmux = true
+	m = press(t, m, `"`, "*", "y", "i", "g")
+	if !strings.HasPrefix(out.String(), "\x1bPtmux;") || !strings.Contains(out.String(), "52;p;") {
+		t.Fatalf("expected the primary selection wrapped for tmux, got %q", out.String())
+	}
+
+	m.Clipboard = failingWriter{}
+	m = press(t, m, `"`, "+", "d", "i", "g")
+	if m.Error == nil {
+		t.Fatalf("expected the clipboard error reported")
+	}
+	if m.Game.Board.At(4, 4) != board.Empty || m.Registers["+"] == nil {
+		t.Fatalf("expected the stones deleted and kept in the register all the same")
+	}
+}
@@ -0,0 +1,166 @@
+package terminal
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// Remote is a game played with others, such as a room of the web server.
+// A buffer bound to one sends the moves played at the end of its main line
+// to the remote instead of playing them, and follows the game the remote
+// sends back in RemoteUpdate messages.
+type Remote interface {
+	// Play asks to play a stone at p, or to pass when p is nil.
+	Play(p *board.Point) error
+	// Say sends a chat message to the others.
+	Say(text string) error
+}
+
+// RemoteUpdate carries the game of Remote, in SGF, after it changed.
+type RemoteUpdate struct {
+	Remote Remote
+	SGF    string
+}
+
+// RemoteChat carries Text said by From in Remote's chat. Without From it
+// is a notice from Remote itself.
+type RemoteChat struct {
+	Remote Remote
+	From   string
+	Text   string
+}
+
+// chatHistory is how many chat lines a buffer keeps for the side panel.
+const chatHistory = 100
+
+var errNotRemote = errors.New("not a game played with others")
+
+// OpenRemote shows the game of r, given in SGF, in a buffer called name.
+// Like :e, it takes over the untouched buffer of a fresh start.
+func (m *Model) OpenRemote(r Remote, name, content string) error {
+	g, err := game.Load(content)
+	if err != nil {
+		return err
+	}
+	if m.buf.Filename == "" && m.buf.Remote == nil && !m.Game.Modified() {
+		m.buf.Game, m.buf.Remote, m.buf.title = g, r, name
+		m.Game = g
+		m.Handler.BoardSize = g.Board.Size
+		m.Handler.CursorX, m.Handler.CursorY = g.Board.Size/2, g.Board.Size/2
+		m.leaveScoring()
+		return nil
+	}
+	b := m.addBuffer(g, "")
+	b.Remote, b.title = r, name
+	m.switchTo(b)
+	return nil
+}
+
+// play plays a stone at p, or passes when p is nil. At the end of a remote
+// game the move goes to the remote, which reports it back once played;
+// anywhere else it starts a local variation.
+func (m *Model) play(p *board.Point) error {
+	if m.buf.Remote != nil && isLive(m.Game) {
+		return m.buf.Remote.Play(p)
+	}
+	if p == nil {
+		m.Game.Pass()
+		return nil
+	}
+	return m.Game.Move(p.X, p.Y)
+}
+
+// say implements :say, sending text to the chat of the current buffer's
+// remote.
+func (m *Model) say(text string) error {
+	if m.buf.Remote == nil {
+		return errNotRemote
+	}
+	if text == "" {
+		return errors.New("E471: Argument required")
+	}
+	return m.buf.Remote.Say(text)
+}
+
+// remoteChat adds a chat line to the buffers bound to c.Remote. Without
+// the side panel to show it, it goes to the message line.
+func (m *Model) remoteChat(c RemoteChat) {
+	line := c.Text
+	if c.From != "" {
+		line = c.From + ": " + c.Text
+	}
+	for _, b := range m.Buffers {
+		if b.Remote != c.Remote {
+			continue
+		}
+		b.chat = append(b.chat, line)
+		if len(b.chat) > chatHistory {
+			b.chat = b.chat[len(b.chat)-chatHistory:]
+		}
+	}
+	if m.buf.Remote == c.Remote && !m.showPanel() {
+		m.Message = line
+	}
+}
+
+// remoteUpdate has the buffers bound to u.Remote follow its game, keeping
+// their local variations. Windows at the end of the old game follow to the
+// new end; others stay on their node unless it was taken back.
+func (m *Model) remoteUpdate(u RemoteUpdate) error {
+	g, err := game.Load(u.SGF)
+	if err != nil {
+		return err
+	}
+	m.syncWindow()
+	for _, b := range m.Buffers {
+		if b.Remote != u.Remote {
+			continue
+		}
+		var live []*Window
+		for _, w := range m.layout.windows() {
+			if w.Buffer == b && (w.Node == nil || isLiveNode(w.Node)) {
+				live = append(live, w)
+			}
+		}
+		if err := b.Game.Follow(g); err != nil {
+			return err
+		}
+		line := b.Game.Root.Line()
+		for _, w := range m.layout.windows() {
+			if w.Buffer != b {
+				continue
+			}
+			if _, err := b.Game.At(w.Node); err != nil || slices.Contains(live, w) {
+				w.Node = line[len(line)-1]
+			}
+		}
+		if b == m.buf {
+			m.leaveScoring()
+			if err := m.Game.GoTo(m.win.Node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isLive reports whether g is at the end of its main line, where a remote
+// game goes on.
+func isLive(g *game.Game) bool {
+	return isLiveNode(g.Current)
+}
+
+func isLiveNode(n *game.Node) bool {
+	if len(n.Children) > 0 {
+		return false
+	}
+	for ; n.Parent != nil; n = n.Parent {
+		if n.Parent.Children[0] != n {
+			return false
+		}
+	}
+	return true
+}
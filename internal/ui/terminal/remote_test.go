@@ -0,0 +1,67 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// fakeRemote plays the moves sent to it on its own game.
+type fakeRemote struct {
+	g *game.Game
+}
+
+func (r *fakeRemote) Play(p *board.Point) error {
+	if p == nil {
+		r.g.Pass()
+		return nil
+	}
+	return r.g.Move(p.X, p.Y)
+}
+
+func (r *fakeRemote) Say(string) error { return nil }
+
+func TestRemoteUpdateKeepsLocalVariations(t *testing.T) {
+	r := &fakeRemote{g: game.NewGame(9)}
+	m := NewModel(9)
+	if err := m.OpenRemote(r, "room", r.g.SGF()); err != nil {
+		t.Fatalf("OpenRemote failed: %v", err)
+	}
+	update := func() {
+		t.Helper()
+		if err := m.remoteUpdate(RemoteUpdate{Remote: r, SGF: r.g.SGF()}); err != nil {
+			t.Fatalf("remoteUpdate failed: %v", err)
+		}
+	}
+
+	// At the end of the game a move goes to the remote.
+	m.play(&board.Point{X: 2, Y: 2})
+	if m.Game.Current != m.Game.Root {
+		t.Fatalf("expected the move sent, not played")
+	}
+	update()
+	r.g.Move(6, 6)
+	update()
+	if m.Game.Board.At(6, 6) != board.White {
+		t.Fatalf("expected the window at the end to follow the game")
+	}
+
+	// Back at move 1, a move starts a local variation, which the next
+	// remote move leaves alone.
+	m.Game.GoToMove(1)
+	m.syncWindow()
+	if err := m.play(&board.Point{X: 5, Y: 5}); err != nil {
+		t.Fatalf("local move failed: %v", err)
+	}
+	variation := m.Game.Current
+	r.g.Move(3, 3)
+	update()
+	if m.Game.Current != variation || m.Game.Board.At(5, 5) != board.White {
+		t.Fatalf("expected to stay on the local variation")
+	}
+	m.Game.GoTo(m.Game.Root)
+	if m.Game.GoToMove(3) != nil || m.Game.Board.At(3, 3) != board.Black {
+		t.Fatalf("expected the remote move on the main line")
+	}
+}
@@ -0,0 +1,73 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/vimgo/vimgo/internal/board"
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// search carries out a / or ? search, or repeats the last one with n (same
+// direction) or N (opposite direction), count times. The cursor goes to the
+// first highlighted point of the match.
+func (m *Model) search(value string, count int) error {
+	backward := m.searchBackward
+	switch value {
+	case "n", "N":
+		if value == "N" {
+			backward = !backward
+		}
+	default:
+		// An empty pattern repeats the last one in the new direction.
+		backward = value[0] == '?'
+		m.searchBackward = backward
+		if value[1:] != "" {
+			m.lastSearch = value[1:]
+		}
+	}
+	q, err := game.ParseQuery(m.lastSearch, m.Game.Board.Size)
+	if err != nil {
+		return err
+	}
+
+	prompt := "/"
+	if backward {
+		prompt = "?"
+	}
+	m.Message = prompt + m.lastSearch
+	m.hlSearch = true
+	for i := 0; i < max(count, 1); i++ {
+		n, wrapped := m.Game.Search(q, backward)
+		if n == nil {
+			return fmt.Errorf("E486: Pattern not found: %s", m.lastSearch)
+		}
+		m.leaveScoring()
+		if err := m.Game.GoTo(n); err != nil {
+			return err
+		}
+		if wrapped {
+			m.Message = "search hit BOTTOM, continuing at TOP"
+			if backward {
+				m.Message = "search hit TOP, continuing at BOTTOM"
+			}
+		}
+	}
+	if points := m.searchMatches(m.Game); len(points) > 0 {
+		m.Handler.CursorX, m.Handler.CursorY = points[0].X, points[0].Y
+	}
+	return nil
+}
+
+// searchMatches returns the points to highlight for the last search in g's
+// current position.
+func (m *Model) searchMatches(g *game.Game) []board.Point {
+	if !m.hlSearch || m.lastSearch == "" {
+		return nil
+	}
+	q, err := game.ParseQuery(m.lastSearch, g.Board.Size)
+	if err != nil {
+		return nil
+	}
+	points, _ := q.Match(g.Current, g.Board)
+	return points
+}
@@ -2,70 +2,134 @@ package terminal
 
 import (
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
-	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 	"github.com/vimgo/vimgo/internal/board"
 	"github.com/vimgo/vimgo/internal/game"
 	"github.com/vimgo/vimgo/internal/rules"
-	"github.com/vimgo/vimgo/internal/sgf"
 	"github.com/vimgo/vimgo/internal/vim"
 )
 
-var (
-	boardStyle = lipgloss.NewStyle().
-			Padding(1).
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("63"))
-
-	statusBarStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("15")).
-			Background(lipgloss.Color("63")).
-			Padding(0, 1)
-
-	blackStone = "●"
-	whiteStone = "○"
-	// Box drawing characters
-	topLeft     = "┌"
-	topRight    = "┐"
-	bottomLeft  = "└"
-	bottomRight = "┘"
-	horizontal  = "─"
-	vertical    = "│"
-	intersection = "┼"
-	teeLeft     = "├"
-	teeRight    = "┤"
-	teeTop      = "┬"
-	teeBottom   = "┴"
-	starPoint   = "╋"
-)
-
-var (
-	gridColor = lipgloss.Color("240") // Subtle grey/blue
-	starColor = lipgloss.Color("214") // Orange/Yellow
-)
-
 type Model struct {
+	// Game is the game of the current buffer.
 	Game    *game.Game
 	Handler *vim.Handler
-	Error   error
+	// Buffers are the open games, in the order they were opened.
+	Buffers []*Buffer
+	buf     *Buffer
+	// alt is the alternate buffer for :e# and Ctrl-^.
+	alt        *Buffer
+	lastBufNum int
+	// layout arranges the windows opened with :split and :vsplit; win is
+	// the focused one.
+	layout     *split
+	win        *Window
+	Error      error
 	Width      int
 	Height     int
 	ShowCoords bool
 	ShowHelp   bool
-	ScoreText  string
+	// ScrollBind keeps all windows on the same move number.
+	ScrollBind bool
+	// Komi and Rules are used by :score.
+	Komi  float64
+	Rules string
+	// Theme names the color scheme set with :colorscheme; see LoadTheme.
+	Theme string
+	// Profile is the terminal's color profile the theme is adapted to,
+	// lipgloss's when NewModel made the model.
+	Profile termenv.Profile
+	// scheme is the theme and st its styles for Profile.
+	scheme *Theme
+	st     styles
+	// Stones are the glyphs for black and white stones. They come from
+	// the theme and :set stones changes them.
+	Stones [2]string
+	// Ownership marks empty points with the color that owns them.
+	Ownership bool
+	// AutoSave writes the buffer's file after every change.
+	AutoSave bool
+	// rcFile is the configuration file read at startup, for :source.
+	rcFile string
+	// lastSearch is the text of the last / or ? search, highlighted on the
+	// board while hlSearch is set.
+	lastSearch     string
+	searchBackward bool
+	hlSearch       bool
+	ScoreText      string
+	// Scoring is the scoring phase entered with :score. Dead holds the
+	// stones marked dead with the m operator.
+	Scoring     bool
+	ScoreMethod string
+	Dead        map[board.Point]bool
+	// Message is informational feedback from the last command, shown
+	// where errors are.
+	Message string
+	// Registers hold yanked regions and moves by name; see store.
+	Registers map[string]*Register
+	// Numbers shows move numbers on the stones.
+	Numbers bool
+	// Density is the board's density: full, compact, half or auto, which
+	// picks the largest that fits.
+	Density string
+	// Panel shows the side panel with the players, clocks and comment;
+	// commentScroll is how far its comment of commentNode is scrolled.
+	Panel         bool
+	commentScroll int
+	commentNode   *game.Node
+	// flash holds the stones the last move captured while they are shown;
+	// flashID tells the timer that clears them from later ones.
+	flash   []board.Point
+	flashID int
+	// lastClick is the last mouse click, for double-clicks.
+	lastClick click
+	// TimeoutLen is how long keys wait to become a longer mapping, like
+	// Vim's timeoutlen; typeaheadID tells the timer from later ones.
+	TimeoutLen  time.Duration
+	typeaheadID int
+	// Clipboard receives OSC 52 sequences for the "+ and "* registers;
+	// nil disables them. Tmux wraps them for a terminal running tmux,
+	// which passes them on.
+	Clipboard io.Writer
+	Tmux      bool
+	// NoFiles keeps the session off the files of the host, for players
+	// who are not its users: commands reading or writing a file fail and
+	// Tab completes no file names. Set it before the program starts.
+	NoFiles bool
 }
 
 func NewModel(size int) Model {
-	return Model{
-		Game:    game.NewGame(size),
-		Handler: vim.NewHandler(size),
+	m := Model{
+		Handler:    newHandler(size),
+		Komi:       7.5,
+		Rules:      "chinese",
+		Profile:    lipgloss.ColorProfile(),
+		Density:    "auto",
+		Panel:      true,
+		TimeoutLen: time.Second,
+		Registers:  make(map[string]*Register),
 	}
+	m.switchTo(m.addBuffer(game.NewGame(size), ""))
+	m.alt = nil
+	m.win = &Window{Buffer: m.buf}
+	m.layout = &split{win: m.win}
+	m.setTheme(&defaultTheme)
+	return m
 }
 
+// Init takes up the settings made after NewModel.
 func (m Model) Init() tea.Cmd {
+	if m.NoFiles {
+		m.Handler.Complete = func(line string) (int, []string) {
+			return filelessCommands.Complete(line, optionNames())
+		}
+	}
 	return nil
 }
 
@@ -74,331 +138,408 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.Width = msg.Width
 		m.Height = msg.Height
+	case RemoteUpdate:
+		m.Error = m.remoteUpdate(msg)
+	case RemoteChat:
+		m.remoteChat(msg)
+	case flashDone:
+		if msg.ID == m.flashID {
+			m.flash = nil
+		}
+	case typeaheadTimeout:
+		if msg.ID != m.typeaheadID || !m.Handler.Waiting() {
+			break
+		}
+		before := m.Game.Current
+		for _, action := range m.Handler.Flush() {
+			if cmd := m.apply(action); cmd != nil {
+				return m, cmd
+			}
+		}
+		m.afterUpdate()
+		return m, m.flashCaptures(before)
 	case tea.KeyMsg:
 		key := msg.String()
-		
-		// Map bubbletea keys to our handler strings
-		switch key {
-		case "up": key = "k"
-		case "down": key = "j"
-		case "left": key = "h"
-		case "right": key = "l"
-		case "backspace": key = "backspace"
-		case "esc": key = "esc"
-		case "enter": key = "enter"
+		before := m.Game.Current
+
+		// Map bubbletea keys to our handler strings. The command line
+		// uses the arrow keys itself.
+		if m.Handler.Mode != vim.Command {
+			switch key {
+			case "up":
+				key = "k"
+			case "down":
+				key = "j"
+			case "left":
+				key = "h"
+			case "right":
+				key = "l"
+			}
 		}
 
-		action := m.Handler.HandleKey(key)
-		if action != nil {
-			switch action.Type {
-			case vim.ActionPlaceStone:
-				m.Error = m.Game.Move(m.Handler.CursorX, m.Handler.CursorY)
-			case vim.ActionUndo:
-				m.Error = m.Game.Undo()
-			case vim.ActionCommand:
-				return m.handleCommand(action.Value)
+		for _, action := range m.Handler.Feed(key) {
+			if cmd := m.apply(action); cmd != nil {
+				return m, cmd
 			}
 		}
+		m.afterUpdate()
 
 		if key == "ctrl+c" {
 			return m, tea.Quit
 		}
+		return m, tea.Batch(m.flashCaptures(before), m.waitForMapping())
+	case tea.MouseMsg:
+		before := m.Game.Current
+		m.handleMouse(msg)
+		m.afterUpdate()
+		return m, m.flashCaptures(before)
 	}
 
 	return m, nil
 }
 
-func (m Model) handleCommand(cmd string) (tea.Model, tea.Cmd) {
-	m.Error = nil
-	parts := strings.Fields(cmd)
-	if len(parts) == 0 {
-		return m, nil
-	}
-
-	switch parts[0] {
-	case "q", "quit":
-		if m.ShowHelp {
-			m.ShowHelp = false
-			return m, nil
-		}
-		return m, tea.Quit
-	case "e", "edit":
-		filename := "game.sgf"
-		if len(parts) > 1 {
-			filename = parts[1]
-		}
-		err := m.loadSGF(filename)
-		if err != nil {
-			m.Error = err
-		}
-	case "w", "write":
-		filename := "game.sgf"
-		if len(parts) > 1 {
-			filename = parts[1]
-		}
-		err := m.saveSGF(filename)
-		if err != nil {
-			m.Error = err
-		}
-	case "undo":
+// apply carries out an action of the key handler.
+func (m *Model) apply(action *vim.Action) tea.Cmd {
+	switch action.Type {
+	case vim.ActionPlaceStone:
+		m.leaveScoring()
+		m.Error = m.play(&board.Point{X: m.Handler.CursorX, Y: m.Handler.CursorY})
+	case vim.ActionUndo:
+		m.leaveScoring()
 		m.Error = m.Game.Undo()
-	case "pass":
-		m.Game.CurrentPlayer = m.Game.CurrentPlayer.Opposite()
-	case "coords", "coordinates", "c":
-		m.ShowCoords = !m.ShowCoords
-	case "?", "help":
-		m.ShowHelp = true
-	case "score":
-		method := "chinese"
-		if len(parts) > 1 {
-			method = parts[1]
+	case vim.ActionOperator:
+		m.Error = m.applyOperator(action)
+	case vim.ActionPaste:
+		m.leaveScoring()
+		m.Error = m.paste(action.Register)
+	case vim.ActionSetupStone:
+		m.leaveScoring()
+		m.Error = m.setupStone(action.Value)
+	case vim.ActionTogglePlayer:
+		m.Game.TogglePlayer()
+	case vim.ActionSearch:
+		m.Error = m.search(action.Value, action.Count)
+	case vim.ActionScroll:
+		n := max(action.Count, 1)
+		if action.Value == "up" {
+			n = -n
 		}
-		// Basic komi 7.5 for Chinese, 6.5 for Japanese? Let's assume 7.5 for now or 0.
-		// The user didn't specify komi, let's use 7.5
-		score := rules.CountScore(m.Game.Board, method, m.Game.BlackCaptures, m.Game.WhiteCaptures, 7.5)
-		m.ScoreText = fmt.Sprintf("[W %.1f B %.1f]", score.White, score.Black)
-	default:
-		m.Error = fmt.Errorf("unknown command: %s", parts[0])
+		m.scrollComment(n)
+	case vim.ActionCommand:
+		return m.handleCommand(action.Value)
 	}
-	return m, nil
+	return nil
 }
 
-func (m Model) saveSGF(filename string) error {
-	content := sgf.SimpleSGFWriter(m.Game.Board.Size, m.Game.Moves)
-	return os.WriteFile(filename, []byte(content), 0644)
-}
+// flashTime is how long captured stones stay visible.
+const flashTime = 300 * time.Millisecond
 
-func (m *Model) loadSGF(filename string) error {
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		return err
+// flashDone clears the captured stones of flash number ID.
+type flashDone struct{ ID int }
+
+// flashCaptures shows the stones captured by stepping from node before to
+// its child, and returns the command that hides them again.
+func (m *Model) flashCaptures(before *game.Node) tea.Cmd {
+	if m.Game.Current.Parent != before {
+		return nil
 	}
-	size, moves, err := sgf.ParseSGF(string(content))
-	if err != nil {
-		return err
+	captured := m.Game.Captured()
+	if len(captured) == 0 {
+		return nil
 	}
+	m.flashID++
+	m.flash = captured
+	id := m.flashID
+	return tea.Tick(flashTime, func(time.Time) tea.Msg { return flashDone{ID: id} })
+}
 
-	// Initialize new game
-	newGame := game.NewGame(size)
-	
-	// Replay moves
-	for _, moveStr := range moves {
-		// Parse move string like "B[pd]"
-		if len(moveStr) < 4 || moveStr[1] != '[' || moveStr[len(moveStr)-1] != ']' {
-			continue // Skip malformed
-		}
-		color := string(moveStr[0])
-		val := moveStr[2 : len(moveStr)-1]
-		
-		// Handle pass
-		if val == "" {
-			if color == "B" {
-				newGame.CurrentPlayer = board.Black
-			} else {
-				newGame.CurrentPlayer = board.White
-			}
-			newGame.CurrentPlayer = newGame.CurrentPlayer.Opposite()
-			continue
-		}
+// typeaheadTimeout flushes the keys waiting for a longer mapping when no
+// key came since timer number ID started.
+type typeaheadTimeout struct{ ID int }
 
-		x, y, err := sgf.FromSGFCoord(val)
-		if err != nil {
-			return err
-		}
+// waitForMapping returns the command that flushes the keys waiting for a
+// longer mapping after TimeoutLen, if there are any.
+func (m *Model) waitForMapping() tea.Cmd {
+	if !m.Handler.Waiting() {
+		return nil
+	}
+	m.typeaheadID++
+	id := m.typeaheadID
+	return tea.Tick(m.TimeoutLen, func(time.Time) tea.Msg { return typeaheadTimeout{ID: id} })
+}
 
-		// Set correct player for move
-		if color == "B" {
-			newGame.CurrentPlayer = board.Black
-		} else {
-			newGame.CurrentPlayer = board.White
+// afterUpdate keeps bound windows in step and writes the file when
+// autosave is set.
+func (m *Model) afterUpdate() {
+	m.scrollBind()
+	if m.AutoSave && m.Game.Modified() && m.buf.Filename != "" {
+		if err := m.saveSGF(m.buf.Filename); err != nil {
+			m.Error = err
+			return
 		}
+		m.Game.MarkSaved()
+	}
+}
 
-		if err := newGame.Move(x, y); err != nil {
-			return fmt.Errorf("failed to replay move %s: %v", moveStr, err)
+// paste puts a register at the cursor: a pattern with its top-left corner
+// there, parts that fall off the board dropped; moves are played from the
+// current node as a variation.
+func (m *Model) paste(name string) error {
+	r, err := m.register(name)
+	if err != nil {
+		return err
+	}
+	if r.Moves != nil {
+		return m.Game.Graft(r.Moves)
+	}
+	changes := make(map[board.Point]board.Color)
+	for y := 0; y < r.Pattern.Height; y++ {
+		for x := 0; x < r.Pattern.Width; x++ {
+			p := board.Point{X: m.Handler.CursorX + x, Y: m.Handler.CursorY + y}
+			changes[p] = r.Pattern.At(x, y)
 		}
 	}
-
-	m.Game = newGame
-	m.Handler = vim.NewHandler(size)
-	return nil
+	return m.Game.Edit(changes)
 }
 
-func (m Model) View() string {
-	if m.Width == 0 {
-		return "Initializing..."
+// setupStone handles the Insert mode editing keys: "B" and "W" toggle a
+// stone of that color at the cursor, "E" erases.
+func (m *Model) setupStone(value string) error {
+	p := board.Point{X: m.Handler.CursorX, Y: m.Handler.CursorY}
+	switch value {
+	case "B":
+		return m.Game.ToggleStone(p, board.Black)
+	case "W":
+		return m.Game.ToggleStone(p, board.White)
+	default:
+		return m.Game.Edit(map[board.Point]board.Color{p: board.Empty})
 	}
+}
 
-	var s strings.Builder
-
-	// Header
-	header := lipgloss.NewStyle().Bold(true).Render("VimGo - Go with Vim keybindings")
-	s.WriteString(lipgloss.NewStyle().Width(m.Width).Align(lipgloss.Center).Render(header))
-	s.WriteString("\n")
-
-	// Render board content
-	var boardView strings.Builder
-	size := m.Game.Board.Size
-	// Helper for coordinates
-	getCoordChar := func(i int) string {
-		if i >= 8 { i++ } // Skip 'I'
-		return string(rune('A' + i))
-	}
-	
-	coordStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-
-	// Top Coordinates
-	if m.ShowCoords {
-		boardView.WriteString("   ") // Space for left numbers
-		for x := 0; x < size; x++ {
-			boardView.WriteString(coordStyle.Render(getCoordChar(x)))
-			if x < size-1 {
-				boardView.WriteString("   ")
+// ownership maps every empty point in a territory to its owner.
+func ownership(b *board.Board) map[board.Point]board.Color {
+	owners := make(map[board.Point]board.Color)
+	seen := make(map[board.Point]bool)
+	for y := 0; y < b.Size; y++ {
+		for x := 0; x < b.Size; x++ {
+			if seen[board.Point{X: x, Y: y}] {
+				continue
+			}
+			points, owner := rules.TerritoryAt(b, x, y)
+			for _, p := range points {
+				seen[p] = true
+				owners[p] = owner
 			}
 		}
-		boardView.WriteString("\n")
 	}
+	return owners
+}
 
-	for y := 0; y < size; y++ {
-		// Left Coordinate
-		if m.ShowCoords {
-			num := fmt.Sprintf("%2d ", size-y)
-			boardView.WriteString(coordStyle.Render(num))
-		}
+// stoneGlyph returns the glyph for a stone of color c.
+func (m Model) stoneGlyph(c board.Color) string {
+	if c == board.White {
+		return m.Stones[1]
+	}
+	return m.Stones[0]
+}
 
-		for x := 0; x < size; x++ {
-			// Determine grid character based on position
-			char := intersection
-			if y == 0 {
-				if x == 0 { char = topLeft
-				} else if x == size-1 { char = topRight
-				} else { char = teeTop }
-			} else if y == size-1 {
-				if x == 0 { char = bottomLeft
-				} else if x == size-1 { char = bottomRight
-				} else { char = teeBottom }
-			} else {
-				if x == 0 { char = teeLeft
-				} else if x == size-1 { char = teeRight }
-			}
+// moveLabel is the move number shown on a stone. As in printed game
+// records with a diagram per hundred moves, only the last two digits are
+// shown from move 100 on.
+func moveLabel(k int) string {
+	if k >= 100 {
+		return fmt.Sprintf("%02d", k%100)
+	}
+	return strconv.Itoa(k)
+}
 
-			// Star points (Hoshi)
-			isStar := false
-			if size == 19 {
-				isStar = (x == 3 || x == 9 || x == 15) && (y == 3 || y == 9 || y == 15)
-			}
-			if size == 13 {
-				isStar = (x == 3 || x == 6 || x == 9) && (y == 3 || y == 6 || y == 9)
-			}
-			if size == 9 {
-				isStar = (x == 2 || x == 6) && (y == 2 || y == 6) || (x == 4 && y == 4)
-			}
-			if isStar {
-				char = starPoint
-			}
+// renderBoard draws w's board at the density that fits. Only the focused
+// window shows the selection and dead stones; the cursor of the others is
+// dimmed.
+func (m Model) renderBoard(w *Window) string {
+	g := m.windowGame(w)
+	focused := w == m.win
+	mk := boardMarks{
+		focused: focused,
+		cursor:  board.Point{X: w.CursorX, Y: w.CursorY},
+		matches: make(map[board.Point]bool),
+		labels:  make(map[board.Point]string),
+		ghosts:  make(map[board.Point]board.Color),
+		flash:   make(map[board.Point]bool),
+		last:    g.LastMove,
+	}
+	if focused {
+		mk.cursor = board.Point{X: m.Handler.CursorX, Y: m.Handler.CursorY}
+		mk.selection = m.visualSelection()
+		mk.dead = m.Dead
+		for _, p := range m.flash {
+			mk.flash[p] = true
+		}
+	}
+	for _, p := range m.searchMatches(g) {
+		mk.matches[p] = true
+	}
+	if m.Ownership {
+		b := g.Board
+		if focused && m.Scoring {
+			b, _, _ = rules.RemoveDead(b, m.Dead)
+		}
+		mk.owners = ownership(b)
+	}
+	if m.Numbers {
+		for p, k := range g.MoveNumbers() {
+			mk.labels[p] = moveLabel(k)
+		}
+	}
+	for _, child := range g.Variations() {
+		mk.ghosts[*child.Point] = child.Color
+	}
 
-			c := m.Game.Board.At(x, y)
-			cellContent := ""
-			if c == board.Black {
-				cellContent = blackStone
-			} else if c == board.White {
-				cellContent = whiteStone
-			} else {
-				// Render grid character with color
-				style := lipgloss.NewStyle().Foreground(gridColor)
-				if isStar {
-					style = style.Foreground(starColor)
-				}
-				cellContent = style.Render(char)
-			}
+	d, view := m.boardLayout(w, g.Board.Size)
+	captured := g.Current.Color.Opposite()
+	var lines []string
+	if d == densityHalf {
+		lines = m.halfBlockLines(g.Board, view, mk, captured)
+	} else {
+		lines = m.gridLines(g.Board, view, d, mk, captured)
+	}
 
-			// Cursor logic
-			if m.Handler.CursorX == x && m.Handler.CursorY == y {
-				style := lipgloss.NewStyle().Background(lipgloss.Color("201")).Foreground(lipgloss.Color("15"))
-				boardView.WriteString(style.Render(cellContent))
-			} else {
-				boardView.WriteString(cellContent)
-			}
+	style := m.st.box.Padding(d.padding(), 1)
+	if !focused {
+		style = style.BorderForeground(m.st.window.GetBackground())
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
 
-			// Horizontal connection line
-			if x < size-1 {
-				lineChar := horizontal + horizontal + horizontal
-				style := lipgloss.NewStyle().Foreground(gridColor)
-				boardView.WriteString(style.Render(lineChar))
-			}
+// renderLayout draws the windows of s side by side or stacked. With more
+// than one window each gets a status line with its buffer and move.
+func (m Model) renderLayout(s *split) string {
+	if s.win == nil {
+		var parts []string
+		for _, c := range s.children {
+			parts = append(parts, m.renderLayout(c))
 		}
-		
-		// Right Coordinate
-		if m.ShowCoords {
-			num := fmt.Sprintf(" %-2d", size-y)
-			boardView.WriteString(coordStyle.Render(num))
+		if s.vertical {
+			return lipgloss.JoinHorizontal(lipgloss.Top, parts...)
 		}
+		return lipgloss.JoinVertical(lipgloss.Left, parts...)
+	}
 
-		boardView.WriteString("\n")
-
-		// Vertical connection row (for square look)
-		if y < size-1 {
-			// Left spacer for coords
-			if m.ShowCoords {
-				boardView.WriteString("   ")
-			}
+	view := m.renderBoard(s.win)
+	if m.layout.win != nil {
+		return view
+	}
+	g := m.windowGame(s.win)
+	// The focused window shows the current buffer, which :b and the like
+	// change without going through the window.
+	buf := s.win.Buffer
+	if s.win == m.win {
+		buf = m.buf
+	}
+	name := buf.Name()
+	if buf.Game.Modified() {
+		name += " [+]"
+	}
+	status := fmt.Sprintf("%s  move %d", name, g.Current.MoveNumber())
+	style := m.st.window.Width(lipgloss.Width(view))
+	if s.win == m.win {
+		style = m.st.status.Width(lipgloss.Width(view))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, view, style.Render(status))
+}
 
-			for x := 0; x < size; x++ {
-				style := lipgloss.NewStyle().Foreground(gridColor)
-				boardView.WriteString(style.Render(vertical))
-				if x < size-1 {
-					boardView.WriteString("   ") // Spacing between vertical lines
-				}
-			}
-			boardView.WriteString("\n")
-		}
+// boardHeight is the height of the area the boards are centered in, below
+// the header. Multi-line messages such as :ls take room from it.
+func (m Model) boardHeight() int {
+	messageLines := strings.Count(m.Message, "\n")
+	if m.Error != nil {
+		messageLines = strings.Count(m.Error.Error(), "\n")
 	}
+	return max(0, m.Height-4-messageLines)
+}
 
-	// Bottom Coordinates
-	if m.ShowCoords {
-		boardView.WriteString("   ") // Space for left numbers
-		for x := 0; x < size; x++ {
-			boardView.WriteString(coordStyle.Render(getCoordChar(x)))
-			if x < size-1 {
-				boardView.WriteString("   ")
-			}
-		}
-		boardView.WriteString("\n")
+func (m Model) View() string {
+	if m.Width == 0 {
+		return "Initializing..."
 	}
 
+	var s strings.Builder
+
+	// Header
+	header := lipgloss.NewStyle().Bold(true).Render("VimGo - Go with Vim keybindings")
+	s.WriteString(lipgloss.NewStyle().Width(m.Width).MaxHeight(1).Align(lipgloss.Center).Render(header))
+	s.WriteString("\n")
+
 	// Center the board
-	styledBoard := boardStyle.Render(boardView.String())
+	styledBoard := m.renderMain()
 
 	if m.ShowHelp {
 		helpText := "\n  VimGo Help\n\n"
 		helpText += "  hjkl    Move cursor\n"
 		helpText += "  x       Place stone\n"
 		helpText += "  u       Undo\n"
-		helpText += "  i       Insert Mode\n"
-		helpText += "  :w      Save (game.sgf)\n"
+		helpText += "  i       Insert Mode (edit position)\n"
+		helpText += "   b/w    Toggle black/white stone\n"
+		helpText += "   e      Erase stone\n"
+		helpText += "   t      Toggle player to move\n"
+		helpText += "   v      Select stones to clear (d)\n"
+		helpText += "  v       Visual Mode (y selection)\n"
+		helpText += "  /x ?x   Search Q16, comment or [XO|.X]\n"
+		helpText += "  n N     Next/previous match\n"
+		helpText += "  p       Paste yanked region or moves\n"
+		helpText += "  \"x      Use register x (\"+ clipboard)\n"
+		helpText += "  d/y/c/m Operators + motion or object\n"
+		helpText += "   ig ag  Group / group and liberties\n"
+		helpText += "   it at  Territory / with its border\n"
+		helpText += "  :w [f]  Save (game.sgf)\n"
+		helpText += "  :wq :x  Save and quit (also ZZ)\n"
+		helpText += "  :q!     Quit without saving (also ZQ)\n"
+		helpText += "  :N      Go to move N\n"
+		helpText += "  :N,Md   Delete moves N-M\n"
+		helpText += "  :set    Show or change options\n"
+		helpText += "  :set numbers  Move numbers on stones\n"
+		helpText += "  :set density=compact  Smaller board\n"
+		helpText += "  :set nopanel  Hide the side panel\n"
+		helpText += "  ^E ^Y   Scroll the comment\n"
+		helpText += "  :say x  Chat in a room\n"
+		helpText += "  :colo X Color scheme (gba, light, ascii)\n"
+		helpText += "  :nnoremap lhs rhs  Map keys (~/.vimgorc)\n"
 		helpText += "  :c      Toggle Coords\n"
-		helpText += "  :e [f]  Load SGF\n"
-		helpText += "  :score  [chinese|japanese]\n"
+		helpText += "  :e [f]  Load SGF (:e# alternate)\n"
+		helpText += "  :ls     List buffers\n"
+		helpText += "  :bn :bp Next/previous buffer (:b N)\n"
+		helpText += "  :bd     Close buffer\n"
+		helpText += "  :sp :vs Split window (^W hjkl moves)\n"
+		helpText += "  :score  [chinese|japanese|off]\n"
+		helpText += "  :'<,'>count   Region stats\n"
+		helpText += "  :'<,'>export  Region diagram\n"
+		helpText += "  :N,My x  Yank moves (:reg lists)\n"
+		helpText += "  Mouse   Click moves, double-click plays\n"
+		helpText += "   wheel  Step back/forward through moves\n"
 		helpText += "  :?      Show Help\n"
+		helpText += "  a | b   Chain commands, Tab completes\n"
 		helpText += "  :q      Quit / Close Help\n"
-		
+
 		helpBox := lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("63")).
+			Border(borders[m.st.glyphs.Border]).
+			BorderForeground(m.st.box.GetBorderTopForeground()).
 			Padding(1, 2).
 			Render(helpText)
-		
+
 		// Overlay help box or replace board? Let's replace for now as overlay is tricky with lipgloss text only
 		// Actually lipgloss.Place effectively centers, so we can just swap what we center.
 		styledBoard = helpBox
 	}
 
-	centeredBoard := lipgloss.Place(m.Width, m.Height-4, lipgloss.Center, lipgloss.Center, styledBoard)
+	centeredBoard := lipgloss.Place(m.Width, m.boardHeight(), lipgloss.Center, lipgloss.Center, styledBoard)
 	s.WriteString(centeredBoard)
 
 	// Error message area
 	if m.Error != nil {
-		errorText := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(fmt.Sprintf("Error: %v", m.Error))
+		errorText := m.st.error.Render(fmt.Sprintf("Error: %v", m.Error))
 		s.WriteString("\n" + errorText)
+	} else if m.Message != "" {
+		s.WriteString("\n" + m.Message)
 	}
 
 	// Status bar at the bottom
@@ -408,15 +549,33 @@ func (m Model) View() string {
 	if m.Game.CurrentPlayer == board.White {
 		turn = "White"
 	}
-	
-	statusText := fmt.Sprintf(" -- %s -- %dx%d -- %s -- Turn: %d -- [%s] -- %s", 
-		modeStr, size, size, turn, len(m.Game.History)+1, coord, m.ScoreText)
-	
+
+	name := m.buf.Name()
+	if m.Game.Modified() {
+		name += " *"
+	}
+
+	statusText := fmt.Sprintf(" -- %s -- %s -- %dx%d -- %s -- Turn: %d -- [%s] -- %s",
+		modeStr, name, m.Game.Board.Size, m.Game.Board.Size, turn, m.Game.Current.MoveNumber()+1, coord, m.ScoreText)
+
+	if pending := m.Handler.Pending(); pending != "" {
+		statusText += " -- " + pending
+	}
+	if m.Handler.Mode == vim.Visual {
+		sel := m.Handler.Selection()
+		statusText += fmt.Sprintf(" -- %dx%d", sel.Width(), sel.Height())
+	}
 	if m.Handler.Mode == vim.Command {
-		statusText = ":" + m.Handler.CommandBuffer
+		before, after := m.Handler.CommandLine()
+		at := " "
+		if after != "" {
+			r := []rune(after)
+			at, after = string(r[0]), string(r[1:])
+		}
+		statusText = m.Handler.CommandType + before + lipgloss.NewStyle().Reverse(true).Render(at) + after
 	}
 
 	// Pin to bottom
-	statusBar := statusBarStyle.Width(m.Width).Render(statusText)
+	statusBar := m.st.status.Padding(0, 1).Width(m.Width).MaxHeight(1).Render(statusText)
 	return lipgloss.JoinVertical(lipgloss.Top, s.String(), statusBar)
 }
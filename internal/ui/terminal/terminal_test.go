@@ -0,0 +1,62 @@
+package terminal
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// specialKeys are the keys typed by name in tests.
+var specialKeys = map[string]tea.KeyType{
+	"esc":    tea.KeyEsc,
+	"enter":  tea.KeyEnter,
+	"tab":    tea.KeyTab,
+	"ctrl+w": tea.KeyCtrlW,
+	"ctrl+e": tea.KeyCtrlE,
+	"ctrl+y": tea.KeyCtrlY,
+}
+
+// press types keys into m: characters, or names such as "esc" and
+// "ctrl+w".
+func press(t *testing.T, m Model, keys ...string) Model {
+	t.Helper()
+	for _, k := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		if typ, ok := specialKeys[k]; ok {
+			msg = tea.KeyMsg{Type: typ}
+		}
+		if msg.String() != k {
+			t.Fatalf("cannot type key %q", k)
+		}
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+	return m
+}
+
+// command runs an ex command line in m, failing the test on an error.
+func command(t *testing.T, m *Model, line string) {
+	t.Helper()
+	m.handleCommand(line)
+	if m.Error != nil {
+		t.Fatalf(":%s: %v", line, m.Error)
+	}
+}
+
+func TestArrowKeysMoveTheCursor(t *testing.T) {
+	m := NewModel(9)
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	m = next.(Model)
+	if m.Handler.CursorX != 5 || m.Handler.CursorY != 4 {
+		t.Fatalf("expected the cursor at (5, 4), got (%d, %d)", m.Handler.CursorX, m.Handler.CursorY)
+	}
+	m = press(t, m, ":", "3", "enter")
+	if m.Error == nil {
+		t.Fatalf("expected :3 to fail on an empty game")
+	}
+	m = press(t, m, "x")
+	if m.Game.Board.At(5, 4) != board.Black {
+		t.Fatalf("expected x to place a stone at the cursor")
+	}
+}
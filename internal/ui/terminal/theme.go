@@ -0,0 +1,449 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// Theme is a color scheme chosen with :colorscheme. Themes other than the
+// built-in ones are TOML or JSON files with the same keys; what a file
+// leaves out keeps the value of the default theme. Colors are ANSI numbers
+// such as "240" or hex values such as "#e8cda5", and an empty color is the
+// terminal's own.
+type Theme struct {
+	Name   string `toml:"name" json:"name"`
+	Glyphs Glyphs `toml:"glyphs" json:"glyphs"`
+	Colors Colors `toml:"colors" json:"colors"`
+}
+
+// Glyphs are the characters a theme draws the board with.
+type Glyphs struct {
+	Black       string `toml:"black" json:"black"`
+	White       string `toml:"white" json:"white"`
+	Star        string `toml:"star" json:"star"`
+	Cross       string `toml:"cross" json:"cross"`
+	TopLeft     string `toml:"top_left" json:"top_left"`
+	TopRight    string `toml:"top_right" json:"top_right"`
+	BottomLeft  string `toml:"bottom_left" json:"bottom_left"`
+	BottomRight string `toml:"bottom_right" json:"bottom_right"`
+	TeeLeft     string `toml:"tee_left" json:"tee_left"`
+	TeeRight    string `toml:"tee_right" json:"tee_right"`
+	TeeTop      string `toml:"tee_top" json:"tee_top"`
+	TeeBottom   string `toml:"tee_bottom" json:"tee_bottom"`
+	Horizontal  string `toml:"horizontal" json:"horizontal"`
+	Vertical    string `toml:"vertical" json:"vertical"`
+	// BlackOwner and WhiteOwner mark territory with :set ownership.
+	BlackOwner string `toml:"black_owner" json:"black_owner"`
+	WhiteOwner string `toml:"white_owner" json:"white_owner"`
+	// Border is the box around the board: rounded, normal, thick, double,
+	// block or ascii.
+	Border string `toml:"border" json:"border"`
+}
+
+// Colors are the colors of a theme.
+type Colors struct {
+	Board      string `toml:"board" json:"board"` // background
+	Border     string `toml:"border" json:"border"`
+	Grid       string `toml:"grid" json:"grid"`
+	Star       string `toml:"star" json:"star"`
+	Coords     string `toml:"coords" json:"coords"`
+	Black      string `toml:"black" json:"black"`
+	White      string `toml:"white" json:"white"`
+	Cursor     string `toml:"cursor" json:"cursor"`
+	CursorText string `toml:"cursor_text" json:"cursor_text"`
+	Selection  string `toml:"selection" json:"selection"`
+	Search     string `toml:"search" json:"search"`
+	Dead       string `toml:"dead" json:"dead"`
+	Inactive   string `toml:"inactive" json:"inactive"`
+	LastMove   string `toml:"last_move" json:"last_move"`
+	Flash      string `toml:"flash" json:"flash"`
+	Ghost      string `toml:"ghost" json:"ghost"`
+	BlackOwner string `toml:"black_owner" json:"black_owner"`
+	WhiteOwner string `toml:"white_owner" json:"white_owner"`
+	Status     string `toml:"status" json:"status"`
+	StatusText string `toml:"status_text" json:"status_text"`
+	Error      string `toml:"error" json:"error"`
+}
+
+var boxGlyphs = Glyphs{
+	Black:       "●",
+	White:       "○",
+	Star:        "╋",
+	Cross:       "┼",
+	TopLeft:     "┌",
+	TopRight:    "┐",
+	BottomLeft:  "└",
+	BottomRight: "┘",
+	TeeLeft:     "├",
+	TeeRight:    "┤",
+	TeeTop:      "┬",
+	TeeBottom:   "┴",
+	Horizontal:  "─",
+	Vertical:    "│",
+	BlackOwner:  "▪",
+	WhiteOwner:  "▪",
+	Border:      "rounded",
+}
+
+var defaultTheme = Theme{
+	Name:   "default",
+	Glyphs: boxGlyphs,
+	Colors: Colors{
+		Border:     "63",
+		Grid:       "240", // Subtle grey/blue
+		Star:       "214", // Orange/Yellow
+		Coords:     "240",
+		Cursor:     "201",
+		CursorText: "15",
+		Selection:  "24",  // Dark blue
+		Search:     "136", // Dark yellow
+		Dead:       "244", // Grey, for stones marked dead
+		Inactive:   "238", // Unfocused windows
+		LastMove:   "196", // Red, for the last stone played
+		Flash:      "160", // Dark red, behind captured stones
+		Ghost:      "242", // Grey, for variation moves
+		BlackOwner: "232",
+		WhiteOwner: "255",
+		Status:     "63",
+		StatusText: "15",
+		Error:      "196",
+	},
+}
+
+// themes are the built-in themes. "gba" uses the palette of the GBA pixel
+// style in doc/project-plan.md: solid stones on a bamboo board inside a
+// block border.
+var themes = map[string]Theme{
+	"default": defaultTheme,
+	"gba": {
+		Name: "gba",
+		Glyphs: withGlyphs(boxGlyphs, func(g *Glyphs) {
+			g.White = "●"
+			g.Star = "■"
+			g.BlackOwner, g.WhiteOwner = "▫", "▫"
+			g.Border = "block"
+		}),
+		Colors: Colors{
+			Board:      "#e8cda5",
+			Border:     "#8b4513",
+			Grid:       "#c9a05c",
+			Star:       "#8b4513",
+			Coords:     "#2d2d2d",
+			Black:      "#1a1a1a",
+			White:      "#f5f5f5",
+			Cursor:     "#ff6b6b",
+			CursorText: "#2d2d2d",
+			Selection:  "#a8c8e8",
+			Search:     "#f0d060",
+			Dead:       "#a08060",
+			Inactive:   "#6b6b6b",
+			LastMove:   "#d03030",
+			Flash:      "#ff6b6b",
+			Ghost:      "#a08060",
+			BlackOwner: "#1a1a1a",
+			WhiteOwner: "#f5f5f5",
+			Status:     "#8b4513",
+			StatusText: "#f5f5f5",
+			Error:      "#d03030",
+		},
+	},
+	"high-contrast": {
+		Name: "high-contrast",
+		Glyphs: withGlyphs(boxGlyphs, func(g *Glyphs) {
+			g.Cross, g.Horizontal, g.Vertical = "╋", "━", "┃"
+			g.TopLeft, g.TopRight, g.BottomLeft, g.BottomRight = "┏", "┓", "┗", "┛"
+			g.TeeLeft, g.TeeRight, g.TeeTop, g.TeeBottom = "┣", "┫", "┳", "┻"
+			g.Star = "◆"
+			g.BlackOwner, g.WhiteOwner = "x", "o"
+			g.Border = "thick"
+		}),
+		Colors: Colors{
+			Border:     "15",
+			Grid:       "7",
+			Star:       "15",
+			Coords:     "15",
+			Black:      "15",
+			White:      "15",
+			Cursor:     "11",
+			CursorText: "0",
+			Selection:  "4",
+			Search:     "5",
+			Dead:       "8",
+			Inactive:   "8",
+			LastMove:   "9",
+			Flash:      "9",
+			Ghost:      "8",
+			BlackOwner: "15",
+			WhiteOwner: "15",
+			Status:     "15",
+			StatusText: "0",
+			Error:      "9",
+		},
+	},
+	"light": {
+		Name:   "light",
+		Glyphs: boxGlyphs,
+		Colors: Colors{
+			Border:     "25",
+			Grid:       "245",
+			Star:       "130",
+			Coords:     "243",
+			Cursor:     "161",
+			CursorText: "15",
+			Selection:  "153",
+			Search:     "222",
+			Dead:       "248",
+			Inactive:   "252",
+			LastMove:   "160",
+			Flash:      "210",
+			Ghost:      "248",
+			BlackOwner: "232",
+			WhiteOwner: "250",
+			Status:     "25",
+			StatusText: "15",
+			Error:      "160",
+		},
+	},
+	// ascii draws only ASCII characters, for fonts and terminals without
+	// box drawing.
+	"ascii": {
+		Name: "ascii",
+		Glyphs: Glyphs{
+			Black: "X", White: "O", Star: "+", Cross: "+",
+			TopLeft: "+", TopRight: "+", BottomLeft: "+", BottomRight: "+",
+			TeeLeft: "+", TeeRight: "+", TeeTop: "+", TeeBottom: "+",
+			Horizontal: "-", Vertical: "|",
+			BlackOwner: "x", WhiteOwner: "o",
+			Border: "ascii",
+		},
+		Colors: defaultTheme.Colors,
+	},
+}
+
+func withGlyphs(g Glyphs, change func(g *Glyphs)) Glyphs {
+	change(&g)
+	return g
+}
+
+var borders = map[string]lipgloss.Border{
+	"rounded": lipgloss.RoundedBorder(),
+	"normal":  lipgloss.NormalBorder(),
+	"thick":   lipgloss.ThickBorder(),
+	"double":  lipgloss.DoubleBorder(),
+	"block":   lipgloss.BlockBorder(),
+	"ascii":   lipgloss.ASCIIBorder(),
+}
+
+// themeNames lists the built-in themes.
+func themeNames() []string {
+	var names []string
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// themeDir is where :colorscheme looks for theme files, like Vim's colors
+// directory.
+func themeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".vimgo", "colors")
+}
+
+// LoadTheme finds a theme by name: a built-in one, name.toml or name.json
+// in ~/.vimgo/colors, or a file named by its path. Without files only the
+// built-in themes are found.
+func LoadTheme(name string, files bool) (*Theme, error) {
+	if t, ok := themes[name]; ok {
+		return &t, nil
+	}
+	if !files {
+		return nil, errNoFiles
+	}
+	var candidates []string
+	switch filepath.Ext(name) {
+	case ".toml", ".json":
+		candidates = []string{name}
+	default:
+		if dir := themeDir(); dir != "" {
+			candidates = []string{filepath.Join(dir, name+".toml"), filepath.Join(dir, name+".json")}
+		}
+	}
+	for _, file := range candidates {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseTheme(file, data)
+	}
+	return nil, fmt.Errorf("E185: Cannot find color scheme '%s'", name)
+}
+
+// parseTheme reads a theme file over the default theme.
+func parseTheme(file string, data []byte) (*Theme, error) {
+	t := defaultTheme
+	t.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	var err error
+	if filepath.Ext(file) == ".json" {
+		err = json.Unmarshal(data, &t)
+	} else {
+		err = toml.Unmarshal(data, &t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if _, ok := borders[t.Glyphs.Border]; !ok {
+		return nil, fmt.Errorf("%s: unknown border %q", file, t.Glyphs.Border)
+	}
+	return &t, nil
+}
+
+// styles are a theme's lipgloss styles for one color profile.
+type styles struct {
+	glyphs Glyphs
+	// base carries the board background; every cell style starts from it.
+	base      lipgloss.Style
+	box       lipgloss.Style
+	grid      lipgloss.Style
+	star      lipgloss.Style
+	coords    lipgloss.Style
+	stones    [2]lipgloss.Style
+	owners    [2]lipgloss.Style
+	ghost     lipgloss.Style
+	dead      lipgloss.Style
+	lastMove  lipgloss.Style
+	label     lipgloss.Style
+	flash     lipgloss.Style
+	cursor    lipgloss.Style
+	inactive  lipgloss.Style
+	selection lipgloss.Style
+	search    lipgloss.Style
+	status    lipgloss.Style
+	window    lipgloss.Style
+	error     lipgloss.Style
+}
+
+// styles resolves t for a terminal with color profile p. Colors beyond the
+// profile are approximated by lipgloss; a board background would only be
+// garish in 16 colors and is dropped. Without colors, the highlights use
+// reverse video, underline and bold instead.
+func (t *Theme) styles(p termenv.Profile) styles {
+	c := t.Colors
+	color := func(s string) lipgloss.TerminalColor {
+		if s == "" || p == termenv.Ascii {
+			return lipgloss.NoColor{}
+		}
+		return lipgloss.Color(s)
+	}
+	base := lipgloss.NewStyle()
+	if p < termenv.ANSI {
+		base = base.Background(color(c.Board))
+	}
+	fg := func(s string) lipgloss.Style { return base.Foreground(color(s)) }
+	bg := func(s string) lipgloss.Style { return lipgloss.NewStyle().Background(color(s)) }
+
+	st := styles{
+		glyphs: t.Glyphs,
+		base:   base,
+		box: base.Padding(1).
+			Border(borders[t.Glyphs.Border]).
+			BorderForeground(color(c.Border)).
+			BorderBackground(base.GetBackground()),
+		grid:      fg(c.Grid),
+		star:      fg(c.Star),
+		coords:    fg(c.Coords),
+		stones:    [2]lipgloss.Style{fg(c.Black), fg(c.White)},
+		owners:    [2]lipgloss.Style{fg(c.BlackOwner), fg(c.WhiteOwner)},
+		ghost:     fg(c.Ghost),
+		dead:      fg(c.Dead),
+		lastMove:  lipgloss.NewStyle().Bold(true).Foreground(color(c.LastMove)),
+		label:     lipgloss.NewStyle().Reverse(true),
+		flash:     bg(c.Flash),
+		cursor:    bg(c.Cursor).Foreground(color(c.CursorText)),
+		inactive:  bg(c.Inactive).Foreground(color(c.CursorText)),
+		selection: bg(c.Selection),
+		search:    bg(c.Search),
+		status:    bg(c.Status).Foreground(color(c.StatusText)),
+		window:    bg(c.Inactive).Foreground(color(c.StatusText)),
+		error:     lipgloss.NewStyle().Foreground(color(c.Error)),
+	}
+	if p == termenv.Ascii {
+		st.ghost = st.ghost.Faint(true)
+		st.dead = st.dead.Faint(true)
+		st.lastMove = st.lastMove.Underline(true)
+		st.flash = st.flash.Blink(true)
+		st.cursor = st.cursor.Reverse(true)
+		st.inactive = st.inactive.Underline(true)
+		st.selection = st.selection.Underline(true)
+		st.search = st.search.Bold(true).Underline(true)
+		st.status = st.status.Reverse(true)
+	}
+	return st
+}
+
+// stone returns the style for a stone of color c.
+func (st styles) stone(c board.Color) lipgloss.Style {
+	if c == board.White {
+		return st.stones[1]
+	}
+	return st.stones[0]
+}
+
+// owner returns the mark and style for territory of color c.
+func (st styles) owner(c board.Color) (string, lipgloss.Style) {
+	if c == board.White {
+		return st.glyphs.WhiteOwner, st.owners[1]
+	}
+	return st.glyphs.BlackOwner, st.owners[0]
+}
+
+// setTheme makes t the theme, with its stones.
+func (m *Model) setTheme(t *Theme) {
+	m.scheme = t
+	m.Theme = t.Name
+	m.Stones = t.stoneGlyphs(m.Profile)
+	m.st = t.styles(m.Profile)
+}
+
+// stoneGlyphs returns the glyphs of black and white stones for a terminal
+// with color profile p. Stones that only their colors tell apart get the
+// default glyphs where the board cannot show them: without colors, or in
+// 16 colors, where the board background is dropped and dark stones vanish
+// on a dark terminal.
+func (t *Theme) stoneGlyphs(p termenv.Profile) [2]string {
+	if t.Glyphs.Black == t.Glyphs.White && p >= termenv.ANSI {
+		return [2]string{boxGlyphs.Black, boxGlyphs.White}
+	}
+	return [2]string{t.Glyphs.Black, t.Glyphs.White}
+}
+
+// colorscheme implements :colorscheme [name]; without a name it shows the
+// current one.
+func (m *Model) colorscheme(args []string) error {
+	if len(args) == 0 {
+		m.Message = m.Theme
+		return nil
+	}
+	t, err := LoadTheme(args[0], !m.NoFiles)
+	if err != nil {
+		return err
+	}
+	m.setTheme(t)
+	return nil
+}
@@ -0,0 +1,55 @@
+package terminal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/muesli/termenv"
+)
+
+func TestThemeWithoutFiles(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.toml")
+	if err := os.WriteFile(file, []byte("not a theme"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel(9)
+	m.NoFiles = true
+	for _, line := range []string{"set theme=" + file, "colorscheme " + file} {
+		m.handleCommand(line)
+		if !errors.Is(m.Error, errNoFiles) {
+			t.Errorf(":%s: expected %v, got %v", line, errNoFiles, m.Error)
+		}
+	}
+	if m.handleCommand("set theme=gba"); m.Error != nil || m.Theme != "gba" {
+		t.Errorf("expected a built-in theme allowed, got %q and %v", m.Theme, m.Error)
+	}
+
+	m.NoFiles = false
+	if m.handleCommand("set theme=" + file); m.Error == nil || errors.Is(m.Error, errNoFiles) {
+		t.Errorf("expected the file read and rejected, got %v", m.Error)
+	}
+}
+
+func TestThemeStonesWithoutColors(t *testing.T) {
+	for _, tc := range []struct {
+		profile termenv.Profile
+		stones  [2]string
+	}{
+		{termenv.TrueColor, [2]string{"●", "●"}},
+		{termenv.ANSI256, [2]string{"●", "●"}},
+		{termenv.ANSI, [2]string{"●", "○"}},
+		{termenv.Ascii, [2]string{"●", "○"}},
+	} {
+		m := NewModel(9)
+		m.Profile = tc.profile
+		if err := m.colorscheme([]string{"gba"}); err != nil {
+			t.Fatal(err)
+		}
+		if m.Stones != tc.stones {
+			t.Errorf("profile %v: expected stones %v, got %v", tc.profile, tc.stones, m.Stones)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/vimgo/vimgo/internal/game"
+)
+
+// Window shows a buffer at one node of its game tree. Several windows may
+// show the same buffer, each with its own cursor and node. The focused
+// window's cursor and node live in the Handler and the buffer's game, and
+// are copied back when focus moves.
+type Window struct {
+	Buffer  *Buffer
+	CursorX int
+	CursorY int
+	Node    *game.Node
+}
+
+// split is a node of the window layout: a window, or a row (vertical, from
+// :vsplit) or column of splits.
+type split struct {
+	win      *Window
+	vertical bool
+	children []*split
+	parent   *split
+}
+
+// windows returns the windows in layout order, left to right and top to
+// bottom.
+func (s *split) windows() []*Window {
+	if s.win != nil {
+		return []*Window{s.win}
+	}
+	var ws []*Window
+	for _, c := range s.children {
+		ws = append(ws, c.windows()...)
+	}
+	return ws
+}
+
+func (s *split) find(w *Window) *split {
+	if s.win == w {
+		return s
+	}
+	for _, c := range s.children {
+		if found := c.find(w); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// rect is a window's place in the layout, as fractions of the screen.
+type rect struct{ x0, y0, x1, y1 float64 }
+
+func (s *split) rects(r rect, out map[*Window]rect) {
+	if s.win != nil {
+		out[s.win] = r
+		return
+	}
+	n := float64(len(s.children))
+	for i, c := range s.children {
+		cr := r
+		if s.vertical {
+			w := (r.x1 - r.x0) / n
+			cr.x0, cr.x1 = r.x0+w*float64(i), r.x0+w*float64(i+1)
+		} else {
+			h := (r.y1 - r.y0) / n
+			cr.y0, cr.y1 = r.y0+h*float64(i), r.y0+h*float64(i+1)
+		}
+		c.rects(cr, out)
+	}
+}
+
+// syncWindow copies the focused window's state back from the handler and
+// the current buffer.
+func (m *Model) syncWindow() {
+	m.win.Buffer = m.buf
+	m.win.CursorX, m.win.CursorY = m.Handler.CursorX, m.Handler.CursorY
+	m.win.Node = m.Game.Current
+}
+
+// focus moves focus to w, showing its buffer at its node.
+func (m *Model) focus(w *Window) {
+	if w == m.win {
+		return
+	}
+	m.syncWindow()
+	m.win = w
+	m.leaveScoring()
+	m.switchTo(w.Buffer)
+	m.Handler.CursorX, m.Handler.CursorY = w.CursorX, w.CursorY
+	if w.Node != m.Game.Current && m.Game.GoTo(w.Node) != nil {
+		// The node went away, for instance with :e!.
+		m.Game.GoTo(m.Game.Root)
+	}
+}
+
+// windowGame returns the game positioned where w shows it.
+func (m *Model) windowGame(w *Window) *game.Game {
+	if w == m.win {
+		return m.Game
+	}
+	g := w.Buffer.Game
+	if view, err := g.At(w.Node); err == nil {
+		return view
+	}
+	return g
+}
+
+// splitWindow implements :split and :vsplit: the new window shows the same
+// buffer at the same node, goes above or to the left, and gets focus. With
+// a file name it then edits that file.
+func (m *Model) splitWindow(vertical bool, args []string) error {
+	m.syncWindow()
+	w := *m.win
+	leaf := m.layout.find(m.win)
+	parent := leaf.parent
+	if parent == nil || parent.vertical != vertical {
+		// Turn the leaf into a row or column holding the old window.
+		old := &split{win: leaf.win, parent: leaf}
+		leaf.win, leaf.vertical, leaf.children = nil, vertical, []*split{old}
+		parent, leaf = leaf, old
+	}
+	for i, c := range parent.children {
+		if c == leaf {
+			s := &split{win: &w, parent: parent}
+			parent.children = append(parent.children[:i], append([]*split{s}, parent.children[i:]...)...)
+			break
+		}
+	}
+	m.win = &w
+	if len(args) > 0 {
+		return m.edit(args, false)
+	}
+	return nil
+}
+
+// closeWindow implements :close. The buffer stays open.
+func (m *Model) closeWindow(w *Window) error {
+	leaf := m.layout.find(w)
+	if leaf.parent == nil {
+		return fmt.Errorf("E444: Cannot close last window")
+	}
+	parent := leaf.parent
+	idx := 0
+	for i, c := range parent.children {
+		if c == leaf {
+			idx = i
+		}
+	}
+	parent.children = append(parent.children[:idx], parent.children[idx+1:]...)
+	if len(parent.children) == 1 {
+		// A row or column of one collapses into its child.
+		only := parent.children[0]
+		parent.win, parent.vertical, parent.children = only.win, only.vertical, only.children
+		for _, c := range parent.children {
+			c.parent = parent
+		}
+	}
+	if w == m.win {
+		next := parent
+		if idx < len(parent.children) {
+			next = parent.children[idx]
+		} else if len(parent.children) > 0 {
+			next = parent.children[len(parent.children)-1]
+		}
+		m.focus(next.windows()[0])
+	}
+	return nil
+}
+
+// onlyWindow implements :only.
+func (m *Model) onlyWindow() {
+	m.layout = &split{win: m.win}
+}
+
+// wincmd runs a Ctrl-w command: h, j, k and l move focus in that direction,
+// w and W cycle through the windows, and the rest split and close.
+func (m *Model) wincmd(arg string) error {
+	switch arg {
+	case "h", "j", "k", "l":
+		if w := m.neighbour(arg); w != nil {
+			m.focus(w)
+		}
+	case "w", "W":
+		ws := m.layout.windows()
+		for i, w := range ws {
+			if w == m.win {
+				dir := 1
+				if arg == "W" {
+					dir = -1
+				}
+				m.focus(ws[((i+dir)%len(ws)+len(ws))%len(ws)])
+				break
+			}
+		}
+	case "s":
+		return m.splitWindow(false, nil)
+	case "v":
+		return m.splitWindow(true, nil)
+	case "c":
+		return m.closeWindow(m.win)
+	case "o":
+		m.onlyWindow()
+	default:
+		return fmt.Errorf("E474: Invalid argument: %s", arg)
+	}
+	return nil
+}
+
+// neighbour returns the nearest window in direction dir that overlaps the
+// focused one, or nil.
+func (m *Model) neighbour(dir string) *Window {
+	rects := make(map[*Window]rect)
+	m.layout.rects(rect{0, 0, 1, 1}, rects)
+	cur := rects[m.win]
+
+	const eps = 1e-9
+	var best *Window
+	bestDist := 2.0
+	for _, w := range m.layout.windows() {
+		r := rects[w]
+		var dist float64
+		overlaps := r.y0 < cur.y1-eps && r.y1 > cur.y0+eps
+		switch dir {
+		case "h":
+			dist = cur.x0 - r.x1
+		case "l":
+			dist = r.x0 - cur.x1
+		case "k":
+			dist = cur.y0 - r.y1
+			overlaps = r.x0 < cur.x1-eps && r.x1 > cur.x0+eps
+		case "j":
+			dist = r.y0 - cur.y1
+			overlaps = r.x0 < cur.x1-eps && r.x1 > cur.x0+eps
+		}
+		if w != m.win && overlaps && dist > -eps && dist < bestDist {
+			best, bestDist = w, dist
+		}
+	}
+	return best
+}
+
+// replaceBuffer replaces b with the current buffer in every window after
+// b is deleted.
+func (m *Model) replaceBuffer(b *Buffer) {
+	for _, w := range m.layout.windows() {
+		if w != m.win && w.Buffer == b {
+			w.Buffer = m.buf
+			w.CursorX, w.CursorY = m.Handler.CursorX, m.Handler.CursorY
+			w.Node = m.Game.Current
+		}
+	}
+}
+
+// scrollBind keeps the other windows on the focused window's move number
+// while scrollbind is set, each along its own line.
+func (m *Model) scrollBind() {
+	if !m.ScrollBind {
+		return
+	}
+	n := m.Game.Current.MoveNumber()
+	for _, w := range m.layout.windows() {
+		if w != m.win && w.Node != nil {
+			w.Node = w.Node.LineMove(n)
+		}
+	}
+}
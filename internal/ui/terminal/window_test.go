@@ -0,0 +1,82 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWindows(t *testing.T) {
+	m := NewModel(9)
+	m = press(t, m, "x")
+	right := m.win
+
+	// The new window goes to the left, at the same node, and gets focus.
+	command(t, &m, "vsplit")
+	left := m.win
+	if left == right || len(m.layout.windows()) != 2 || m.layout.windows()[0] != left {
+		t.Fatalf("expected a new window on the left")
+	}
+	m = press(t, m, "l", "u")
+	if m.Game.Current != m.Game.Root {
+		t.Fatalf("expected u to take back the move in the left window")
+	}
+
+	// Each window keeps its own cursor and node.
+	m = press(t, m, "ctrl+w", "l")
+	if m.win != right || m.Handler.CursorX != 4 || m.Game.Current.MoveNumber() != 1 {
+		t.Fatalf("expected the right window at move 1 with its cursor, got x=%d at move %d",
+			m.Handler.CursorX, m.Game.Current.MoveNumber())
+	}
+	m = press(t, m, "ctrl+w", "h")
+	if m.win != left || m.Handler.CursorX != 5 || m.Game.Current != m.Game.Root {
+		t.Fatalf("expected the left window back at the root with its cursor")
+	}
+	m = press(t, m, "ctrl+w", "h")
+	if m.win != left {
+		t.Fatalf("expected Ctrl-w h to stay in the leftmost window")
+	}
+
+	// :split in the right window puts the new one above it.
+	m = press(t, m, "ctrl+w", "w")
+	command(t, &m, "split")
+	top := m.win
+	m = press(t, m, "ctrl+w", "j")
+	if m.win != right {
+		t.Fatalf("expected Ctrl-w j to reach the window below")
+	}
+	m = press(t, m, "ctrl+w", "h")
+	if m.win != left {
+		t.Fatalf("expected Ctrl-w h to reach the window spanning the left")
+	}
+	m = press(t, m, "ctrl+w", "W")
+	if m.win != right {
+		t.Fatalf("expected Ctrl-w W to cycle backwards to the last window")
+	}
+
+	// :q closes a window while there are others; the buffer stays.
+	command(t, &m, "quit")
+	if len(m.layout.windows()) != 2 || m.win == right || len(m.Buffers) != 1 {
+		t.Fatalf("expected :q to close the window")
+	}
+	command(t, &m, "only")
+	if ws := m.layout.windows(); len(ws) != 1 || ws[0] != top && ws[0] != left {
+		t.Fatalf("expected :only to keep the focused window")
+	}
+	if m.handleCommand("close"); m.Error == nil || !strings.HasPrefix(m.Error.Error(), "E444") {
+		t.Fatalf("expected E444 closing the last window, got %v", m.Error)
+	}
+}
+
+func TestScrollBind(t *testing.T) {
+	m := NewModel(9)
+	for _, k := range []string{"x", "l", "x", "l", "x"} {
+		m = press(t, m, k)
+	}
+	command(t, &m, "vsplit")
+	command(t, &m, "set scrollbind")
+	m = press(t, m, "u", "u")
+	other := m.layout.windows()[1]
+	if n := other.Node.MoveNumber(); n != 1 {
+		t.Fatalf("expected the bound window at move 1, got %d", n)
+	}
+}
@@ -0,0 +1,198 @@
+package vim
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxHistory bounds the command-line history, like Vim's 'history' option.
+const maxHistory = 100
+
+func (h *Handler) enterCommand(initial string) {
+	h.Mode = Command
+	h.CommandType = ":"
+	h.CommandBuffer = initial
+	h.CommandCursor = utf8.RuneCountInString(initial)
+	h.histIndex = len(h.history)
+	h.completion = nil
+}
+
+// enterSearch opens the command line for a / or ? search, which has its own
+// history.
+func (h *Handler) enterSearch(kind string) {
+	h.enterCommand("")
+	h.CommandType = kind
+	h.histIndex = len(h.searchHistory)
+}
+
+// hist returns the history of the current command-line type.
+func (h *Handler) hist() *[]string {
+	if h.CommandType == ":" {
+		return &h.history
+	}
+	return &h.searchHistory
+}
+
+func (h *Handler) leaveCommand() {
+	h.Mode = Normal
+	h.CommandBuffer = ""
+	h.CommandCursor = 0
+	h.completion = nil
+}
+
+// CommandLine splits the command line at the cursor for rendering.
+func (h *Handler) CommandLine() (before, after string) {
+	runes := []rune(h.CommandBuffer)
+	return string(runes[:h.CommandCursor]), string(runes[h.CommandCursor:])
+}
+
+// History returns the command-line history, oldest first.
+func (h *Handler) History() []string {
+	return h.history
+}
+
+func (h *Handler) handleCommandKey(key string) *Action {
+	if key != "tab" && key != "shift+tab" {
+		h.completion = nil
+	}
+
+	runes := []rune(h.CommandBuffer)
+	switch key {
+	case "enter":
+		cmd, kind := h.CommandBuffer, h.CommandType
+		h.addHistory(cmd)
+		h.leaveCommand()
+		if kind != ":" {
+			return &Action{Type: ActionSearch, Value: kind + cmd}
+		}
+		return &Action{Type: ActionCommand, Value: cmd}
+	case "esc", "ctrl+c":
+		h.leaveCommand()
+		return &Action{Type: ActionEnterMode, Value: "NORMAL"}
+	case "backspace", "ctrl+h":
+		// Backspace on an empty line leaves the command line, as in Vim.
+		if len(runes) == 0 {
+			h.leaveCommand()
+			return &Action{Type: ActionEnterMode, Value: "NORMAL"}
+		}
+		if h.CommandCursor > 0 {
+			h.setLine(append(runes[:h.CommandCursor-1:h.CommandCursor-1], runes[h.CommandCursor:]...), h.CommandCursor-1)
+		}
+	case "delete":
+		if h.CommandCursor < len(runes) {
+			h.setLine(append(runes[:h.CommandCursor:h.CommandCursor], runes[h.CommandCursor+1:]...), h.CommandCursor)
+		}
+	case "left":
+		h.CommandCursor = max(0, h.CommandCursor-1)
+	case "right":
+		h.CommandCursor = min(len(runes), h.CommandCursor+1)
+	case "home", "ctrl+b":
+		h.CommandCursor = 0
+	case "end", "ctrl+e":
+		h.CommandCursor = len(runes)
+	case "ctrl+u":
+		h.setLine(runes[h.CommandCursor:], 0)
+	case "ctrl+w":
+		i := h.CommandCursor
+		for i > 0 && runes[i-1] == ' ' {
+			i--
+		}
+		for i > 0 && runes[i-1] != ' ' {
+			i--
+		}
+		h.setLine(append(runes[:i:i], runes[h.CommandCursor:]...), i)
+	case "up":
+		h.recall(-1)
+	case "down":
+		h.recall(1)
+	case "tab", "shift+tab":
+		if h.CommandType != ":" {
+			break
+		}
+		if key == "tab" {
+			h.complete(1)
+		} else {
+			h.complete(-1)
+		}
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			r, _ := utf8.DecodeRuneInString(key)
+			line := append(runes[:h.CommandCursor:h.CommandCursor], r)
+			h.setLine(append(line, runes[h.CommandCursor:]...), h.CommandCursor+1)
+		}
+	}
+	return nil
+}
+
+func (h *Handler) setLine(runes []rune, cursor int) {
+	h.CommandBuffer = string(runes)
+	h.CommandCursor = cursor
+}
+
+func (h *Handler) addHistory(cmd string) {
+	if strings.TrimSpace(cmd) == "" {
+		return
+	}
+	history := h.hist()
+	// Keep one copy of each command, most recent last.
+	for i, old := range *history {
+		if old == cmd {
+			*history = append((*history)[:i], (*history)[i+1:]...)
+			break
+		}
+	}
+	*history = append(*history, cmd)
+	if len(*history) > maxHistory {
+		*history = (*history)[1:]
+	}
+}
+
+// recall steps through the history. Like Vim, only entries starting with
+// the text typed before the first Up are considered.
+func (h *Handler) recall(dir int) {
+	history := *h.hist()
+	if h.histIndex == len(history) {
+		h.histPrefix = h.CommandBuffer
+	}
+	for i := h.histIndex + dir; i >= 0 && i <= len(history); i += dir {
+		if i == len(history) {
+			h.histIndex = i
+			h.setLine([]rune(h.histPrefix), utf8.RuneCountInString(h.histPrefix))
+			return
+		}
+		if strings.HasPrefix(history[i], h.histPrefix) {
+			h.histIndex = i
+			h.setLine([]rune(history[i]), utf8.RuneCountInString(history[i]))
+			return
+		}
+	}
+}
+
+// complete replaces the word before the cursor with the next (dir 1) or
+// previous (dir -1) completion candidate.
+func (h *Handler) complete(dir int) {
+	if h.completion == nil {
+		if h.Complete == nil {
+			return
+		}
+		before, after := h.CommandLine()
+		start, candidates := h.Complete(before)
+		if len(candidates) == 0 {
+			return
+		}
+		h.completion = candidates
+		h.compStart = utf8.RuneCountInString(before[:start])
+		h.compTrailer = after
+		h.compIndex = -1
+		if dir < 0 {
+			h.compIndex = 0
+		}
+	}
+	n := len(h.completion)
+	h.compIndex = ((h.compIndex+dir)%n + n) % n
+
+	runes := []rune(h.CommandBuffer)
+	line := append(runes[:h.compStart:h.compStart], []rune(h.completion[h.compIndex])...)
+	cursor := len(line)
+	h.setLine(append(line, []rune(h.compTrailer)...), cursor)
+}
@@ -0,0 +1,81 @@
+package vim
+
+import "testing"
+
+func TestCommandLineEditing(t *testing.T) {
+	h := NewHandler(9)
+	feed(h, ":", "w", "q", "left", "left", "x", "end", "!")
+	if h.CommandBuffer != "xwq!" || h.CommandCursor != 4 {
+		t.Fatalf("unexpected line %q cursor %d", h.CommandBuffer, h.CommandCursor)
+	}
+	feed(h, "ctrl+w", "s", "e", "t")
+	if h.CommandBuffer != "set" {
+		t.Fatalf("expected ctrl+w to delete the word, got %q", h.CommandBuffer)
+	}
+}
+
+func TestCommandHistoryUsesPrefix(t *testing.T) {
+	h := NewHandler(9)
+	for _, cmd := range []string{"score", "set coords", "pass"} {
+		feed(h, ":")
+		for _, r := range cmd {
+			feed(h, string(r))
+		}
+		if a := feed(h, "enter"); a == nil || a.Value != cmd {
+			t.Fatalf("expected command %q, got %+v", cmd, a)
+		}
+	}
+
+	feed(h, ":", "s", "up")
+	if h.CommandBuffer != "set coords" {
+		t.Fatalf("expected most recent match, got %q", h.CommandBuffer)
+	}
+	feed(h, "up")
+	if h.CommandBuffer != "score" {
+		t.Fatalf("expected older match, got %q", h.CommandBuffer)
+	}
+	feed(h, "down", "down")
+	if h.CommandBuffer != "s" {
+		t.Fatalf("expected typed prefix back, got %q", h.CommandBuffer)
+	}
+}
+
+func TestCommandCompletionCycles(t *testing.T) {
+	h := NewHandler(9)
+	h.Complete = func(line string) (int, []string) {
+		return 2, []string{"one.sgf", "two.sgf"}
+	}
+	feed(h, ":", "e", " ", "tab")
+	if h.CommandBuffer != "e one.sgf" {
+		t.Fatalf("unexpected completion %q", h.CommandBuffer)
+	}
+	feed(h, "tab")
+	if h.CommandBuffer != "e two.sgf" {
+		t.Fatalf("expected second candidate, got %q", h.CommandBuffer)
+	}
+}
+
+func TestSearchLineHasItsOwnHistory(t *testing.T) {
+	h := NewHandler(9)
+	feed(h, ":", "c", "enter")
+	feed(h, "/", "Q", "1", "6")
+	if h.CommandType != "/" {
+		t.Fatalf("expected a / prompt, got %q", h.CommandType)
+	}
+	a := h.HandleKey("enter")
+	if a == nil || a.Type != ActionSearch || a.Value != "/Q16" {
+		t.Fatalf("expected search for Q16, got %+v", a)
+	}
+
+	feed(h, "?", "up")
+	if h.CommandBuffer != "Q16" {
+		t.Fatalf("expected search history, got %q", h.CommandBuffer)
+	}
+	a = feed(h, "esc", "3", "N")
+	if a == nil || a.Type != ActionSearch || a.Value != "N" || a.Count != 3 {
+		t.Fatalf("expected 3N, got %+v", a)
+	}
+	if len(h.History()) != 1 {
+		t.Fatalf("searches must not enter the command history: %q", h.History())
+	}
+}
@@ -2,6 +2,9 @@ package vim
 
 import (
 	"strconv"
+	"strings"
+
+	"github.com/vimgo/vimgo/internal/board"
 )
 
 type Mode int
@@ -35,7 +38,47 @@ type Handler struct {
 	BoardSize     int
 	InputBuffer   string
 	CommandBuffer string
-	RepeatCount   int
+	// CommandCursor is the cursor position in CommandBuffer, in runes.
+	CommandCursor int
+	// CommandType is the command-line prompt: ":" for ex commands, "/" and
+	// "?" for searching forward and backward.
+	CommandType string
+	// Complete, if set, returns Tab completions for a command line: the
+	// byte offset where the completed word starts and its candidates.
+	Complete    func(line string) (int, []string)
+	RepeatCount int
+	// VisualX and VisualY anchor the selection while in Visual mode; the
+	// cursor is the other corner.
+	VisualX int
+	VisualY int
+	// LastSelection is the most recent Visual selection, used for the
+	// '<,'> command range.
+	LastSelection board.Rect
+	// VisualInsert records that the selection was started from Insert
+	// mode, where d and c clear it; leaving Visual mode returns there.
+	VisualInsert bool
+	// Operator is the operator waiting for a motion or text object.
+	Operator  string
+	opCount   int
+	objPrefix string
+	// prefix holds the first key of a two-key Normal mode command such
+	// as ZZ.
+	prefix string
+	// register is the register named with " for the next command.
+	register string
+	// mappings holds the :nmap and :cmap mappings by mode; typeahead holds
+	// keys that may still become a mapping.
+	mappings  map[Mode][]mapping
+	typeahead []string
+
+	history       []string
+	searchHistory []string
+	histIndex     int
+	histPrefix    string
+	completion    []string
+	compIndex     int
+	compStart     int
+	compTrailer   string
 }
 
 func NewHandler(boardSize int) *Handler {
@@ -52,6 +95,16 @@ type Action struct {
 	Type  ActionType
 	Value string
 	Count int
+	// Operator actions apply to a text object ("ig", "ag", "it", "at") or,
+	// when Object is empty, to the rectangle swept by a motion or selected
+	// in Visual mode.
+	Object string
+	Region board.Rect
+	// Visual marks an operator on the Visual selection.
+	Visual bool
+	// Register names the register an operator or paste uses; empty means
+	// the unnamed one.
+	Register string
 }
 
 type ActionType int
@@ -64,6 +117,16 @@ const (
 	ActionUndo
 	ActionRedo
 	ActionPass
+	ActionOperator
+	ActionPaste
+	ActionSetupStone
+	ActionTogglePlayer
+	// ActionSearch searches the game tree: Value is "/text" or "?text" from
+	// the command line, or "n" and "N" to repeat the last search.
+	ActionSearch
+	// ActionScroll scrolls the comment in the side panel Count lines, down
+	// for Ctrl-E and up for Ctrl-Y; Value is "down" or "up".
+	ActionScroll
 )
 
 func (h *Handler) HandleKey(key string) *Action {
@@ -74,85 +137,277 @@ func (h *Handler) HandleKey(key string) *Action {
 		return h.handleCommandKey(key)
 	case Insert:
 		return h.handleInsertKey(key)
+	case Visual:
+		return h.handleVisualKey(key)
 	}
 	return nil
 }
 
+// Selection returns the rectangle spanned by the Visual anchor and the cursor.
+func (h *Handler) Selection() board.Rect {
+	return board.NewRect(
+		board.Point{X: h.VisualX, Y: h.VisualY},
+		board.Point{X: h.CursorX, Y: h.CursorY},
+	)
+}
+
+// handleInsertKey edits the position: b and w toggle stones, e erases and t
+// switches the player to move. The cursor moves as in Normal mode.
 func (h *Handler) handleInsertKey(key string) *Action {
-	if key == "esc" {
+	if h.readCount(key) {
+		return nil
+	}
+	if h.Operator != "" {
+		return h.handleOperatorKey(key)
+	}
+	count := h.takeCount()
+
+	if operators[key] {
+		h.startOperator(key, count)
+		return nil
+	}
+
+	if h.moveCursor(key, count) {
+		return &Action{Type: ActionMove}
+	}
+
+	switch key {
+	case "b":
+		return &Action{Type: ActionSetupStone, Value: "B"}
+	case "w":
+		return &Action{Type: ActionSetupStone, Value: "W"}
+	case "e", "x":
+		return &Action{Type: ActionSetupStone, Value: "E"}
+	case "t":
+		return &Action{Type: ActionTogglePlayer}
+	case "v":
+		h.enterVisual(true)
+		return &Action{Type: ActionEnterMode, Value: "VISUAL"}
+	case "esc":
 		h.Mode = Normal
 		return &Action{Type: ActionEnterMode, Value: "NORMAL"}
 	}
 	return nil
 }
 
-func (h *Handler) handleNormalKey(key string) *Action {
-	// Handle numbers for repeat count
-	if _, err := strconv.Atoi(key); err == nil {
-		h.InputBuffer += key
-		h.RepeatCount, _ = strconv.Atoi(h.InputBuffer)
-		return nil
+// readCount accumulates digits typed before a command. It reports whether key
+// was consumed as part of the count.
+func (h *Handler) readCount(key string) bool {
+	if _, err := strconv.Atoi(key); err != nil {
+		return false
+	}
+	// A leading zero is not a count.
+	if key == "0" && h.InputBuffer == "" {
+		return false
 	}
+	h.InputBuffer += key
+	h.RepeatCount, _ = strconv.Atoi(h.InputBuffer)
+	return true
+}
 
+// takeCount returns the pending count (at least 1) and resets it.
+func (h *Handler) takeCount() int {
 	count := h.RepeatCount
 	if count == 0 {
 		count = 1
 	}
 	h.InputBuffer = ""
 	h.RepeatCount = 0
+	return count
+}
 
+// moveCursor applies a cursor motion and reports whether key was one.
+func (h *Handler) moveCursor(key string, count int) bool {
 	switch key {
 	case "h":
 		h.CursorX = max(0, h.CursorX-count)
-		return &Action{Type: ActionMove}
 	case "l":
 		h.CursorX = min(h.BoardSize-1, h.CursorX+count)
-		return &Action{Type: ActionMove}
 	case "j":
 		h.CursorY = min(h.BoardSize-1, h.CursorY+count)
-		return &Action{Type: ActionMove}
 	case "k":
 		h.CursorY = max(0, h.CursorY-count)
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *Handler) handleNormalKey(key string) *Action {
+	if h.prefix != "" {
+		return h.handlePrefixedKey(key)
+	}
+	if h.readCount(key) {
+		return nil
+	}
+	if h.Operator != "" {
+		return h.handleOperatorKey(key)
+	}
+	count := h.takeCount()
+
+	if operators[key] {
+		h.startOperator(key, count)
+		return nil
+	}
+	register := h.takeRegister()
+
+	if h.moveCursor(key, count) {
 		return &Action{Type: ActionMove}
+	}
+
+	switch key {
+	case "Z", "ctrl+w":
+		h.prefix = key
+		return nil
+	case `"`:
+		h.prefix = key
+		// Keep a count typed before the register, as in 3"ap.
+		if count > 1 {
+			h.InputBuffer, h.RepeatCount = strconv.Itoa(count), count
+		}
+		return nil
+	case "ctrl+^":
+		return &Action{Type: ActionCommand, Value: "e#"}
+	case "ctrl+e":
+		return &Action{Type: ActionScroll, Value: "down", Count: count}
+	case "ctrl+y":
+		return &Action{Type: ActionScroll, Value: "up", Count: count}
 	case "x":
 		return &Action{Type: ActionPlaceStone, Count: count}
 	case ":":
-		h.Mode = Command
-		h.CommandBuffer = ""
+		h.enterCommand("")
+		return &Action{Type: ActionEnterMode, Value: "COMMAND"}
+	case "/", "?":
+		h.enterSearch(key)
 		return &Action{Type: ActionEnterMode, Value: "COMMAND"}
+	case "n", "N":
+		return &Action{Type: ActionSearch, Value: key, Count: count}
 	case "u":
 		return &Action{Type: ActionUndo, Count: count}
 	case "i":
 		h.Mode = Insert
 		return &Action{Type: ActionEnterMode, Value: "INSERT"}
+	case "v":
+		h.enterVisual(false)
+		return &Action{Type: ActionEnterMode, Value: "VISUAL"}
+	case "p":
+		return &Action{Type: ActionPaste, Count: count, Register: register}
 	}
 	return nil
 }
 
-func (h *Handler) handleCommandKey(key string) *Action {
-	if key == "enter" {
-		cmd := h.CommandBuffer
-		h.Mode = Normal
-		h.CommandBuffer = ""
-		return &Action{Type: ActionCommand, Value: cmd}
-	}
-	if key == "esc" {
-		h.Mode = Normal
-		h.CommandBuffer = ""
-		return &Action{Type: ActionEnterMode, Value: "NORMAL"}
+// handlePrefixedKey completes a two-key Normal mode command.
+func (h *Handler) handlePrefixedKey(key string) *Action {
+	cmd := h.prefix + key
+	h.prefix = ""
+	if strings.HasPrefix(cmd, "ctrl+w") {
+		return windowAction(strings.TrimPrefix(key, "ctrl+"))
 	}
-	if key == "backspace" {
-		if len(h.CommandBuffer) > 0 {
-			h.CommandBuffer = h.CommandBuffer[:len(h.CommandBuffer)-1]
+	if strings.HasPrefix(cmd, `"`) {
+		if IsRegister(key) {
+			h.register = key
 		}
 		return nil
 	}
-	if len(key) == 1 {
-		h.CommandBuffer += key
+	switch cmd {
+	case "ZZ":
+		return &Action{Type: ActionCommand, Value: "x"}
+	case "ZQ":
+		return &Action{Type: ActionCommand, Value: "q!"}
+	}
+	return nil
+}
+
+// windowAction maps the key after Ctrl-w to a window command. Ctrl-w
+// Ctrl-j works like Ctrl-w j.
+func windowAction(key string) *Action {
+	switch key {
+	case "h", "j", "k", "l", "w", "W", "s", "v", "c", "o":
+		return &Action{Type: ActionCommand, Value: "wincmd " + key}
+	case "S":
+		return &Action{Type: ActionCommand, Value: "wincmd s"}
+	case "q":
+		return &Action{Type: ActionCommand, Value: "quit"}
+	}
+	return nil
+}
+
+// IsRegister reports whether name is a register: a-z (A-Z to append),
+// 0-9, " for the unnamed register, - for deletions, + and * for the system
+// clipboard and _ to discard.
+func IsRegister(name string) bool {
+	if len(name) != 1 {
+		return false
+	}
+	c := name[0]
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte(`"-+*_`, c) >= 0
+}
+
+// takeRegister returns the register named for this command and resets it.
+func (h *Handler) takeRegister() string {
+	r := h.register
+	h.register = ""
+	return r
+}
+
+func (h *Handler) handleVisualKey(key string) *Action {
+	if h.prefix != "" {
+		return h.handlePrefixedKey(key)
+	}
+	if h.readCount(key) {
+		return nil
+	}
+	count := h.takeCount()
+
+	if h.moveCursor(key, count) {
+		return &Action{Type: ActionMove}
+	}
+
+	switch key {
+	case "o":
+		// Jump to the other corner, as in Vim.
+		h.CursorX, h.VisualX = h.VisualX, h.CursorX
+		h.CursorY, h.VisualY = h.VisualY, h.CursorY
+		return &Action{Type: ActionMove}
+	case "d", "x", "y", "c", "m":
+		h.leaveVisual()
+		op := key
+		if op == "x" {
+			op = "d"
+		}
+		return h.operatorAction(&Action{Value: op, Region: h.LastSelection, Visual: true})
+	case `"`:
+		h.prefix = key
+		return nil
+	case ":":
+		h.leaveVisual()
+		h.enterCommand("'<,'>")
+		return &Action{Type: ActionEnterMode, Value: "COMMAND"}
+	case "esc", "v":
+		h.leaveVisual()
+		return &Action{Type: ActionEnterMode, Value: h.Mode.String()}
 	}
 	return nil
 }
 
+// enterVisual starts a selection at the cursor; from Insert mode it is one
+// whose stones d and c may clear.
+func (h *Handler) enterVisual(insert bool) {
+	h.Mode = Visual
+	h.VisualX, h.VisualY = h.CursorX, h.CursorY
+	h.VisualInsert = insert
+}
+
+// leaveVisual returns to the mode Visual mode was started from, remembering
+// the selection for '<,'>.
+func (h *Handler) leaveVisual() {
+	h.LastSelection = h.Selection()
+	h.Mode = Normal
+	if h.VisualInsert {
+		h.Mode = Insert
+	}
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -0,0 +1,145 @@
+package vim
+
+import (
+	"testing"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+func feed(h *Handler, keys ...string) *Action {
+	var last *Action
+	for _, k := range keys {
+		last = h.HandleKey(k)
+	}
+	return last
+}
+
+func TestOperatorWithTextObject(t *testing.T) {
+	h := NewHandler(9)
+	a := feed(h, "d", "a", "g")
+	if a == nil || a.Type != ActionOperator || a.Value != "d" || a.Object != "ag" {
+		t.Fatalf("expected d operator on ag, got %+v", a)
+	}
+	if h.Operator != "" || h.Pending() != "" {
+		t.Fatalf("operator still pending: %q", h.Pending())
+	}
+}
+
+func TestOperatorWithCountedMotion(t *testing.T) {
+	h := NewHandler(9)
+	a := feed(h, "2", "y", "3", "l")
+	if a == nil || a.Type != ActionOperator {
+		t.Fatalf("expected operator action, got %+v", a)
+	}
+	want := board.NewRect(board.Point{X: 4, Y: 4}, board.Point{X: 8, Y: 4})
+	if a.Region != want {
+		t.Fatalf("expected region %+v, got %+v", want, a.Region)
+	}
+	if h.CursorX != 4 {
+		t.Fatalf("operator must not move the cursor, got x=%d", h.CursorX)
+	}
+}
+
+func TestChangeOperatorEntersInsert(t *testing.T) {
+	h := NewHandler(9)
+	feed(h, "c", "i", "g")
+	if h.Mode != Insert {
+		t.Fatalf("expected Insert mode after c, got %v", h.Mode)
+	}
+}
+
+func TestVisualFromInsert(t *testing.T) {
+	h := NewHandler(9)
+	a := feed(h, "v", "l", "c")
+	if a == nil || !a.Visual || h.VisualInsert || h.Mode != Normal {
+		t.Fatalf("expected c on a Normal mode selection to stay in Normal mode, got %+v in %v", a, h.Mode)
+	}
+
+	a = feed(h, "i", "v", "l", "d")
+	if a == nil || !a.Visual || !h.VisualInsert || h.Mode != Insert {
+		t.Fatalf("expected d on an Insert mode selection to return to Insert mode, got %+v in %v", a, h.Mode)
+	}
+	if a := feed(h, "v", "esc"); a == nil || a.Value != "INSERT" || h.Mode != Insert {
+		t.Fatalf("expected esc to return to Insert mode, got %+v in %v", a, h.Mode)
+	}
+}
+
+func TestEscapeCancelsOperator(t *testing.T) {
+	h := NewHandler(9)
+	if a := feed(h, "m", "i", "esc"); a != nil {
+		t.Fatalf("expected no action, got %+v", a)
+	}
+	if h.Pending() != "" {
+		t.Fatalf("expected nothing pending, got %q", h.Pending())
+	}
+}
+
+func TestWindowCommands(t *testing.T) {
+	h := NewHandler(9)
+	h.HandleKey("ctrl+w")
+	if h.Pending() != "^W" {
+		t.Fatalf("expected ^W pending, got %q", h.Pending())
+	}
+	a := h.HandleKey("l")
+	if a == nil || a.Type != ActionCommand || a.Value != "wincmd l" {
+		t.Fatalf("expected wincmd l, got %+v", a)
+	}
+	a = feed(h, "ctrl+w", "ctrl+j")
+	if a == nil || a.Value != "wincmd j" {
+		t.Fatalf("Ctrl-w Ctrl-j should work like Ctrl-w j, got %+v", a)
+	}
+	if h.CursorY != 4 {
+		t.Fatalf("window commands must not move the cursor")
+	}
+}
+
+func TestRegisterPrefix(t *testing.T) {
+	h := NewHandler(9)
+	h.HandleKey(`"`)
+	h.HandleKey("a")
+	if h.Pending() != `"a` {
+		t.Fatalf("expected \"a pending, got %q", h.Pending())
+	}
+	a := feed(h, "y", "i", "g")
+	if a == nil || a.Type != ActionOperator || a.Register != "a" {
+		t.Fatalf("expected yank into a, got %+v", a)
+	}
+
+	a = feed(h, `"`, "1", "2", "p")
+	if a == nil || a.Type != ActionPaste || a.Register != "1" || a.Count != 2 {
+		t.Fatalf("expected paste from 1 with count 2, got %+v", a)
+	}
+	if a := feed(h, "p"); a.Register != "" {
+		t.Fatalf("register should apply to one command only, got %q", a.Register)
+	}
+}
+
+func TestDoubledOperatorTakesWholeBoard(t *testing.T) {
+	h := NewHandler(9)
+	a := feed(h, `"`, "+", "y", "y")
+	want := board.NewRect(board.Point{}, board.Point{X: 8, Y: 8})
+	if a == nil || a.Region != want || a.Register != "+" {
+		t.Fatalf("expected whole-board yank into +, got %+v", a)
+	}
+
+	for _, op := range []string{"d", "c", "m"} {
+		if a := feed(h, op, op); a != nil {
+			t.Errorf("expected %s%s to do nothing, got %+v", op, op, a)
+		}
+		if h.Mode != Normal || h.Operator != "" {
+			t.Errorf("expected %s%s to leave Normal mode alone, got mode %v operator %q", op, op, h.Mode, h.Operator)
+		}
+	}
+}
+
+func TestScrollKeys(t *testing.T) {
+	h := NewHandler(9)
+	a := feed(h, "3", "ctrl+e")
+	if a == nil || a.Type != ActionScroll || a.Value != "down" || a.Count != 3 {
+		t.Fatalf("expected to scroll down 3 lines, got %+v", a)
+	}
+	a = feed(h, "ctrl+y")
+	if a == nil || a.Type != ActionScroll || a.Value != "up" || a.Count != 1 {
+		t.Fatalf("expected to scroll up a line, got %+v", a)
+	}
+}
@@ -0,0 +1,267 @@
+package vim
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// mapping is a key mapping defined with :nmap, :nnoremap and friends.
+type mapping struct {
+	lhs     []string
+	rhs     []string
+	noremap bool
+}
+
+// maxMapDepth limits how often recursive mappings may expand one key.
+const maxMapDepth = 1000
+
+// keyNames translates Vim key notation to the key strings HandleKey takes.
+var keyNames = map[string]string{
+	"cr":     "enter",
+	"enter":  "enter",
+	"return": "enter",
+	"esc":    "esc",
+	"space":  " ",
+	"tab":    "tab",
+	"s-tab":  "shift+tab",
+	"bs":     "backspace",
+	"del":    "delete",
+	"up":     "up",
+	"down":   "down",
+	"left":   "left",
+	"right":  "right",
+	"home":   "home",
+	"end":    "end",
+	"lt":     "<",
+	"bar":    "|",
+	"bslash": "\\",
+}
+
+// keyNotation is how FormatKeys writes the named keys.
+var keyNotation = map[string]string{
+	"enter":     "<CR>",
+	"esc":       "<Esc>",
+	"tab":       "<Tab>",
+	"shift+tab": "<S-Tab>",
+	"backspace": "<BS>",
+	"delete":    "<Del>",
+	"up":        "<Up>",
+	"down":      "<Down>",
+	"left":      "<Left>",
+	"right":     "<Right>",
+	"home":      "<Home>",
+	"end":       "<End>",
+	"\\":        "<Bslash>",
+}
+
+// ParseKeys splits a mapping side such as ":w<CR>" or "<C-w>l" into keys.
+// A "<" that does not start a known key name stands for itself.
+func ParseKeys(s string) ([]string, error) {
+	var keys []string
+	for i := 0; i < len(s); {
+		if s[i] == '<' {
+			if end := strings.IndexByte(s[i:], '>'); end > 1 {
+				name := strings.ToLower(s[i+1 : i+end])
+				if key, ok := keyNames[name]; ok {
+					keys = append(keys, key)
+					i += end + 1
+					continue
+				}
+				if rest, ok := strings.CutPrefix(name, "c-"); ok && len(rest) == 1 {
+					keys = append(keys, "ctrl+"+rest)
+					i += end + 1
+					continue
+				}
+			}
+		}
+		r := []rune(s[i:])[0]
+		keys = append(keys, string(r))
+		i += len(string(r))
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("E474: Invalid argument")
+	}
+	return keys, nil
+}
+
+// FormatKeys is the inverse of ParseKeys, for listing mappings.
+func FormatKeys(keys []string) string {
+	var b strings.Builder
+	for _, k := range keys {
+		switch {
+		case k == "<":
+			b.WriteString("<lt>")
+		case k == "|":
+			b.WriteString("<Bar>")
+		case k == " ":
+			b.WriteString("<Space>")
+		case strings.HasPrefix(k, "ctrl+"):
+			b.WriteString("<C-" + strings.TrimPrefix(k, "ctrl+") + ">")
+		case keyNotation[k] != "":
+			b.WriteString(keyNotation[k])
+		default:
+			b.WriteString(k)
+		}
+	}
+	return b.String()
+}
+
+// Map defines a mapping for Normal or Command mode, replacing any mapping
+// with the same keys. With noremap the keys of rhs are not mapped again.
+func (h *Handler) Map(mode Mode, lhs, rhs []string, noremap bool) {
+	if h.mappings == nil {
+		h.mappings = make(map[Mode][]mapping)
+	}
+	h.Unmap(mode, lhs)
+	h.mappings[mode] = append(h.mappings[mode], mapping{lhs: lhs, rhs: rhs, noremap: noremap})
+}
+
+// Unmap removes a mapping and reports whether there was one.
+func (h *Handler) Unmap(mode Mode, lhs []string) bool {
+	ms := h.mappings[mode]
+	for i, m := range ms {
+		if equalKeys(m.lhs, lhs) {
+			h.mappings[mode] = append(ms[:i], ms[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Mappings lists the mappings of a mode whose keys start with prefix, one
+// per line in Vim's format: a * marks noremap mappings.
+func (h *Handler) Mappings(mode Mode, prefix []string) []string {
+	var lines []string
+	for _, m := range h.mappings[mode] {
+		if len(m.lhs) < len(prefix) || !equalKeys(m.lhs[:len(prefix)], prefix) {
+			continue
+		}
+		star := " "
+		if m.noremap {
+			star = "*"
+		}
+		lines = append(lines, fmt.Sprintf("%-10s %s %s", FormatKeys(m.lhs), star, FormatKeys(m.rhs)))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// Feed handles a typed key, expanding mappings of the current mode first,
+// and returns the actions of the keys it dispatched. Keys that start a
+// longer mapping wait for the next key, or for Flush.
+func (h *Handler) Feed(key string) []*Action {
+	h.typeahead = append(h.typeahead, key)
+	return h.feed(false)
+}
+
+// Waiting reports whether typed keys wait to become a longer mapping.
+func (h *Handler) Waiting() bool {
+	return len(h.typeahead) > 0
+}
+
+// Flush handles the keys waiting for a longer mapping as if no more came,
+// like Vim when timeoutlen runs out: the longest mapping they complete
+// applies, and the other keys stand for themselves.
+func (h *Handler) Flush() []*Action {
+	return h.feed(true)
+}
+
+// feed dispatches the typeahead. With final, keys no longer wait for a
+// longer mapping.
+func (h *Handler) feed(final bool) []*Action {
+	var actions []*Action
+	dispatch := func(k string) {
+		if a := h.HandleKey(k); a != nil {
+			actions = append(actions, a)
+		}
+	}
+
+	depth := 0
+	for len(h.typeahead) > 0 {
+		m, waiting := h.lookupMapping()
+		if waiting && !final {
+			break
+		}
+		if m == nil {
+			k := h.typeahead[0]
+			h.typeahead = h.typeahead[1:]
+			dispatch(k)
+			continue
+		}
+
+		rest := h.typeahead[len(m.lhs):]
+		rhs := m.rhs
+		if !m.noremap && len(rhs) >= len(m.lhs) && equalKeys(rhs[:len(m.lhs)], m.lhs) {
+			// As in Vim, a mapping that starts with its own keys does not
+			// map them again.
+			for _, k := range m.lhs {
+				dispatch(k)
+			}
+			rhs = rhs[len(m.lhs):]
+		}
+		if m.noremap {
+			h.typeahead = rest
+			for _, k := range rhs {
+				dispatch(k)
+			}
+			continue
+		}
+
+		if depth++; depth > maxMapDepth {
+			// E223: recursive mapping. Drop what is left.
+			h.typeahead = nil
+			break
+		}
+		h.typeahead = append(append([]string{}, rhs...), rest...)
+	}
+	return actions
+}
+
+// lookupMapping finds the longest mapping matching the start of the
+// typeahead. It reports waiting when the typeahead could still become a
+// longer mapping.
+func (h *Handler) lookupMapping() (found *mapping, waiting bool) {
+	if !h.mapsApply() {
+		return nil, false
+	}
+	for i := range h.mappings[h.Mode] {
+		m := &h.mappings[h.Mode][i]
+		n := min(len(m.lhs), len(h.typeahead))
+		if !equalKeys(m.lhs[:n], h.typeahead[:n]) {
+			continue
+		}
+		if len(m.lhs) > len(h.typeahead) {
+			waiting = true
+			continue
+		}
+		if found == nil || len(m.lhs) > len(found.lhs) {
+			found = m
+		}
+	}
+	return found, waiting
+}
+
+// mapsApply reports whether mappings apply to the next key: in Normal mode
+// outside an operator or two-key command, and on the command line.
+func (h *Handler) mapsApply() bool {
+	switch h.Mode {
+	case Normal:
+		return h.Operator == "" && h.prefix == ""
+	case Command:
+		return true
+	}
+	return false
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
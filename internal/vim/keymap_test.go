@@ -0,0 +1,128 @@
+package vim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func feedAll(h *Handler, keys ...string) []*Action {
+	var actions []*Action
+	for _, k := range keys {
+		actions = append(actions, h.Feed(k)...)
+	}
+	return actions
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(":w<CR><C-w>l<lt><nope>")
+	if err != nil {
+		t.Fatalf("ParseKeys failed: %v", err)
+	}
+	want := []string{":", "w", "enter", "ctrl+w", "l", "<", "<", "n", "o", "p", "e", ">"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("expected %q, got %q", want, keys)
+	}
+	if got := FormatKeys(keys[:6]); got != ":w<CR><C-w>l<lt>" {
+		t.Fatalf("FormatKeys round trip gave %q", got)
+	}
+}
+
+func TestNoremapRunsCommand(t *testing.T) {
+	h := NewHandler(9)
+	h.Map(Normal, []string{"Q"}, []string{":", "w", "q", "enter"}, true)
+	actions := h.Feed("Q")
+	last := actions[len(actions)-1]
+	if last.Type != ActionCommand || last.Value != "wq" {
+		t.Fatalf("expected :wq command, got %+v", last)
+	}
+	if h.Mode != Normal {
+		t.Fatalf("expected Normal mode after the command, got %v", h.Mode)
+	}
+}
+
+func TestRecursiveMapUsesOtherMappings(t *testing.T) {
+	h := NewHandler(9)
+	h.Map(Normal, []string{"H"}, []string{"h"}, true)
+	h.Map(Normal, []string{"L"}, []string{"H", "H"}, false)
+	h.Map(Normal, []string{"N"}, []string{"H", "H"}, true)
+	feedAll(h, "L")
+	if h.CursorX != 2 {
+		t.Fatalf("nmap should expand H, cursor at %d", h.CursorX)
+	}
+	feedAll(h, "N")
+	if h.CursorX != 2 {
+		t.Fatalf("nnoremap must not expand H, cursor at %d", h.CursorX)
+	}
+}
+
+func TestMultiKeyMappingWaits(t *testing.T) {
+	h := NewHandler(9)
+	h.Map(Normal, []string{"g", "l"}, []string{"l", "l", "l"}, true)
+	if a := h.Feed("g"); len(a) != 0 || h.Pending() != "g" {
+		t.Fatalf("expected g to wait, got %v pending %q", a, h.Pending())
+	}
+	feedAll(h, "l")
+	if h.CursorX != 7 {
+		t.Fatalf("expected gl to move 3 right, cursor at %d", h.CursorX)
+	}
+
+	// A key that ends the match replays the held keys unmapped.
+	feedAll(h, "g", "h")
+	if h.CursorX != 6 || h.Pending() != "" {
+		t.Fatalf("expected g to be dropped and h to move, cursor at %d", h.CursorX)
+	}
+}
+
+func TestFlushEndsTheWait(t *testing.T) {
+	h := NewHandler(9)
+	h.Map(Normal, []string{"g", "l", "l"}, []string{"l", "l", "l"}, true)
+	feedAll(h, "g", "l")
+	if !h.Waiting() || h.CursorX != 4 {
+		t.Fatalf("expected gl to wait for the longer mapping")
+	}
+	// Nothing is mapped to g or gl: the keys stand for themselves, g is
+	// dropped and l moves.
+	h.Flush()
+	if h.Waiting() || h.CursorX != 5 || h.Pending() != "" {
+		t.Fatalf("expected gl typed unmapped, cursor at %d pending %q", h.CursorX, h.Pending())
+	}
+
+	// A shorter mapping the keys complete applies.
+	h.Map(Normal, []string{"g", "l"}, []string{"h"}, true)
+	feedAll(h, "g", "l")
+	if h.CursorX != 5 {
+		t.Fatalf("expected gl to wait for gll, cursor at %d", h.CursorX)
+	}
+	h.Flush()
+	if h.Waiting() || h.CursorX != 4 {
+		t.Fatalf("expected the gl mapping after the wait, cursor at %d", h.CursorX)
+	}
+}
+
+func TestSelfRecursiveMapTerminates(t *testing.T) {
+	h := NewHandler(9)
+	h.Map(Normal, []string{"a"}, []string{"b"}, false)
+	h.Map(Normal, []string{"b"}, []string{"a"}, false)
+	feedAll(h, "a")
+	if h.Pending() != "" {
+		t.Fatalf("recursive mapping left typeahead %q", h.Pending())
+	}
+
+	h.Map(Normal, []string{"j"}, []string{"j", "l"}, false)
+	feedAll(h, "j")
+	if h.CursorX != 5 || h.CursorY != 5 {
+		t.Fatalf("a mapping starting with its own keys should not loop, cursor at %d,%d", h.CursorX, h.CursorY)
+	}
+}
+
+func TestCommandLineMapping(t *testing.T) {
+	h := NewHandler(9)
+	h.Map(Command, []string{"ctrl+a"}, []string{"h", "o", "m", "e"}, true)
+	feedAll(h, ":", "ctrl+a")
+	if h.CommandBuffer != "home" {
+		t.Fatalf("expected cmap to insert text, got %q", h.CommandBuffer)
+	}
+	if h.Unmap(Command, []string{"ctrl+a"}) != true || len(h.Mappings(Command, nil)) != 0 {
+		t.Fatalf("expected unmap to remove the mapping")
+	}
+}
@@ -0,0 +1,112 @@
+package vim
+
+import (
+	"strconv"
+
+	"github.com/vimgo/vimgo/internal/board"
+)
+
+// Operators wait for a motion or a text object, as in Vim: "dag" removes a
+// group, "mig" toggles it dead while scoring and "y3l" yanks four
+// intersections of the row.
+var operators = map[string]bool{
+	"d": true, // delete stones
+	"y": true, // yank into the register
+	"c": true, // delete, then edit in Insert mode
+	"m": true, // toggle dead stones in the scoring phase
+}
+
+// textObjects are typed after "i" (inner) or "a" (around): "g" is the group
+// under the cursor and "t" the territory under it. The "a" forms add the
+// liberties of the group and the stones bordering the territory.
+var textObjects = map[string]bool{
+	"g": true,
+	"t": true,
+}
+
+// Pending returns the partially typed command (count, operator and text
+// object prefix) for display, like Vim's showcmd.
+func (h *Handler) Pending() string {
+	s := ""
+	if h.opCount > 1 {
+		s += strconv.Itoa(h.opCount)
+	}
+	prefix := h.prefix
+	if prefix == "ctrl+w" {
+		prefix = "^W"
+	}
+	if h.register != "" {
+		prefix = `"` + h.register + prefix
+	}
+	return s + prefix + h.Operator + h.InputBuffer + h.objPrefix + FormatKeys(h.typeahead)
+}
+
+func (h *Handler) startOperator(op string, count int) {
+	h.Operator = op
+	h.opCount = count
+	h.objPrefix = ""
+}
+
+func (h *Handler) cancelOperator() {
+	h.Operator = ""
+	h.opCount = 0
+	h.objPrefix = ""
+	h.InputBuffer = ""
+	h.RepeatCount = 0
+}
+
+// handleOperatorKey completes a pending operator with a text object or a
+// motion. Any other key cancels it.
+func (h *Handler) handleOperatorKey(key string) *Action {
+	op := h.Operator
+
+	if prefix := h.objPrefix; prefix != "" {
+		h.cancelOperator()
+		if !textObjects[key] {
+			return nil
+		}
+		return h.operatorAction(&Action{Value: op, Object: prefix + key})
+	}
+
+	switch key {
+	case "i", "a":
+		h.objPrefix = key
+		return nil
+	}
+
+	count := h.opCount * h.takeCount()
+	h.cancelOperator()
+
+	// yy yanks the whole board. The other doubled operators do nothing:
+	// a stray dd would clear every stone; dag or Visual mode say which.
+	if key == op {
+		if op != "y" {
+			return nil
+		}
+		region := board.NewRect(board.Point{}, board.Point{X: h.BoardSize - 1, Y: h.BoardSize - 1})
+		return h.operatorAction(&Action{Value: op, Region: region})
+	}
+
+	// Operators leave the cursor where it was, so move a copy.
+	startX, startY := h.CursorX, h.CursorY
+	if !h.moveCursor(key, count) {
+		return nil
+	}
+	region := board.NewRect(
+		board.Point{X: startX, Y: startY},
+		board.Point{X: h.CursorX, Y: h.CursorY},
+	)
+	h.CursorX, h.CursorY = startX, startY
+	return h.operatorAction(&Action{Value: op, Region: region})
+}
+
+// operatorAction finishes an operator action; "c" continues in Insert mode,
+// except on a selection that was not made there, which is not changed.
+func (h *Handler) operatorAction(a *Action) *Action {
+	a.Type = ActionOperator
+	a.Register = h.takeRegister()
+	if a.Value == "c" && (!a.Visual || h.VisualInsert) {
+		h.Mode = Insert
+	}
+	return a
+}
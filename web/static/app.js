@@ -17,9 +17,29 @@
   fitAddon.fit();
   term.focus();
 
+  // REPLAY_IDLE is the longest pause of a replay, in seconds, unless the
+  // recording sets its own idle_time_limit.
+  const REPLAY_IDLE = 2;
+
+  // At /replay/<id>, the recording of a session is played back instead.
+  const replay = window.location.pathname.match(/^\/replay\/([^/]+)$/);
+  if (replay) {
+    play(`/replay/${replay[1]}.cast`);
+    return;
+  }
+
+  // The server keeps our session for a while after the page goes away;
+  // its token, kept for this tab, takes it up again after a reload.
+  const SESSION = "vimgo-session";
+  const query = new URLSearchParams(window.location.search);
+  const token = window.sessionStorage.getItem(SESSION);
+  if (token) {
+    query.set("session", token);
+  }
+
   const protocol = window.location.protocol === "https:" ? "wss" : "ws";
   const ws = new WebSocket(
-    `${protocol}://${window.location.host}/ws${window.location.search}`
+    `${protocol}://${window.location.host}/ws?${query}`
   );
   ws.binaryType = "arraybuffer";
 
@@ -47,6 +67,18 @@
     }
 
     if (typeof event.data === "string") {
+      if (event.data.startsWith("{")) {
+        const msg = JSON.parse(event.data);
+        if (msg.type === "session") {
+          window.sessionStorage.setItem(SESSION, msg.token);
+          if (msg.replay) {
+            const link = document.getElementById("replay");
+            link.href = `/replay/${msg.replay}`;
+            link.hidden = false;
+          }
+        }
+        return;
+      }
       term.write(event.data);
     }
   };
@@ -70,4 +102,93 @@
     fitAddon.fit();
     sendResize();
   });
+
+  // play fetches the asciicast recording at url and plays it: space
+  // pauses and resumes, r starts over.
+  function play(url) {
+    fetch(url)
+      .then(function (res) {
+        if (!res.ok) {
+          throw new Error(`${res.status} ${res.statusText}`);
+        }
+        return res.text();
+      })
+      .then(function (text) {
+        const lines = text.split("\n").filter(Boolean);
+        const header = JSON.parse(lines[0]);
+        const events = lines.slice(1).map(function (line) {
+          return JSON.parse(line);
+        });
+        if (header.title) {
+          document.title = `${header.title} (replay)`;
+        }
+
+        // Each event's time in the replay, with long pauses cut short.
+        const idle = header.idle_time_limit || REPLAY_IDLE;
+        let at = 0;
+        let last = 0;
+        const times = events.map(function (ev) {
+          at += Math.min(ev[0] - last, idle);
+          last = ev[0];
+          return at;
+        });
+
+        let next = 0;
+        let offset = 0;
+        let started = 0;
+        let timer = null;
+
+        function position() {
+          return offset + (performance.now() - started) / 1000;
+        }
+
+        function tick() {
+          const now = position();
+          while (next < events.length && times[next] <= now) {
+            const [, code, data] = events[next++];
+            if (code === "o") {
+              term.write(data);
+            } else if (code === "r") {
+              const [cols, rows] = data.split("x").map(Number);
+              term.resize(cols, rows);
+            }
+          }
+          if (next < events.length) {
+            timer = setTimeout(tick, (times[next] - now) * 1000);
+          } else {
+            timer = null;
+            term.write("\r\n\x1b[33mEnd of recording\x1b[0m\r\n");
+          }
+        }
+
+        function start() {
+          term.reset();
+          term.resize(header.width, header.height);
+          next = 0;
+          offset = 0;
+          started = performance.now();
+          tick();
+        }
+
+        term.onData(function (data) {
+          if (data === "r") {
+            clearTimeout(timer);
+            start();
+          } else if (data === " " && next < events.length) {
+            if (timer !== null) {
+              clearTimeout(timer);
+              timer = null;
+              offset = position();
+            } else {
+              started = performance.now();
+              tick();
+            }
+          }
+        });
+        start();
+      })
+      .catch(function (err) {
+        term.write(`\x1b[31mNo recording: ${err.message}\x1b[0m\r\n`);
+      });
+  }
 })();
@@ -0,0 +1,585 @@
+(function () {
+  const params = new URLSearchParams(window.location.search);
+  // The server also serves this page at /watch/<room> to spectators, who
+  // follow the game read-only and browse its moves on their own.
+  const WATCH = "/watch/";
+  const watching = window.location.pathname.startsWith(WATCH);
+  const room = watching
+    ? decodeURIComponent(window.location.pathname.slice(WATCH.length))
+    : params.get("room");
+  if (!room) {
+    window.location.replace("/new?ui=board");
+    return;
+  }
+
+  // The GBA palette of doc/project-plan.md, as in the "gba" terminal theme.
+  const COLORS = {
+    board: "#e8cda5",
+    grid: "#c9a05c",
+    border: "#8b4513",
+    star: "#8b4513",
+    black: "#1a1a1a",
+    blackShine: "#4a4a4a",
+    white: "#f5f5f5",
+    outline: "#2d2d2d",
+    cursor: "#ff6b6b",
+    lastMove: "#d03030",
+  };
+  // The board is drawn in small pixels, CELL apart, and scaled up by a
+  // whole number so they stay sharp.
+  const CELL = 12;
+  const STONE = 5;
+  const MARGIN = 12;
+  const COLUMNS = "ABCDEFGHJKLMNOPQRST";
+
+  const canvas = document.getElementById("board");
+  const ctx = canvas.getContext("2d");
+  const el = {
+    status: document.getElementById("status"),
+    viewers: document.getElementById("viewers"),
+    error: document.getElementById("error"),
+    actions: document.getElementById("actions"),
+    pass: document.getElementById("pass"),
+    undo: document.getElementById("undo"),
+    browse: document.getElementById("browse"),
+    first: document.getElementById("first"),
+    prev: document.getElementById("prev"),
+    next: document.getElementById("next"),
+    live: document.getElementById("live"),
+    invite: document.getElementById("invite"),
+    link: document.getElementById("link"),
+    watchLink: document.getElementById("watch-link"),
+    messages: document.getElementById("messages"),
+    say: document.getElementById("say"),
+    text: document.getElementById("text"),
+  };
+  const players = {
+    black: document.getElementById("player-black"),
+    white: document.getElementById("player-white"),
+  };
+
+  let seat = "";
+  let state = null;
+  let cursor = null;
+  // view is the move a spectator looks at, or null to follow the game.
+  let view = null;
+  let undoFrom = "";
+  let requestID = 0;
+  let errorTimer = 0;
+
+  el.link.value = `${window.location.origin}/board.html?room=${encodeURIComponent(room)}`;
+  el.watchLink.value = `${window.location.origin}${WATCH}${encodeURIComponent(room)}`;
+  for (const input of [el.link, el.watchLink]) {
+    input.addEventListener("focus", function () {
+      input.select();
+    });
+  }
+  if (watching) {
+    el.invite.hidden = el.link.hidden = true;
+    el.actions.hidden = el.say.hidden = true;
+    el.browse.hidden = false;
+  }
+
+  // The server keeps our seat for a while after the page goes away; the
+  // session token, kept for this tab, takes it up again after a reload.
+  const SESSION = "vimgo-session";
+  const query = new URLSearchParams(window.location.search);
+  if (window.sessionStorage.getItem(SESSION)) {
+    query.set("session", window.sessionStorage.getItem(SESSION));
+  }
+
+  const protocol = window.location.protocol === "https:" ? "wss" : "ws";
+  const path = watching ? window.location.pathname : "/ws/board";
+  const ws = new WebSocket(
+    `${protocol}://${window.location.host}${path}?${query}`,
+    "vimgo.v1"
+  );
+
+  function send(msg) {
+    if (ws.readyState !== WebSocket.OPEN) {
+      return;
+    }
+    requestID++;
+    ws.send(JSON.stringify(Object.assign({ id: String(requestID) }, msg)));
+  }
+
+  ws.onmessage = function (event) {
+    const msg = JSON.parse(event.data);
+    switch (msg.type) {
+      case "session":
+        window.sessionStorage.setItem(SESSION, msg.token);
+        return;
+      case "hello":
+        seat = msg.seat;
+        break;
+      case "state":
+        state = msg;
+        undoFrom = "";
+        if (view !== null && view >= state.move) {
+          view = null;
+        }
+        if (!cursor) {
+          const c = Math.floor(state.size / 2);
+          cursor = { x: c, y: c };
+        }
+        resize();
+        break;
+      case "played":
+        if (!state) {
+          return;
+        }
+        addMove(msg);
+        undoFrom = "";
+        draw();
+        break;
+      case "viewers":
+        el.viewers.textContent = msg.count ? `${msg.count} watching` : "";
+        return;
+      case "clock":
+        showClock(msg);
+        return;
+      case "undo":
+        undoFrom = msg.seat;
+        addMessage("", `${msg.from || msg.seat} asks to take back a move`);
+        break;
+      case "chat":
+        addMessage(msg.from || speaker(msg.seat), msg.text);
+        return;
+      case "left":
+        addMessage("", `${msg.from || speaker(msg.seat)} left, the ${msg.seat} seat is free`);
+        return;
+      case "error":
+        showError(msg.message);
+        return;
+    }
+    showPanel();
+  };
+
+  ws.onclose = function () {
+    el.status.textContent = "Disconnected";
+    el.pass.disabled = el.undo.disabled = true;
+  };
+
+  // Drawing
+
+  function resize() {
+    if (!state) {
+      return;
+    }
+    const pixels = (state.size - 1) * CELL + 2 * MARGIN;
+    const stage = canvas.parentElement;
+    const scale = Math.max(1, Math.floor(Math.min(stage.clientWidth, stage.clientHeight) / pixels));
+    canvas.width = canvas.height = pixels;
+    canvas.style.width = canvas.style.height = `${pixels * scale}px`;
+    draw();
+  }
+
+  function at(x, y) {
+    return { px: MARGIN + x * CELL, py: MARGIN + y * CELL };
+  }
+
+  function draw() {
+    const size = state.size;
+    const pos = shown();
+    const span = (size - 1) * CELL;
+    ctx.fillStyle = COLORS.board;
+    ctx.fillRect(0, 0, canvas.width, canvas.height);
+
+    // Block border, like the gba theme's.
+    ctx.fillStyle = COLORS.border;
+    ctx.fillRect(0, 0, canvas.width, 2);
+    ctx.fillRect(0, canvas.height - 2, canvas.width, 2);
+    ctx.fillRect(0, 0, 2, canvas.height);
+    ctx.fillRect(canvas.width - 2, 0, 2, canvas.height);
+
+    ctx.fillStyle = COLORS.grid;
+    for (let i = 0; i < size; i++) {
+      ctx.fillRect(MARGIN, MARGIN + i * CELL, span + 1, 1);
+      ctx.fillRect(MARGIN + i * CELL, MARGIN, 1, span + 1);
+    }
+    ctx.fillStyle = COLORS.star;
+    for (const [x, y] of starPoints(size)) {
+      const { px, py } = at(x, y);
+      ctx.fillRect(px - 1, py - 1, 3, 3);
+    }
+
+    pos.board.forEach(function (row, y) {
+      for (let x = 0; x < size; x++) {
+        if (row[x] === "X" || row[x] === "O") {
+          drawStone(x, y, row[x] === "X");
+        }
+      }
+    });
+
+    if (pos.lastMove) {
+      const { px, py } = at(pos.lastMove.x, pos.lastMove.y);
+      ctx.fillStyle = COLORS.lastMove;
+      ctx.fillRect(px - 1, py - 1, 3, 3);
+    }
+    if (cursor && seat) {
+      drawCursor(cursor.x, cursor.y);
+    }
+
+    // Scanlines.
+    ctx.fillStyle = "rgba(0, 0, 0, 0.06)";
+    for (let y = 0; y < canvas.height; y += 2) {
+      ctx.fillRect(0, y, canvas.width, 1);
+    }
+  }
+
+  // disc fills a pixel circle of radius r around px, py.
+  function disc(px, py, r) {
+    for (let dy = -r; dy <= r; dy++) {
+      const w = Math.floor(Math.sqrt(r * r + r - dy * dy));
+      ctx.fillRect(px - w, py + dy, 2 * w + 1, 1);
+    }
+  }
+
+  function drawStone(x, y, black) {
+    const { px, py } = at(x, y);
+    if (black) {
+      ctx.fillStyle = COLORS.black;
+      disc(px, py, STONE);
+      ctx.fillStyle = COLORS.blackShine;
+      ctx.fillRect(px - 3, py - 3, 2, 1);
+      return;
+    }
+    ctx.fillStyle = COLORS.outline;
+    disc(px, py, STONE);
+    ctx.fillStyle = COLORS.white;
+    disc(px, py, STONE - 1);
+  }
+
+  function drawCursor(x, y) {
+    const { px, py } = at(x, y);
+    const r = STONE + 1;
+    ctx.fillStyle = COLORS.cursor;
+    for (const [sx, sy] of [[-1, -1], [1, -1], [-1, 1], [1, 1]]) {
+      const cx = px + sx * r;
+      const cy = py + sy * r;
+      ctx.fillRect(Math.min(cx, cx - sx * 2), cy, 3, 1);
+      ctx.fillRect(cx, Math.min(cy, cy - sy * 2), 1, 3);
+    }
+  }
+
+  function starPoints(size) {
+    const lines = { 9: [2, 6], 13: [3, 9], 19: [3, 9, 15] }[size] || [];
+    const points = [];
+    for (const y of lines) {
+      for (const x of lines) {
+        points.push([x, y]);
+      }
+    }
+    if (size % 2 === 1 && size >= 9) {
+      points.push([(size - 1) / 2, (size - 1) / 2]);
+    }
+    return points;
+  }
+
+  // Moves
+
+  // shown is the position on the board: the game's, or the one at move
+  // view while a spectator browses.
+  function shown() {
+    return view === null ? state : replay(view);
+  }
+
+  // replay plays the game's first n moves on an empty board, as rooms
+  // start from one with black to play.
+  function replay(n) {
+    const grid = Array.from({ length: state.size }, function () {
+      return Array(state.size).fill(".");
+    });
+    const captures = { black: 0, white: 0 };
+    let last = null;
+    state.moves.slice(0, n).forEach(function (move, i) {
+      const color = i % 2 === 0 ? "black" : "white";
+      last = parseCoordinate(move);
+      if (last) {
+        captures[color] += place(grid, last.x, last.y, color);
+      }
+    });
+    return {
+      board: grid.map(function (row) {
+        return row.join("");
+      }),
+      lastMove: last,
+      captures: captures,
+    };
+  }
+
+  // addMove adds a played move to the end of the game.
+  function addMove(msg) {
+    const grid = state.board.map(function (row) {
+      return row.split("");
+    });
+    if (msg.point) {
+      place(grid, msg.point.x, msg.point.y, msg.color);
+    }
+    state.board = grid.map(function (row) {
+      return row.join("");
+    });
+    state.moves.push(msg.point ? COLUMNS[msg.point.x] + (state.size - msg.point.y) : "pass");
+    state.move = msg.move;
+    state.lastMove = msg.point || null;
+    state.captures = msg.captures;
+    state.toPlay = msg.color === "black" ? "white" : "black";
+  }
+
+  function parseCoordinate(move) {
+    if (move === "pass") {
+      return null;
+    }
+    return { x: COLUMNS.indexOf(move[0]), y: state.size - Number(move.slice(1)) };
+  }
+
+  // place puts a stone of color on grid, an array of rows of points, and
+  // takes the groups it leaves without liberties. It returns how many
+  // stones it took.
+  function place(grid, x, y, color) {
+    const stone = color === "black" ? "X" : "O";
+    const enemy = stone === "X" ? "O" : "X";
+    grid[y][x] = stone;
+    let taken = 0;
+    for (const [nx, ny] of neighbors(x, y)) {
+      if (grid[ny][nx] !== enemy) {
+        continue;
+      }
+      const group = groupAt(grid, nx, ny);
+      if (group.liberties === 0) {
+        for (const [gx, gy] of group.stones) {
+          grid[gy][gx] = ".";
+        }
+        taken += group.stones.length;
+      }
+    }
+    return taken;
+  }
+
+  function neighbors(x, y) {
+    return [[x - 1, y], [x + 1, y], [x, y - 1], [x, y + 1]].filter(function ([nx, ny]) {
+      return nx >= 0 && ny >= 0 && nx < state.size && ny < state.size;
+    });
+  }
+
+  function groupAt(grid, x, y) {
+    const color = grid[y][x];
+    const stones = [[x, y]];
+    const seen = new Set([`${x},${y}`]);
+    const liberties = new Set();
+    for (let i = 0; i < stones.length; i++) {
+      for (const [nx, ny] of neighbors(stones[i][0], stones[i][1])) {
+        const key = `${nx},${ny}`;
+        if (grid[ny][nx] === ".") {
+          liberties.add(key);
+        } else if (grid[ny][nx] === color && !seen.has(key)) {
+          seen.add(key);
+          stones.push([nx, ny]);
+        }
+      }
+    }
+    return { stones: stones, liberties: liberties.size };
+  }
+
+  // browse shows move n, or follows the game again from its last move.
+  function browse(n) {
+    if (!state) {
+      return;
+    }
+    n = Math.max(0, Math.min(state.move, n));
+    view = n === state.move ? null : n;
+    draw();
+    showPanel();
+  }
+
+  function current() {
+    return view === null ? state.move : view;
+  }
+
+  // Panel
+
+  function showPanel() {
+    if (!state) {
+      return;
+    }
+    const pos = shown();
+    for (const color of ["black", "white"]) {
+      const p = players[color];
+      p.querySelector(".name").textContent =
+        state.names[color] || color[0].toUpperCase() + color.slice(1);
+      p.querySelector(".captures").textContent = pos.captures[color];
+      p.classList.toggle("to-play", !state.result && state.toPlay === color);
+    }
+
+    let status;
+    if (view !== null) {
+      status = `Move ${view} of ${state.move}`;
+    } else if (state.result) {
+      status = `Game over: ${state.result}`;
+    } else if (!seat) {
+      status = `Watching, move ${state.move}`;
+    } else if (state.toPlay === seat) {
+      status = `Your move (${state.move + 1})`;
+    } else {
+      status = `Waiting for ${state.toPlay}`;
+    }
+    el.status.textContent = status;
+
+    const playing = seat !== "" && !state.result;
+    el.pass.disabled = !playing || state.toPlay !== seat;
+    el.undo.disabled = !playing || undoFrom === seat;
+    el.undo.textContent = undoFrom && undoFrom !== seat ? "Accept undo" : "Undo";
+    el.first.disabled = el.prev.disabled = current() === 0;
+    el.next.disabled = el.live.disabled = view === null;
+  }
+
+  function showClock(msg) {
+    for (const color of ["black", "white"]) {
+      const left = Math.max(0, Math.round(msg.left[color]));
+      const clock = players[color].querySelector(".clock");
+      clock.textContent = `${Math.floor(left / 60)}:${String(left % 60).padStart(2, "0")}`;
+      clock.style.color = msg.running === color ? "var(--accent)" : "";
+    }
+  }
+
+  function showError(message) {
+    el.error.textContent = message;
+    window.clearTimeout(errorTimer);
+    errorTimer = window.setTimeout(function () {
+      el.error.textContent = "";
+    }, 3000);
+  }
+
+  // speaker names someone without a name by their seat, as the server
+  // does in the game record.
+  function speaker(seat) {
+    return seat ? seat[0].toUpperCase() + seat.slice(1) : "Spectator";
+  }
+
+  function addMessage(from, text) {
+    const li = document.createElement("li");
+    if (from) {
+      const name = document.createElement("span");
+      name.className = "from";
+      name.textContent = `${from}: `;
+      li.appendChild(name);
+    }
+    li.appendChild(document.createTextNode(text));
+    el.messages.appendChild(li);
+    el.messages.scrollTop = el.messages.scrollHeight;
+  }
+
+  // Input
+
+  function play(x, y) {
+    if (!seat || !state || state.result) {
+      return;
+    }
+    send({ type: "move", x: x, y: y });
+  }
+
+  function pointAt(event) {
+    const rect = canvas.getBoundingClientRect();
+    const scale = rect.width / canvas.width;
+    const x = Math.round(((event.clientX - rect.left) / scale - MARGIN) / CELL);
+    const y = Math.round(((event.clientY - rect.top) / scale - MARGIN) / CELL);
+    if (!state || x < 0 || y < 0 || x >= state.size || y >= state.size) {
+      return null;
+    }
+    return { x: x, y: y };
+  }
+
+  canvas.addEventListener("pointermove", function (event) {
+    if (event.pointerType !== "mouse") {
+      return;
+    }
+    const p = pointAt(event);
+    if (p && (!cursor || p.x !== cursor.x || p.y !== cursor.y)) {
+      cursor = p;
+      draw();
+    }
+  });
+
+  // A mouse click plays; on touch screens the first tap only moves the
+  // cursor, and a second tap on the same point plays.
+  canvas.addEventListener("pointerup", function (event) {
+    const p = pointAt(event);
+    if (!p) {
+      return;
+    }
+    const again = cursor && p.x === cursor.x && p.y === cursor.y;
+    cursor = p;
+    draw();
+    if (event.pointerType === "mouse" || again) {
+      play(p.x, p.y);
+    }
+  });
+
+  document.addEventListener("keydown", function (event) {
+    if (event.target === el.text || !state || !cursor) {
+      return;
+    }
+    if (watching) {
+      const steps = {
+        h: current() - 1, ArrowLeft: current() - 1,
+        l: current() + 1, ArrowRight: current() + 1,
+        g: 0, Home: 0,
+        G: state.move, End: state.move,
+      };
+      if (event.key in steps) {
+        browse(steps[event.key]);
+        event.preventDefault();
+      }
+      return;
+    }
+    const moves = {
+      h: [-1, 0], ArrowLeft: [-1, 0],
+      l: [1, 0], ArrowRight: [1, 0],
+      k: [0, -1], ArrowUp: [0, -1],
+      j: [0, 1], ArrowDown: [0, 1],
+    };
+    if (moves[event.key]) {
+      const [dx, dy] = moves[event.key];
+      cursor = {
+        x: Math.min(state.size - 1, Math.max(0, cursor.x + dx)),
+        y: Math.min(state.size - 1, Math.max(0, cursor.y + dy)),
+      };
+      draw();
+      event.preventDefault();
+    } else if (event.key === "x" || event.key === "Enter") {
+      play(cursor.x, cursor.y);
+    }
+  });
+
+  el.pass.addEventListener("click", function () {
+    send({ type: "pass" });
+  });
+
+  el.undo.addEventListener("click", function () {
+    send({ type: "undo" });
+  });
+
+  el.first.addEventListener("click", function () {
+    browse(0);
+  });
+
+  el.prev.addEventListener("click", function () {
+    browse(current() - 1);
+  });
+
+  el.next.addEventListener("click", function () {
+    browse(current() + 1);
+  });
+
+  el.live.addEventListener("click", function () {
+    browse(state.move);
+  });
+
+  el.say.addEventListener("submit", function (event) {
+    event.preventDefault();
+    send({ type: "chat", text: el.text.value });
+    el.text.value = "";
+  });
+
+  window.addEventListener("resize", resize);
+})();
@@ -0,0 +1,21 @@
+// Package web holds the pages of the web UI, built into the server so
+// that it runs without them on disk.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed static
+var files embed.FS
+
+// Static returns the files of the static directory: the terminal page,
+// the board page and their scripts and styles.
+func Static() fs.FS {
+	static, err := fs.Sub(files, "static")
+	if err != nil {
+		panic(err)
+	}
+	return static
+}